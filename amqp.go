@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/radu-matei/events-operator/pkg/amqp"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+)
+
+const (
+	// providerAMQP is the provider name of eventproviders consuming AMQP 0-9-1 queues
+	providerAMQP = "amqp"
+
+	// defaultPrefetch is how many unacknowledged messages the broker sends to a consumer, by default
+	defaultPrefetch = 10
+	// defaultAMQPMaxDeliveryCount is how many times a message is delivered before it is dropped, by default
+	defaultAMQPMaxDeliveryCount = 5
+)
+
+// syncAMQP runs the consumer of the queue of the eventprovider. Every replica of the operator
+// consumes the queue, so messages are shared between them.
+func (c *Controller) syncAMQP(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.AMQP
+	if spec == nil {
+		return fmt.Errorf("the amqp provider requires an amqp spec")
+	}
+	if spec.SecretName == "" || spec.Queue == "" {
+		return fmt.Errorf("an AMQP secret and queue are required")
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+	if err != nil {
+		return fmt.Errorf("cannot get AMQP secret %s: %v", spec.SecretName, err)
+	}
+	url, err := secretKey(secret, "url")
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, tlsVersion, err := c.clientTLS(ep, spec.TLS)
+	if err != nil {
+		return err
+	}
+
+	prefetch := defaultPrefetch
+	if spec.Prefetch > 0 {
+		prefetch = int(spec.Prefetch)
+	}
+	maxDeliveryCount := defaultAMQPMaxDeliveryCount
+	if spec.MaxDeliveryCount > 0 {
+		maxDeliveryCount = int(spec.MaxDeliveryCount)
+	}
+
+	return c.syncSource(ep, &amqp.Source{
+		URL:                string(url),
+		TLS:                tlsConfig,
+		Queue:              spec.Queue,
+		Declare:            spec.Declare,
+		Exchange:           spec.Exchange,
+		RoutingKeys:        spec.RoutingKeys,
+		DeadLetterExchange: spec.DeadLetterExchange,
+		MaxDeliveryCount:   maxDeliveryCount,
+		Prefetch:           prefetch,
+		Retry:              retryPolicy(spec.Retry),
+		EventSource:        eventProviderURI(ep),
+	}, spec, secret.ResourceVersion, tlsVersion)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/eventgrid"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// azureSecret returns the azureSecretName secret of the eventprovider, nil when it has none
+func (c *Controller) azureSecret(ep *v1alpha1.EventProvider) (*corev1.Secret, error) {
+	if ep.Spec.AzureSecretName == "" {
+		return nil, nil
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(ep.Spec.AzureSecretName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get Azure secret %s: %v", ep.Spec.AzureSecretName, err)
+	}
+
+	return secret, nil
+}
+
+// azureCredentials returns the service principal credentials of an eventprovider: the tenantID, clientID
+// and clientSecret keys of its Azure secret, or the credentials of the operator when secret is nil
+func azureCredentials(secret *corev1.Secret) (eventgrid.Credentials, error) {
+	if secret == nil {
+		return eventgrid.DefaultCredentials(), nil
+	}
+
+	var credentials eventgrid.Credentials
+	for key, value := range map[string]*string{
+		"tenantID":     &credentials.TenantID,
+		"clientID":     &credentials.ClientID,
+		"clientSecret": &credentials.ClientSecret,
+	} {
+		b, err := secretKey(secret, key)
+		if err != nil {
+			return eventgrid.Credentials{}, err
+		}
+		*value = string(b)
+	}
+
+	return credentials, nil
+}
+
+// resourceVersion returns the resource version of a secret, empty when it is nil
+func resourceVersion(secret *corev1.Secret) string {
+	if secret == nil {
+		return ""
+	}
+
+	return secret.ResourceVersion
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// clientTLS returns the TLS configuration the operator connects to a broker with, nil when spec is nil,
+// and the resource version of its secret. The CA certificates of the broker are read from the ca.crt
+// key of the secret, and the client certificate from its tls.crt and tls.key keys.
+func (c *Controller) clientTLS(ep *v1alpha1.EventProvider, spec *v1alpha1.ClientTLSSpec) (*tls.Config, string, error) {
+	if spec == nil {
+		return nil, "", nil
+	}
+
+	config := &tls.Config{
+		ServerName:         spec.ServerName,
+		InsecureSkipVerify: spec.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if spec.SecretName == "" {
+		return config, "", nil
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot get TLS secret %s: %v", spec.SecretName, err)
+	}
+
+	if ca, ok := secret.Data["ca.crt"]; ok {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, "", fmt.Errorf("secret %s/%s has no valid certificate in its ca.crt key", secret.Namespace, secret.Name)
+		}
+	}
+
+	_, hasCert := secret.Data[corev1.TLSCertKey]
+	_, hasKey := secret.Data[corev1.TLSPrivateKeyKey]
+	if hasCert || hasKey {
+		certificate, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid client certificate in secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, secret.ResourceVersion, nil
+}
+
+// userCredentials returns the username and password keys of a secret, and its resource version
+func (c *Controller) userCredentials(ep *v1alpha1.EventProvider, secretName string) (string, string, string, error) {
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(secretName)
+	if err != nil {
+		return "", "", "", fmt.Errorf("cannot get credentials secret %s: %v", secretName, err)
+	}
+
+	username, err := secretKey(secret, "username")
+	if err != nil {
+		return "", "", "", err
+	}
+	password, err := secretKey(secret, "password")
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return string(username), string(password), secret.ResourceVersion, nil
+}
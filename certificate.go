@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// certificateResource is the cert-manager Certificate resource
+var certificateResource = schema.GroupVersionResource{
+	Group:    "cert-manager.io",
+	Version:  "v1",
+	Resource: "certificates",
+}
+
+// tlsAcmeAnnotation is the legacy annotation asking kube-lego / cert-manager's
+// ingress-shim to issue a certificate for an Ingress
+const tlsAcmeAnnotation = "kubernetes.io/tls-acme"
+
+// certificateSpecFields are the fields of the Certificate spec set by the operator.
+// Other fields, such as the ones defaulted by cert-manager, are left alone.
+var certificateSpecFields = []string{"issuerRef", "dnsNames", "secretName"}
+
+// discoverCertificateAPI checks whether cert-manager is installed in the cluster
+func discoverCertificateAPI(client discovery.DiscoveryInterface) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(certificateResource.GroupVersion().String())
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot discover resources for %s: %v", certificateResource.GroupVersion(), err)
+	}
+
+	for _, r := range resources.APIResources {
+		if r.Name == certificateResource.Resource {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// managesCertificate returns true when the operator issues the handler certificate through cert-manager.
+// Certificates are only managed for Ingresses - in HTTPRoute mode TLS is terminated by the Gateway.
+// The certificate of the shared host always uses the operator-wide issuer.
+func managesCertificate(ep *v1alpha1.EventProvider, config ControllerConfig) bool {
+	switch exposureMode(ep, config) {
+	case v1alpha1.ExposureModeIngress:
+		return certificateIssuer(ep, config).Name != ""
+	case v1alpha1.ExposureModeSharedIngress:
+		return config.CertificateIssuer.Name != ""
+	default:
+		return false
+	}
+}
+
+// syncCertificate makes sure the cert-manager Certificate of the handler exists,
+// reports its progress in the eventprovider status and returns whether it was issued.
+// The eventprovider is queued again when the certificate status changes.
+func (c *Controller) syncCertificate(ep *v1alpha1.EventProvider) (*v1alpha1.EventProvider, bool, error) {
+	if !managesCertificate(ep, c.config) {
+		return ep, true, nil
+	}
+	if c.certificateLister == nil {
+		return ep, false, fmt.Errorf("cert-manager is not installed in the cluster")
+	}
+
+	var desired *unstructured.Unstructured
+	if exposureMode(ep, c.config) == v1alpha1.ExposureModeSharedIngress {
+		// the shared certificate is not owned by any eventprovider
+		desired = newCertificate(c.config.OperatorNamespace, sharedIngressName, c.config.SharedHost, certificateIssuer(ep, c.config))
+	} else {
+		desired = newCertificate(ep.Namespace, ingressName(ep), ep.Spec.Host, certificateIssuer(ep, c.config))
+		desired.SetOwnerReferences([]metav1.OwnerReference{
+			*metav1.NewControllerRef(ep, v1alpha1.SchemeGroupVersion.WithKind("EventProvider")),
+		})
+	}
+
+	var certificate *unstructured.Unstructured
+	obj, err := c.certificateLister.ByNamespace(desired.GetNamespace()).Get(desired.GetName())
+	if errors.IsNotFound(err) {
+		certificate, err = c.dynamicclientset.Resource(certificateResource).Namespace(desired.GetNamespace()).Create(context.TODO(), desired, metav1.CreateOptions{})
+	} else if err == nil {
+		var ok bool
+		if certificate, ok = obj.(*unstructured.Unstructured); !ok {
+			return ep, false, fmt.Errorf("unexpected object in the Certificate cache: %#v", obj)
+		}
+		if certificateDrifted(certificate, desired) {
+			// NEVER modify objects from the store. It's a read-only, local cache.
+			certificateCopy := certificate.DeepCopy()
+			for _, field := range certificateSpecFields {
+				value, _, _ := unstructured.NestedFieldCopy(desired.Object, "spec", field)
+				if err = unstructured.SetNestedField(certificateCopy.Object, value, "spec", field); err != nil {
+					return ep, false, fmt.Errorf("cannot set certificate %s: %v", field, err)
+				}
+			}
+			certificate, err = c.dynamicclientset.Resource(certificateResource).Namespace(desired.GetNamespace()).Update(context.TODO(), certificateCopy, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		return ep, false, err
+	}
+	glog.V(4).Infof("certificate name: %v", certificate.GetName())
+
+	condition := certificateCondition(certificate)
+	ep, err = c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		meta.SetStatusCondition(&status.Conditions, condition)
+	})
+	if err != nil {
+		return ep, false, fmt.Errorf("cannot update eventprovider status: %v", err)
+	}
+
+	return ep, condition.Status == metav1.ConditionTrue, nil
+}
+
+// certificateDrifted returns true when a field of the Certificate spec set by the operator has another value
+func certificateDrifted(current, desired *unstructured.Unstructured) bool {
+	for _, field := range certificateSpecFields {
+		currentValue, _, _ := unstructured.NestedFieldNoCopy(current.Object, "spec", field)
+		desiredValue, _, _ := unstructured.NestedFieldNoCopy(desired.Object, "spec", field)
+		if !equality.Semantic.DeepEqual(currentValue, desiredValue) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newCertificate creates a Certificate for host, stored in the secret used by the Ingress of the same name
+func newCertificate(namespace, name, host string, issuer v1alpha1.IssuerReference) *unstructured.Unstructured {
+	certificate := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"secretName": name,
+				"dnsNames":   []interface{}{host},
+				"issuerRef": map[string]interface{}{
+					"group": certificateResource.Group,
+					"kind":  issuer.Kind,
+					"name":  issuer.Name,
+				},
+			},
+		},
+	}
+	certificate.SetAPIVersion(certificateResource.GroupVersion().String())
+	certificate.SetKind("Certificate")
+	certificate.SetNamespace(namespace)
+	certificate.SetName(name)
+
+	return certificate
+}
+
+// certificateCondition translates the Ready condition of a Certificate into the
+// CertificateReady condition of the eventprovider
+func certificateCondition(certificate *unstructured.Unstructured) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    v1alpha1.ConditionCertificateReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Pending",
+		Message: fmt.Sprintf("waiting for cert-manager to issue certificate %s", certificate.GetName()),
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	for _, c := range conditions {
+		certificateCondition, ok := c.(map[string]interface{})
+		if !ok || certificateCondition["type"] != "Ready" {
+			continue
+		}
+
+		if certificateCondition["status"] == "True" {
+			condition.Status = metav1.ConditionTrue
+		}
+		if reason, ok := certificateCondition["reason"].(string); ok && reason != "" {
+			condition.Reason = reason
+		}
+		if message, ok := certificateCondition["message"].(string); ok && message != "" {
+			condition.Message = message
+		}
+	}
+
+	return condition
+}
+
+// certificateIssuer returns the issuer of the handler certificate, falling back to the operator-wide issuer
+func certificateIssuer(ep *v1alpha1.EventProvider, config ControllerConfig) v1alpha1.IssuerReference {
+	issuer := ep.Spec.TLS.IssuerRef
+	if issuer.Name == "" || exposureMode(ep, config) == v1alpha1.ExposureModeSharedIngress {
+		issuer = config.CertificateIssuer
+	}
+	if issuer.Kind == "" {
+		issuer.Kind = "Issuer"
+	}
+
+	return issuer
+}
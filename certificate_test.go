@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSyncCertificate(t *testing.T) {
+	ep := newTestEventProvider("handler")
+	ep.Spec.Host = "events.example.com"
+	ep.Spec.TLS.IssuerRef = v1alpha1.IssuerReference{Name: "letsencrypt", Kind: "ClusterIssuer"}
+
+	sync := func(existing *unstructured.Unstructured) (*unstructured.Unstructured, int) {
+		var objects []runtime.Object
+		if existing != nil {
+			objects = append(objects, existing)
+		}
+		c, _ := newTestController(t, ep)
+		client, lister := newTestDynamicClient(t, c, certificateResource, "CertificateList", objects...)
+		c.certificateLister = lister
+		if _, _, err := c.syncCertificate(ep); err != nil {
+			t.Fatalf("cannot sync certificate: %v", err)
+		}
+		certificate, err := client.Resource(certificateResource).Namespace("default").Get(context.TODO(), ingressName(ep), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var writes int
+		for _, action := range client.Actions() {
+			if action.GetVerb() != "get" {
+				writes++
+			}
+		}
+		return certificate, writes
+	}
+
+	certificate, _ := sync(nil)
+	if issuer, _, _ := unstructured.NestedString(certificate.Object, "spec", "issuerRef", "name"); issuer != "letsencrypt" {
+		t.Fatalf("got issuer %q", issuer)
+	}
+
+	// fields defaulted by cert-manager are not drift
+	if err := unstructured.SetNestedField(certificate.Object, "RSA", "spec", "privateKey", "algorithm"); err != nil {
+		t.Fatal(err)
+	}
+	if _, writes := sync(certificate); writes != 0 {
+		t.Errorf("up to date: got %d writes", writes)
+	}
+
+	// the issuer changed, and the DNS names were edited by hand
+	ep.Spec.TLS.IssuerRef = v1alpha1.IssuerReference{Name: "internal", Kind: "Issuer"}
+	if err := unstructured.SetNestedStringSlice(certificate.Object, []string{"stale.example.com"}, "spec", "dnsNames"); err != nil {
+		t.Fatal(err)
+	}
+	updated, writes := sync(certificate)
+	issuer, _, _ := unstructured.NestedString(updated.Object, "spec", "issuerRef", "name")
+	kind, _, _ := unstructured.NestedString(updated.Object, "spec", "issuerRef", "kind")
+	dnsNames, _, _ := unstructured.NestedStringSlice(updated.Object, "spec", "dnsNames")
+	algorithm, _, _ := unstructured.NestedString(updated.Object, "spec", "privateKey", "algorithm")
+	if writes != 1 || issuer != "internal" || kind != "Issuer" || len(dnsNames) != 1 || dnsNames[0] != "events.example.com" || algorithm != "RSA" {
+		t.Errorf("got %d writes and certificate %+v", writes, updated.Object)
+	}
+}
@@ -0,0 +1,59 @@
+// The receiver runs next to an Event Grid handler, answers the Event Grid
+// handshakes on its behalf and proxies the actual events to it.
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/eventgrid/receiver"
+)
+
+var (
+	// listenAddress is the address the receiver listens on
+	listenAddress = getEnvVarOrDefault("RECEIVER_ADDR", ":9180")
+	// handlerURL is the URL of the handler events are forwarded to
+	handlerURL = getEnvVarOrDefault("HANDLER_URL", "http://localhost:80")
+	// allowedOrigin is the origin allowed to deliver CloudEvents
+	allowedOrigin = getEnvVarOrDefault("ALLOWED_ORIGIN", "*")
+)
+
+func main() {
+	flag.Parse()
+
+	handler, err := newReceiver(handlerURL, allowedOrigin)
+	if err != nil {
+		glog.Fatalf("Error parsing HANDLER_URL: %s", err.Error())
+	}
+
+	glog.Infof("Forwarding events received on %s to %s", listenAddress, handlerURL)
+	if err := http.ListenAndServe(listenAddress, handler); err != nil {
+		glog.Fatalf("Error running receiver: %s", err.Error())
+	}
+}
+
+// newReceiver returns the receiver proxying events to the handler at handlerURL
+func newReceiver(handlerURL, allowedOrigin string) (http.Handler, error) {
+	target, err := url.Parse(handlerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := receiver.NewHandler(httputil.NewSingleHostReverseProxy(target))
+	handler.AllowedOrigin = allowedOrigin
+
+	return handler, nil
+}
+
+func getEnvVarOrDefault(varName, defaultValue string) string {
+	value, ok := os.LookupEnv(varName)
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
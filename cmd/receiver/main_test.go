@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/eventgrid/receiver"
+)
+
+// eventHandler is the Event Grid handler the receiver runs next to, recording the deliveries it gets
+type eventHandler struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	deliveries []*http.Request
+	bodies     []string
+}
+
+func newEventHandler(t *testing.T) *eventHandler {
+	h := &eventHandler{}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.deliveries = append(h.deliveries, r)
+		h.bodies = append(h.bodies, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+func (h *eventHandler) received() ([]*http.Request, []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.deliveries, h.bodies
+}
+
+func TestReceiver(t *testing.T) {
+	handler := newEventHandler(t)
+	r, err := newReceiver(handler.URL, "eventgrid.azure.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	send := func(method, body string, header map[string]string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(method, srv.URL+"/api/events", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+
+		return resp
+	}
+
+	// the subscription validation is answered by the receiver
+	resp := send(http.MethodPost, `[{"id":"1","topic":"/t","eventType":"Microsoft.EventGrid.SubscriptionValidationEvent","eventTime":"2020-01-02T03:04:05Z","data":{"validationCode":"code"}}]`, map[string]string{"aeg-event-type": "SubscriptionValidation"})
+	var validation receiver.SubscriptionValidationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&validation); err != nil || validation.ValidationResponse != "code" {
+		t.Errorf("got validation response %+v: %v", validation, err)
+	}
+
+	// so is the abuse protection handshake, for the allowed origin only
+	resp = send(http.MethodOptions, "", map[string]string{"WebHook-Request-Origin": "eventgrid.azure.net"})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("WebHook-Allowed-Origin") != "eventgrid.azure.net" {
+		t.Errorf("got status %d and allowed origin %q", resp.StatusCode, resp.Header.Get("WebHook-Allowed-Origin"))
+	}
+	if resp = send(http.MethodOptions, "", map[string]string{"WebHook-Request-Origin": "attacker.example.com"}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %d for another origin", resp.StatusCode)
+	}
+
+	if deliveries, _ := handler.received(); len(deliveries) != 0 {
+		t.Fatalf("the handshakes were forwarded to the handler")
+	}
+
+	// events are proxied to the handler, without the lifecycle events
+	resp = send(http.MethodPost, `[
+		{"id":"2","topic":"/t","eventType":"Microsoft.EventGrid.SubscriptionDeletedEvent","eventTime":"2020-01-02T03:04:05Z","data":{}},
+		{"id":"3","topic":"/t","subject":"/blobs/a","eventType":"Microsoft.Storage.BlobCreated","eventTime":"2020-01-02T03:04:05Z","data":{"api":"PutBlob"}}
+	]`, map[string]string{"aeg-event-type": "Notification", "Content-Type": "application/json"})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("got status %d, the status of the handler was not proxied", resp.StatusCode)
+	}
+
+	deliveries, bodies := handler.received()
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries", len(deliveries))
+	}
+	if deliveries[0].URL.Path != "/api/events" || deliveries[0].Header.Get("aeg-event-type") != "Notification" {
+		t.Errorf("got delivery to %s with headers %v", deliveries[0].URL.Path, deliveries[0].Header)
+	}
+	var events []receiver.Event
+	if err := json.Unmarshal([]byte(bodies[0]), &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].ID != "3" {
+		t.Errorf("got events %+v", events)
+	}
+
+	if _, err := newReceiver("http://[::1", "*"); err == nil {
+		t.Errorf("no error for an invalid handler URL")
+	}
+}
+
+func TestGetEnvVarOrDefault(t *testing.T) {
+	t.Setenv("RECEIVER_TEST_SET", "set")
+	t.Setenv("RECEIVER_TEST_EMPTY", "")
+
+	if value := getEnvVarOrDefault("RECEIVER_TEST_SET", "default"); value != "set" {
+		t.Errorf("got %q", value)
+	}
+	// set but empty is kept
+	if value := getEnvVarOrDefault("RECEIVER_TEST_EMPTY", "default"); value != "" {
+		t.Errorf("got %q", value)
+	}
+	if value := getEnvVarOrDefault("RECEIVER_TEST_UNSET", "default"); value != "default" {
+		t.Errorf("got %q", value)
+	}
+}
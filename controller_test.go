@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	epfake "github.com/radu-matei/events-operator/pkg/client/clientset/versioned/fake"
+	informers "github.com/radu-matei/events-operator/pkg/client/informers/externalversions"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/source"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newTestController returns a controller backed by fake clientsets. The listers are filled
+// with objects, and are not updated by the clientsets.
+func newTestController(t *testing.T, objects ...runtime.Object) (*Controller, *k8sfake.Clientset) {
+	var kubeObjects, epObjects []runtime.Object
+	for _, obj := range objects {
+		if _, ok := obj.(*v1alpha1.EventProvider); ok {
+			epObjects = append(epObjects, obj)
+		} else {
+			kubeObjects = append(kubeObjects, obj)
+		}
+	}
+
+	kubeclient := k8sfake.NewSimpleClientset(kubeObjects...)
+	epclient := epfake.NewSimpleClientset(epObjects...)
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeclient, 0)
+	epInformerFactory := informers.NewSharedInformerFactory(epclient, 0)
+
+	services := kubeInformerFactory.Core().V1().Services()
+	secrets := kubeInformerFactory.Core().V1().Secrets()
+	deployments := kubeInformerFactory.Apps().V1().Deployments()
+	ingresses := kubeInformerFactory.Networking().V1().Ingresses()
+	eps := epInformerFactory.Eventprovider().V1alpha1().EventProviders()
+	for _, obj := range objects {
+		var err error
+		switch obj.(type) {
+		case *corev1.Service:
+			err = services.Informer().GetIndexer().Add(obj)
+		case *corev1.Secret:
+			err = secrets.Informer().GetIndexer().Add(obj)
+		case *appsv1.Deployment:
+			err = deployments.Informer().GetIndexer().Add(obj)
+		case *networkingv1.Ingress:
+			err = ingresses.Informer().GetIndexer().Add(obj)
+		case *v1alpha1.EventProvider:
+			err = eps.Informer().GetIndexer().Add(obj)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dispatcher := gateway.NewDispatcher(time.Second)
+	c := &Controller{
+		kubeclientset: kubeclient,
+		epclientset:   epclient,
+		config: ControllerConfig{
+			IngressAPI:        ingressAPINetworkingV1,
+			OperatorNamespace: "events-operator",
+			ClusterDomain:     "cluster.local",
+			GatewayService:    "events-operator-gateway",
+			GatewayPort:       80,
+			ContentMode:       cloudevents.ModeBinary,
+		},
+		gateway:           gateway.New(dispatcher),
+		sources:           source.NewManager(dispatcher),
+		epLister:          eps.Lister(),
+		deploymentsLister: deployments.Lister(),
+		servicesLister:    services.Lister(),
+		secretsLister:     secrets.Lister(),
+		ingressAPI:        ingressAPINetworkingV1,
+		ingressLister:     ingresses.Lister(),
+	}
+
+	return c, kubeclient
+}
+
+// newTestDynamicClient sets the dynamic clientset of the controller to a fake one serving objects,
+// and returns it with a lister of resource filled with the same objects
+func newTestDynamicClient(t *testing.T, c *Controller, resource schema.GroupVersionResource, listKind string, objects ...runtime.Object) (*dynamicfake.FakeDynamicClient, cache.GenericLister) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{resource: listKind}, objects...)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objects {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.dynamicclientset = client
+
+	return client, cache.NewGenericLister(indexer, resource.GroupResource())
+}
+
+// newTestEventProvider returns an eventprovider in namespace default
+func newTestEventProvider(name string) *v1alpha1.EventProvider {
+	return &v1alpha1.EventProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			UID:       types.UID(name + "-uid"),
+		},
+	}
+}
+
+// getService returns a service from the fake clientset
+func getService(t *testing.T, client *k8sfake.Clientset, namespace, name string) *corev1.Service {
+	service, err := client.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("cannot get service %s/%s: %v", namespace, name, err)
+	}
+
+	return service
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/checkpoints"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/eventhubs"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// providerEventHubs is the provider name of eventproviders consuming Azure Event Hubs
+	providerEventHubs = "eventhubs"
+
+	// defaultEventHubsBatchSize is how many events are received at once from a partition, by default
+	defaultEventHubsBatchSize = 100
+)
+
+// syncEventHubs runs the consumer of the event hub of the eventprovider. Every replica of the
+// operator runs a consumer, and the partitions of the event hub are balanced between them.
+func (c *Controller) syncEventHubs(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.EventHubs
+	if spec == nil {
+		return fmt.Errorf("the eventhubs provider requires an eventHubs spec")
+	}
+	if spec.EventHub == "" {
+		return fmt.Errorf("an event hub is required")
+	}
+
+	start, err := eventHubsStartPosition(spec.StartPosition)
+	if err != nil {
+		return err
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("cannot get the identity of the operator replica: %v", err)
+	}
+
+	src := &eventhubs.Source{
+		Namespace:     spec.Namespace,
+		EventHub:      spec.EventHub,
+		ConsumerGroup: spec.ConsumerGroup,
+		StartPosition: start,
+		MaxBatchSize:  defaultEventHubsBatchSize,
+		Identity:      identity,
+		Retry:         retryPolicy(spec.Retry),
+		EventSource:   eventProviderURI(ep),
+	}
+	if src.ConsumerGroup == "" {
+		src.ConsumerGroup = azeventhubs.DefaultConsumerGroup
+	}
+	if spec.MaxBatchSize > 0 {
+		src.MaxBatchSize = int(spec.MaxBatchSize)
+	}
+
+	// the namespace is authorized with a connection string, or with the
+	// service principal of the eventprovider
+	secret, err := c.azureSecret(ep)
+	if err != nil {
+		return err
+	}
+	tokenCredential := func() (azcore.TokenCredential, error) {
+		credentials, err := azureCredentials(secret)
+		if err != nil {
+			return nil, err
+		}
+		return credentials.TokenCredential()
+	}
+	if secret != nil && len(secret.Data["connectionString"]) > 0 {
+		src.ConnectionString = string(secret.Data["connectionString"])
+	} else {
+		if spec.Namespace == "" {
+			return fmt.Errorf("an Event Hubs namespace is required")
+		}
+		if src.Credential, err = tokenCredential(); err != nil {
+			return err
+		}
+	}
+
+	// checkpoints are kept in a blob container of the storage account, authorized with its
+	// shared key or the service principal, or in a configmap
+	if spec.CheckpointContainer != "" {
+		if ep.Spec.StorageAccount == "" {
+			return fmt.Errorf("a storage account is required to checkpoint in container %s", spec.CheckpointContainer)
+		}
+		containerURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s", ep.Spec.StorageAccount, spec.CheckpointContainer)
+
+		var client *container.Client
+		if secret != nil && len(secret.Data["accountKey"]) > 0 {
+			key, err := container.NewSharedKeyCredential(ep.Spec.StorageAccount, string(secret.Data["accountKey"]))
+			if err != nil {
+				return fmt.Errorf("invalid storage account key: %v", err)
+			}
+			client, err = container.NewClientWithSharedKeyCredential(containerURL, key, nil)
+			if err != nil {
+				return fmt.Errorf("cannot create blob container client: %v", err)
+			}
+		} else {
+			token, err := tokenCredential()
+			if err != nil {
+				return err
+			}
+			if client, err = container.NewClient(containerURL, token, nil); err != nil {
+				return fmt.Errorf("cannot create blob container client: %v", err)
+			}
+		}
+
+		if src.CheckpointStore, err = checkpoints.NewBlobStore(client, nil); err != nil {
+			return fmt.Errorf("cannot create blob checkpoint store: %v", err)
+		}
+	} else {
+		owner := *metav1.NewControllerRef(ep, v1alpha1.SchemeGroupVersion.WithKind("EventProvider"))
+		src.CheckpointStore = eventhubs.NewConfigMapStore(c.kubeclientset, ep.Namespace, fmt.Sprintf("%s-eventhubs", ep.Name), owner)
+	}
+
+	return c.syncSource(ep, src, spec, ep.Spec.StorageAccount, resourceVersion(secret))
+}
+
+// eventHubsStartPosition parses where partitions without a checkpoint are consumed from: Latest,
+// the default, Earliest, or an RFC 3339 enqueued time
+func eventHubsStartPosition(position string) (azeventhubs.StartPosition, error) {
+	switch {
+	case position == "" || strings.EqualFold(position, "Latest"):
+		latest := true
+		return azeventhubs.StartPosition{Latest: &latest}, nil
+	case strings.EqualFold(position, "Earliest"):
+		earliest := true
+		return azeventhubs.StartPosition{Earliest: &earliest}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, position)
+	if err != nil {
+		return azeventhubs.StartPosition{}, fmt.Errorf("invalid start position %q: Latest, Earliest or an RFC 3339 time are expected", position)
+	}
+
+	return azeventhubs.StartPosition{EnqueuedTime: &t, Inclusive: true}, nil
+}
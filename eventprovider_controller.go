@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -11,23 +12,32 @@ import (
 	sscheme "github.com/radu-matei/events-operator/pkg/client/clientset/versioned/scheme"
 	informers "github.com/radu-matei/events-operator/pkg/client/informers/externalversions"
 	listers "github.com/radu-matei/events-operator/pkg/client/listers/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
 	eventgrid "github.com/radu-matei/events-operator/pkg/eventgrid"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/source"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
-	extensionlisters "k8s.io/client-go/listers/extensions/v1beta1"
+	extensionslisters "k8s.io/client-go/listers/extensions/v1beta1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -35,10 +45,52 @@ import (
 
 const controllerAgentName = "eventprovider_controller"
 
+// ControllerConfig holds the operator-wide settings of the controller
+type ControllerConfig struct {
+	// IngressAPI is the group version used for Ingress resources, as discovered from the cluster
+	IngressAPI string
+	// IngressClass is the default IngressClass for the handlers' Ingresses
+	IngressClass string
+	// IngressAnnotations are added to every Ingress created by the operator
+	IngressAnnotations map[string]string
+	// HTTPRouteAPI is true when the cluster serves the Gateway API HTTPRoute resource
+	HTTPRouteAPI bool
+	// HTTPRouteParent is the default Gateway HTTPRoutes attach to
+	HTTPRouteParent v1alpha1.ParentReference
+	// CertificateAPI is true when cert-manager is installed in the cluster
+	CertificateAPI bool
+	// CertificateIssuer is the default issuer of handler certificates. When empty,
+	// certificates are only managed for eventproviders setting their own issuer.
+	CertificateIssuer v1alpha1.IssuerReference
+	// SharedHost is the host eventproviders without a host of their own are exposed on
+	SharedHost string
+	// OperatorNamespace is the namespace of the shared Ingress
+	OperatorNamespace string
+	// ClusterDomain is the DNS domain of the cluster, used to resolve services across namespaces
+	ClusterDomain string
+	// ReceiverImage is the image of the receiver sidecar
+	ReceiverImage string
+	// GatewayService is the name of the service in front of the event gateway, in the operator namespace
+	GatewayService string
+	// GatewayPort is the port of the service in front of the event gateway
+	GatewayPort int32
+	// ContentMode is the default CloudEvents content mode events are dispatched to sinks in
+	ContentMode cloudevents.Mode
+	// GitHubAPIURL is the default base URL of the GitHub REST API
+	GitHubAPIURL string
+}
+
 // Controller is the controller implementation for Foo resources
 type Controller struct {
-	kubeclientset kubernetes.Interface
-	epclientset   clientset.Interface
+	kubeclientset    kubernetes.Interface
+	epclientset      clientset.Interface
+	dynamicclientset dynamic.Interface
+
+	config  ControllerConfig
+	gateway *gateway.Gateway
+	sources *source.Manager
+
+	restMapper meta.RESTMapper
 
 	epLister listers.EventProviderLister
 	epSynced cache.InformerSynced
@@ -49,8 +101,22 @@ type Controller struct {
 	servicesLister corelisters.ServiceLister
 	servicesSynced cache.InformerSynced
 
-	ingressLister extensionlisters.IngressLister
-	ingressSynced cache.InformerSynced
+	secretsLister corelisters.SecretLister
+	secretsSynced cache.InformerSynced
+
+	ingressAPI          string
+	ingressLister       networkinglisters.IngressLister
+	legacyIngressLister extensionslisters.IngressLister
+	ingressSynced       cache.InformerSynced
+
+	httpRouteLister cache.GenericLister
+	httpRouteSynced cache.InformerSynced
+
+	certificateLister cache.GenericLister
+	certificateSynced cache.InformerSynced
+
+	// snsSubscriptions backs off the subscriptions of SNS endpoints pending confirmation
+	snsSubscriptions snsSubscriptions
 
 	queue    workqueue.RateLimitingInterface
 	recorder record.EventRecorder
@@ -60,16 +126,22 @@ type Controller struct {
 func NewController(
 	kubeclientset kubernetes.Interface,
 	epclientset clientset.Interface,
+	dynamicclientset dynamic.Interface,
 
 	kubeInformerFactory kubeinformers.SharedInformerFactory,
-	epInformerFactory informers.SharedInformerFactory) *Controller {
+	epInformerFactory informers.SharedInformerFactory,
+	dynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory,
+
+	config ControllerConfig,
+	eventGateway *gateway.Gateway,
+	sources *source.Manager) *Controller {
 
 	epInformer := epInformerFactory.Eventprovider().V1alpha1().EventProviders()
 	sscheme.AddToScheme(scheme.Scheme)
 
 	deploymentInformer := kubeInformerFactory.Apps().V1().Deployments()
 	serviceInformer := kubeInformerFactory.Core().V1().Services()
-	ingressInformer := kubeInformerFactory.Extensions().V1beta1().Ingresses()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
 
 	glog.V(4).Info("Creating event broadcaster")
 	eventBroadcaster := record.NewBroadcaster()
@@ -78,8 +150,15 @@ func NewController(
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
 
 	c := &Controller{
-		kubeclientset: kubeclientset,
-		epclientset:   epclientset,
+		kubeclientset:    kubeclientset,
+		epclientset:      epclientset,
+		dynamicclientset: dynamicclientset,
+
+		config:  config,
+		gateway: eventGateway,
+		sources: sources,
+
+		restMapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(kubeclientset.Discovery())),
 
 		epLister: epInformer.Lister(),
 		epSynced: epInformer.Informer().HasSynced,
@@ -90,32 +169,82 @@ func NewController(
 		servicesLister: serviceInformer.Lister(),
 		servicesSynced: serviceInformer.Informer().HasSynced,
 
-		ingressLister: ingressInformer.Lister(),
-		ingressSynced: ingressInformer.Informer().HasSynced,
+		secretsLister: secretInformer.Lister(),
+		secretsSynced: secretInformer.Informer().HasSynced,
+
+		ingressAPI: config.IngressAPI,
 
 		queue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "EventProviders"),
 		recorder: recorder,
 	}
 
+	// Only watch the Ingress API the cluster actually serves
+	if c.ingressAPI == ingressAPIExtensionsV1beta1 {
+		ingressInformer := kubeInformerFactory.Extensions().V1beta1().Ingresses()
+		c.legacyIngressLister = ingressInformer.Lister()
+		c.ingressSynced = ingressInformer.Informer().HasSynced
+	} else {
+		ingressInformer := kubeInformerFactory.Networking().V1().Ingresses()
+		c.ingressLister = ingressInformer.Lister()
+		c.ingressSynced = ingressInformer.Informer().HasSynced
+	}
+
+	// HTTPRoutes can only be watched when the Gateway API is installed
+	if config.HTTPRouteAPI {
+		httpRouteInformer := dynamicInformerFactory.ForResource(httpRouteResource)
+		c.httpRouteLister = httpRouteInformer.Lister()
+		c.httpRouteSynced = httpRouteInformer.Informer().HasSynced
+
+		// Requeue the owning EventProvider when the route status changes
+		httpRouteInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: c.handleObject,
+			UpdateFunc: func(old, new interface{}) {
+				c.handleObject(new)
+			},
+			DeleteFunc: c.handleObject,
+		})
+	}
+
+	// Certificates can only be watched when cert-manager is installed
+	if config.CertificateAPI {
+		certificateInformer := dynamicInformerFactory.ForResource(certificateResource)
+		c.certificateLister = certificateInformer.Lister()
+		c.certificateSynced = certificateInformer.Informer().HasSynced
+
+		// Requeue the owning EventProvider when the certificate status changes
+		certificateInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: c.handleObject,
+			UpdateFunc: func(old, new interface{}) {
+				c.handleObject(new)
+			},
+			DeleteFunc: c.handleObject,
+		})
+	}
+
+	// Requeue the eventprovider when the state of its source changes, to report it
+	sources.OnChange = func(namespace, name string) {
+		c.queue.Add(fmt.Sprintf("%s/%s", namespace, name))
+	}
+
 	glog.Info("Setting up event handlers")
 	// Set up an event handler for when EventProvider resources change
 	epInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			glog.Info("AddFunc called with object: %v", obj)
+			glog.Infof("AddFunc called with object: %v", obj)
 			key, err := cache.MetaNamespaceKeyFunc(obj)
 			if err == nil {
 				c.queue.Add(key)
 			}
 		},
 		UpdateFunc: func(old interface{}, new interface{}) {
-			glog.Info("UpdateFunc called with objects: %v, %v", old, new)
+			glog.Infof("UpdateFunc called with objects: %v, %v", old, new)
 			key, err := cache.MetaNamespaceKeyFunc(new)
 			if err == nil {
 				c.queue.Add(key)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
-			glog.Info("DeleteFunc called with object: %v", obj)
+			glog.Infof("DeleteFunc called with object: %v", obj)
 			// IndexerInformer uses a delta nodeQueue, therefore for deletes we have to use this
 			// key function.
 			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
@@ -141,7 +270,14 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 
 	// Wait for the caches to be synced before starting workers
 	glog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.epSynced, c.deploymentsSynced, c.servicesSynced); !ok {
+	cacheSyncs := []cache.InformerSynced{c.epSynced, c.deploymentsSynced, c.servicesSynced, c.secretsSynced, c.ingressSynced}
+	if c.httpRouteSynced != nil {
+		cacheSyncs = append(cacheSyncs, c.httpRouteSynced)
+	}
+	if c.certificateSynced != nil {
+		cacheSyncs = append(cacheSyncs, c.certificateSynced)
+	}
+	if ok := cache.WaitForCacheSync(stopCh, cacheSyncs...); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
@@ -158,6 +294,29 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	return nil
 }
 
+// handleObject will take any resource implementing metav1.Object and attempt
+// to find the EventProvider resource that 'owns' it. It does this by looking at
+// the objects metadata.ownerReferences field for an appropriate OwnerReference,
+// and enqueues that EventProvider to be processed.
+func (c *Controller) handleObject(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("error decoding object, invalid type %T", obj))
+		return
+	}
+
+	ownerRef := metav1.GetControllerOf(object)
+	if ownerRef == nil || ownerRef.Kind != "EventProvider" {
+		return
+	}
+
+	c.queue.Add(fmt.Sprintf("%s/%s", object.GetNamespace(), ownerRef.Name))
+}
+
 // runWorker is a long-running function that will continually call the
 // processNextWorkItem function in order to read and process a message on the
 // workqueue.
@@ -229,114 +388,298 @@ func (c *Controller) syncHandler(key string) error {
 		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
 		return nil
 	}
-	fmt.Printf("\nReceived: namespace: %v, name: %v\n", namespace, name)
+	glog.V(4).Infof("syncing eventprovider %s", key)
 
 	ep, err := c.epLister.EventProviders(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		// the eventprovider was deleted, drop its route and its path on the shared ingress
+		glog.Infof("eventprovider '%s' no longer exists", key)
+		c.gateway.DeleteRoute(namespace, name)
+		c.sources.Stop(namespace, name)
+		return c.syncSharedIngress()
+	}
 	if err != nil {
 		return fmt.Errorf("error getting resource: %v", err)
 	}
-	fmt.Printf("eventprovider: %v", ep)
+
+	if ep.DeletionTimestamp != nil {
+		return c.finalize(ep)
+	}
 
 	switch ep.Spec.ProviderName {
-	case "eventgrid.azure.com":
-		if ep.Spec.EventType != "Microsoft.Storage" {
-			return fmt.Errorf("can only handle storage events")
-		}
+	case gateway.ProviderEventGrid:
+		return c.syncEventGrid(ep)
+	case gateway.ProviderWebhook:
+		return c.syncWebhook(ep)
+	case gateway.ProviderGitHub:
+		return c.syncGitHub(ep)
+	case gateway.ProviderGitLab:
+		return c.syncGitLab(ep)
+	case gateway.ProviderGitea:
+		return c.syncGitea(ep)
+	case gateway.ProviderSNS:
+		return c.syncSNS(ep)
+	case gateway.ProviderPubSub:
+		return c.syncPubSub(ep)
+	case providerSchedule:
+		return c.syncSchedule(ep)
+	case providerKubernetes:
+		return c.syncKubernetes(ep)
+	case providerKafka:
+		return c.syncKafka(ep)
+	case providerNATS:
+		return c.syncNATS(ep)
+	case providerMQTT:
+		return c.syncMQTT(ep)
+	case providerRedisStreams:
+		return c.syncRedisStreams(ep)
+	case providerAMQP:
+		return c.syncAMQP(ep)
+	case providerStorageQueue:
+		return c.syncStorageQueue(ep)
+	case providerServiceBus:
+		return c.syncServiceBus(ep)
+	case providerEventHubs:
+		return c.syncEventHubs(ep)
+	case gateway.ProviderS3:
+		return c.syncS3(ep)
 
-		// TODO - also check deployment, service and ingress health
+	default:
+		return fmt.Errorf("cannot handle provider %v", ep.Spec.ProviderName)
+	}
+}
 
-		// first check for deployment
-		deploymentName := fmt.Sprintf("%s%sdeployment", ep.Name, ep.Spec.StorageAccount)
-		deployment, err := c.deploymentsLister.Deployments(ep.Namespace).Get(deploymentName)
-		// If the resource doesn't exist, we'll create it
-		if errors.IsNotFound(err) {
-			deployment, err = c.kubeclientset.AppsV1().Deployments(ep.Namespace).Create(newDeployment(ep, deploymentName))
-		}
-		fmt.Printf("deployment name: %v", deployment.Name)
+// syncEventGrid makes sure the Event Grid subscription of the eventprovider
+// delivers events to its public endpoint
+func (c *Controller) syncEventGrid(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.EventType != "Microsoft.Storage" {
+		return fmt.Errorf("can only handle storage events")
+	}
+
+	ep, endpointURL, err := c.syncEndpoint(ep, gateway.Route{})
+	if err != nil {
+		return err
+	}
+
+	// The endpoint is not ready yet, the eventprovider is queued again when it is
+	if endpointURL == "" {
+		return nil
+	}
 
-		// If an error occurs during Get/Create, we'll requeue the item so we can
-		// attempt processing again later. This could have been caused by a
-		// temporary network failure, or any other transient reason.
+	name := fmt.Sprintf("%seventsubscription", ep.Spec.StorageAccount)
+	// check eventsubscription exists for given storage account
+	exists, err := eventgrid.CheckEventSubscription(name, ep.Spec.ResourceGroup, ep.Spec.StorageAccount, endpointURL)
+	if err != nil {
+		glog.V(4).Infof("cannot check eventgrid subscription %s: %v", name, err)
+	}
+	// if the eventsubscription does not exist, create it
+	if !exists {
+		err = eventgrid.CreateOrUpdateEventSubscription(ep.Spec.ResourceGroup, ep.Spec.StorageAccount, endpointURL)
 		if err != nil {
-			fmt.Printf("%v", err)
-			return err
+			return fmt.Errorf("cannot create eventgrid subscription %s: %v", name, err)
 		}
+	}
 
-		// check the service
-		serviceName := fmt.Sprintf("%s%sservice", ep.Name, ep.Spec.StorageAccount)
-		service, err := c.servicesLister.Services(ep.Namespace).Get(serviceName)
-		// If the resource doesn't exist, we'll create it
-		if errors.IsNotFound(err) {
-			service, err = c.kubeclientset.CoreV1().Services(ep.Namespace).Create(newService(ep, serviceName, deploymentName))
-		}
-		fmt.Printf("service name: %v", service.Name)
+	return nil
+}
 
-		// If an error occurs during Get/Create, we'll requeue the item so we can
-		// attempt processing again later. This could have been caused by a
-		// temporary network failure, or any other transient reason.
-		if err != nil {
-			fmt.Printf("%v", err)
-			return err
+// syncEndpoint makes sure events for the eventprovider can be received from outside of
+// the cluster - either by its own handler, or by the event gateway when it has a sink -
+// and returns the public URL of the endpoint. An empty URL means the endpoint is not ready yet.
+// route holds the provider specific settings of the gateway route, the rest is filled in here.
+func (c *Controller) syncEndpoint(ep *v1alpha1.EventProvider, route gateway.Route) (*v1alpha1.EventProvider, string, error) {
+	// events received by the endpoint are not produced by a source
+	c.sources.Stop(ep.Namespace, ep.Name)
+
+	var err error
+	if ep.Spec.Sink != nil {
+		err = c.syncGatewayBackend(ep)
+	} else {
+		err = c.syncHandlerWorkload(ep)
+	}
+
+	// If an error occurs during Get/Create, we'll requeue the item so we can
+	// attempt processing again later. This could have been caused by a
+	// temporary network failure, or any other transient reason.
+	if err != nil {
+		return ep, "", err
+	}
+
+	// events are delivered to a secret path when they go through the gateway,
+	// and eventproviders exposed on the shared host are told apart by their path
+	if ep.Spec.Sink != nil || exposureMode(ep, c.config) == v1alpha1.ExposureModeSharedIngress {
+		if ep, err = c.syncPath(ep); err != nil {
+			return ep, "", err
 		}
+	}
+
+	// expose the service and work out the public URL of the endpoint
+	var endpointURL string
+	ep, endpointURL, err = c.syncExposure(ep, backendServiceName(ep, c.config))
+	if err != nil || endpointURL == "" {
+		return ep, "", err
+	}
+
+	// Event Grid validates the endpoint as soon as the subscription is created,
+	// so wait until the certificate of the handler was issued
+	var certificateReady bool
+	ep, certificateReady, err = c.syncCertificate(ep)
+	if err != nil || !certificateReady {
+		return ep, "", err
+	}
 
-		// check ingress
-		ingressName := fmt.Sprintf("%s%singress", ep.Name, ep.Spec.Host)
-		ingress, err := c.ingressLister.Ingresses(ep.Namespace).Get(ingressName)
-		if errors.IsNotFound(err) {
-			ingress, err = c.kubeclientset.ExtensionsV1beta1().Ingresses(ep.Namespace).Create(newIngress(ep, ingressName, serviceName))
+	var sinkURL string
+	if ep.Spec.Sink != nil {
+		if sinkURL, err = c.resolveSink(ep); err != nil {
+			return ep, "", err
 		}
-		fmt.Printf("ingress name: %v", ingress.Name)
 
-		// If an error occurs during Get/Create, we'll requeue the item so we can
-		// attempt processing again later. This could have been caused by a
-		// temporary network failure, or any other transient reason.
+		mode, err := c.sinkMode(ep)
 		if err != nil {
-			fmt.Printf("%v", err)
-			return err
+			return ep, "", err
 		}
 
-		name := fmt.Sprintf("%seventsubscription", ep.Spec.StorageAccount)
-		tlsWebhook := fmt.Sprintf("https://%s", ep.Spec.Host)
-		// check eventsubscription exists for given storage account
-		exists, err := eventgrid.CheckEventSubscription(name, ep.Spec.ResourceGroup, ep.Spec.StorageAccount, tlsWebhook)
-		if err != nil {
-			fmt.Printf("cannot check eventgrid subscription: %v", err)
+		route.Namespace = ep.Namespace
+		route.Name = ep.Name
+		route.Provider = ep.Spec.ProviderName
+		route.Path = ep.Status.Path
+		route.Sink = sinkURL
+		route.Mode = mode
+		c.gateway.SetRoute(route)
+	} else {
+		// the sink was removed, events are now received by the handler
+		c.gateway.DeleteRoute(ep.Namespace, ep.Name)
+	}
+
+	ep, err = c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		status.EndpointURL = endpointURL
+		status.SinkURL = sinkURL
+	})
+	if err != nil {
+		return ep, "", fmt.Errorf("cannot update eventprovider status: %v", err)
+	}
+
+	return ep, endpointURL, nil
+}
+
+// syncHandlerWorkload makes sure the deployment running hostImage and the service in front of it exist
+func (c *Controller) syncHandlerWorkload(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.HostImage == "" {
+		return fmt.Errorf("either a hostImage or a sink is required")
+	}
+	if ep.Spec.Handler.Receiver && c.config.ReceiverImage == "" {
+		return fmt.Errorf("no receiver image configured for the operator")
+	}
+	if ep.Spec.Handler.Receiver && handlerPort(ep) == receiverPort {
+		return fmt.Errorf("the handler cannot listen on port %d, which is used by the receiver", receiverPort)
+	}
+
+	// TODO - also check deployment, service and ingress health
+
+	deploymentName := fmt.Sprintf("%s%sdeployment", ep.Name, ep.Spec.StorageAccount)
+	if err := c.syncHandlerDeployment(newDeployment(ep, deploymentName, c.config), ep.Namespace); err != nil {
+		return fmt.Errorf("cannot sync deployment %s: %v", deploymentName, err)
+	}
+
+	serviceName := handlerServiceName(ep)
+	if err := c.syncHandlerService(newService(ep, serviceName, deploymentName), ep.Namespace); err != nil {
+		return fmt.Errorf("cannot sync service %s: %v", serviceName, err)
+	}
+
+	return nil
+}
+
+// syncHandlerDeployment creates the deployment of the handler, or updates its containers when
+// they drifted. The selector of a deployment is immutable, so it is recreated when it changed.
+func (c *Controller) syncHandlerDeployment(desired *appsv1.Deployment, namespace string) error {
+	deployments := c.kubeclientset.AppsV1().Deployments(namespace)
+	deployment, err := c.deploymentsLister.Deployments(namespace).Get(desired.Name)
+	switch {
+	case errors.IsNotFound(err):
+		_, err = deployments.Create(context.TODO(), desired, metav1.CreateOptions{})
+	case err != nil:
+	case !equality.Semantic.DeepEqual(deployment.Spec.Selector, desired.Spec.Selector):
+		glog.Infof("recreating deployment %s/%s, its selector changed", namespace, desired.Name)
+		if err = deployments.Delete(context.TODO(), desired.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
 		}
-		// if the eventsubscription does not exist, create it
-		if !exists {
-			err = eventgrid.CreateOrUpdateEventSubscription(ep.Spec.ResourceGroup, ep.Spec.StorageAccount, tlsWebhook)
-			if err != nil {
-				fmt.Printf("%v", err)
-				return err
-			}
+		_, err = deployments.Create(context.TODO(), desired, metav1.CreateOptions{})
+	case containersDrifted(deployment.Spec.Template.Spec.Containers, desired.Spec.Template.Spec.Containers):
+		deploymentCopy := deployment.DeepCopy()
+		deploymentCopy.Spec.Template.Spec.Containers = desired.Spec.Template.Spec.Containers
+		_, err = deployments.Update(context.TODO(), deploymentCopy, metav1.UpdateOptions{})
+	default:
+		glog.V(4).Infof("deployment %s/%s is up to date", namespace, desired.Name)
+	}
+
+	return err
+}
+
+// containersDrifted returns true when the containers of a deployment differ from the desired ones,
+// ignoring the fields defaulted by the API server
+func containersDrifted(current, desired []corev1.Container) bool {
+	if len(current) != len(desired) {
+		return true
+	}
+	for i := range desired {
+		if current[i].Name != desired[i].Name ||
+			current[i].Image != desired[i].Image ||
+			!equality.Semantic.DeepEqual(current[i].Command, desired[i].Command) ||
+			!equality.Semantic.DeepEqual(current[i].Env, desired[i].Env) ||
+			!equality.Semantic.DeepEqual(current[i].Ports, desired[i].Ports) {
+			return true
 		}
+	}
+
+	return false
+}
 
+// syncHandlerService creates the service in front of the handler, or updates its type, selector
+// and ports when they drifted. The cluster IP of a service is immutable, so it is recreated when
+// it switches between headless and ClusterIP.
+func (c *Controller) syncHandlerService(desired *corev1.Service, namespace string) error {
+	services := c.kubeclientset.CoreV1().Services(namespace)
+	service, err := c.servicesLister.Services(namespace).Get(desired.Name)
+	switch {
+	case errors.IsNotFound(err):
+		_, err = services.Create(context.TODO(), desired, metav1.CreateOptions{})
+	case err != nil:
+	case (service.Spec.ClusterIP == corev1.ClusterIPNone) != (desired.Spec.ClusterIP == corev1.ClusterIPNone):
+		glog.Infof("recreating service %s/%s, it switched between headless and ClusterIP", namespace, desired.Name)
+		if err = services.Delete(context.TODO(), desired.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = services.Create(context.TODO(), desired, metav1.CreateOptions{})
+	case service.Spec.Type != desired.Spec.Type ||
+		!equality.Semantic.DeepEqual(service.Spec.Selector, desired.Spec.Selector) ||
+		!equality.Semantic.DeepEqual(service.Spec.Ports, desired.Spec.Ports):
+		serviceCopy := service.DeepCopy()
+		serviceCopy.Spec.Type = desired.Spec.Type
+		serviceCopy.Spec.Selector = desired.Spec.Selector
+		serviceCopy.Spec.Ports = desired.Spec.Ports
+		_, err = services.Update(context.TODO(), serviceCopy, metav1.UpdateOptions{})
 	default:
-		return fmt.Errorf("cannot handle provider %v", ep.Spec.ProviderName)
+		glog.V(4).Infof("service %s/%s is up to date", namespace, desired.Name)
 	}
 
-	return nil
+	return err
 }
 
 // newDeployment creates a new Deployment based on an eventprovider
-func newDeployment(ep *v1alpha1.EventProvider, name string) *appsv1.Deployment {
+func newDeployment(ep *v1alpha1.EventProvider, name string, config ControllerConfig) *appsv1.Deployment {
 
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: name,
 		},
 		Spec: appsv1.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": "name",
-				},
+				MatchLabels: handlerLabels(name),
 			},
 			Replicas: to.Int32Ptr(1),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": "name",
-					},
+					Labels: handlerLabels(name),
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -345,9 +688,9 @@ func newDeployment(ep *v1alpha1.EventProvider, name string) *appsv1.Deployment {
 							Image: ep.Spec.HostImage,
 							Ports: []corev1.ContainerPort{
 								{
-									Name:          "http",
-									Protocol:      corev1.ProtocolTCP,
-									ContainerPort: 80,
+									Name:          handlerPortName(ep),
+									Protocol:      handlerProtocol(ep),
+									ContainerPort: handlerPort(ep),
 								},
 							},
 						},
@@ -357,69 +700,89 @@ func newDeployment(ep *v1alpha1.EventProvider, name string) *appsv1.Deployment {
 		},
 	}
 
+	if ep.Spec.Handler.Receiver {
+		containers := &deployment.Spec.Template.Spec.Containers
+		*containers = append(*containers, newReceiverContainer(ep, config))
+	}
+
+	return deployment
 }
 
 func newService(ep *v1alpha1.EventProvider, serviceName, deploymentName string) *corev1.Service {
-	return &corev1.Service{
+	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: serviceName,
 		},
 		Spec: corev1.ServiceSpec{
-
-			Selector: map[string]string{"app": "name"},
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: handlerLabels(deploymentName),
 			Ports: []corev1.ServicePort{
 				{
-					Name: "eventgrid-80",
-					Port: 80,
-					TargetPort: intstr.IntOrString{
-						IntVal: 80,
-					},
+					Name:       handlerPortName(ep),
+					Protocol:   handlerProtocol(ep),
+					Port:       handlerPort(ep),
+					TargetPort: handlerTargetPort(ep),
 				},
 			},
 		},
 	}
+
+	if ep.Spec.Handler.ServiceType == v1alpha1.ServiceTypeHeadless {
+		service.Spec.ClusterIP = corev1.ClusterIPNone
+	}
+
+	return service
 }
 
-func newIngress(ep *v1alpha1.EventProvider, ingressName, serviceName string) *v1beta1.Ingress {
-	annotations := map[string]string{"kubernetes.io/tls-acme": "true", "kubernetes.io/ingress.class": "nginx"}
-	return &v1beta1.Ingress{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        ingressName,
-			Annotations: annotations,
-		},
-		Spec: v1beta1.IngressSpec{
-			Backend: &v1beta1.IngressBackend{
-				ServiceName: serviceName,
-				ServicePort: intstr.IntOrString{
-					IntVal: 80,
-				},
-			},
-			TLS: []v1beta1.IngressTLS{
-				v1beta1.IngressTLS{
-					Hosts:      []string{ep.Spec.Host},
-					SecretName: ingressName,
-				},
-			},
-			Rules: []v1beta1.IngressRule{
-				v1beta1.IngressRule{
-					Host: ep.Spec.Host,
-					IngressRuleValue: v1beta1.IngressRuleValue{
-						HTTP: &v1beta1.HTTPIngressRuleValue{
-							Paths: []v1beta1.HTTPIngressPath{
-								v1beta1.HTTPIngressPath{
-									Path: "/",
-									Backend: v1beta1.IngressBackend{
-										ServiceName: serviceName,
-										ServicePort: intstr.IntOrString{
-											IntVal: 80,
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+// handlerServiceName returns the name of the service in front of the handler
+func handlerServiceName(ep *v1alpha1.EventProvider) string {
+	return fmt.Sprintf("%s%sservice", ep.Name, ep.Spec.StorageAccount)
+}
+
+// handlerLabels returns the labels shared by the handler pods and the service selecting them
+func handlerLabels(deploymentName string) map[string]string {
+	return map[string]string{
+		"app": deploymentName,
+	}
+}
+
+// handlerPort returns the port the handler listens on, defaulting to 80
+func handlerPort(ep *v1alpha1.EventProvider) int32 {
+	if ep.Spec.Handler.Port != 0 {
+		return ep.Spec.Handler.Port
+	}
+
+	return 80
+}
+
+// handlerPortName returns the name of the handler port, defaulting to http
+func handlerPortName(ep *v1alpha1.EventProvider) string {
+	if ep.Spec.Handler.PortName != "" {
+		return ep.Spec.Handler.PortName
+	}
+
+	return "http"
+}
+
+// handlerProtocol returns the protocol of the handler port, defaulting to TCP
+func handlerProtocol(ep *v1alpha1.EventProvider) corev1.Protocol {
+	if ep.Spec.Handler.Protocol != "" {
+		return ep.Spec.Handler.Protocol
 	}
+
+	return corev1.ProtocolTCP
+}
+
+// handlerTargetPort returns the service target port: the receiver port when the
+// receiver runs in front of the handler, the container port name if one was
+// explicitly configured, and the container port number otherwise
+func handlerTargetPort(ep *v1alpha1.EventProvider) intstr.IntOrString {
+	if ep.Spec.Handler.Receiver {
+		return intstr.FromString(receiverPortName)
+	}
+	if ep.Spec.Handler.PortName != "" {
+		return intstr.FromString(ep.Spec.Handler.PortName)
+	}
+
+	return intstr.FromInt(int(handlerPort(ep)))
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+// syncTestWorkload syncs the handler workload of ep with the given existing objects, and returns
+// the resulting deployment and service, along with the fake clientset
+func syncTestWorkload(t *testing.T, ep *v1alpha1.EventProvider, objects ...runtime.Object) (*appsv1.Deployment, *corev1.Service, *k8sfake.Clientset) {
+	c, client := newTestController(t, append(objects, ep)...)
+	if err := c.syncHandlerWorkload(ep); err != nil {
+		t.Fatalf("cannot sync handler workload: %v", err)
+	}
+
+	deployment, err := client.AppsV1().Deployments("default").Get(context.TODO(), "handlerdeployment", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return deployment, getService(t, client, "default", "handlerservice"), client
+}
+
+func TestSyncHandlerWorkload(t *testing.T) {
+	ep := newTestEventProvider("handler")
+	ep.Spec.HostImage = "handler:1"
+
+	deployment, service, client := syncTestWorkload(t, ep)
+	if ports := service.Spec.Ports; len(ports) != 1 || ports[0].Port != 80 || ports[0].Protocol != corev1.ProtocolTCP {
+		t.Errorf("got service ports %+v", ports)
+	}
+
+	// up to date
+	_, _, client = syncTestWorkload(t, ep, deployment, service)
+	for _, action := range client.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("up to date: got action %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+
+	// the port, protocol and image changed
+	ep.Spec.HostImage = "handler:2"
+	ep.Spec.Handler.Port = 8080
+	ep.Spec.Handler.Protocol = corev1.ProtocolUDP
+	service.Spec.ClusterIP = "10.0.0.1"
+	deployment, service, _ = syncTestWorkload(t, ep, deployment, service)
+	container := deployment.Spec.Template.Spec.Containers[0]
+	if container.Image != "handler:2" || container.Ports[0].ContainerPort != 8080 || container.Ports[0].Protocol != corev1.ProtocolUDP {
+		t.Errorf("got container %+v", container)
+	}
+	if ports := service.Spec.Ports; len(ports) != 1 || ports[0].Port != 8080 || ports[0].Protocol != corev1.ProtocolUDP || service.Spec.ClusterIP != "10.0.0.1" {
+		t.Errorf("got service %+v", service.Spec)
+	}
+
+	// a headless service is recreated, as its cluster IP is immutable
+	ep.Spec.Handler.ServiceType = v1alpha1.ServiceTypeHeadless
+	_, service, _ = syncTestWorkload(t, ep, deployment, service)
+	if service.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Errorf("got cluster IP %s for a headless service", service.Spec.ClusterIP)
+	}
+
+	// a deployment whose selector changed is recreated, as its selector is immutable
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}
+	deployment.Spec.Template.Labels = map[string]string{"app": "other"}
+	deployment, _, client = syncTestWorkload(t, ep, deployment, service)
+	if deployment.Spec.Selector.MatchLabels["app"] != "handlerdeployment" || deployment.Spec.Template.Labels["app"] != "handlerdeployment" {
+		t.Errorf("got deployment %+v", deployment.Spec)
+	}
+	var deleted bool
+	for _, action := range client.Actions() {
+		deleted = deleted || (action.GetVerb() == "delete" && action.GetResource().Resource == "deployments")
+	}
+	if !deleted {
+		t.Errorf("the deployment was not recreated")
+	}
+}
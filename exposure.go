@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+)
+
+// exposureMode returns the exposure mode of the eventprovider. Eventproviders without
+// a mode are exposed on the shared host when they have no host of their own and the
+// operator has a shared host, through their own Ingress otherwise.
+func exposureMode(ep *v1alpha1.EventProvider, config ControllerConfig) v1alpha1.ExposureMode {
+	if ep.Spec.Exposure.Mode != "" {
+		return ep.Spec.Exposure.Mode
+	}
+	if ep.Spec.Host == "" && config.SharedHost != "" {
+		return v1alpha1.ExposureModeSharedIngress
+	}
+
+	return v1alpha1.ExposureModeIngress
+}
+
+// syncExposure exposes the handler service outside of the cluster according to
+// the eventprovider exposure mode, and returns the public URL of the handler.
+// An empty URL means the exposure is not ready yet.
+func (c *Controller) syncExposure(ep *v1alpha1.EventProvider, serviceName string) (*v1alpha1.EventProvider, string, error) {
+	switch exposureMode(ep, c.config) {
+	case v1alpha1.ExposureModeIngress:
+		if ep.Spec.Host == "" {
+			return ep, "", fmt.Errorf("the Ingress exposure mode requires a host")
+		}
+		if err := c.syncIngress(ep, ingressName(ep), serviceName); err != nil {
+			return ep, "", err
+		}
+
+		return ep, gatewayURL(ep, fmt.Sprintf("https://%s", ep.Spec.Host)), nil
+
+	case v1alpha1.ExposureModeSharedIngress:
+		// the path is already part of the shared URL
+		endpointURL, err := c.syncSharedExposure(ep)
+		return ep, endpointURL, err
+
+	case v1alpha1.ExposureModeHTTPRoute:
+		routeName := fmt.Sprintf("%shttproute", ep.Name)
+		endpointURL, err := c.syncHTTPRoute(ep, routeName, serviceName)
+		return ep, gatewayURL(ep, endpointURL), err
+
+	case v1alpha1.ExposureModeNone:
+		if ep.Spec.Exposure.URL != "" {
+			return ep, gatewayURL(ep, ep.Spec.Exposure.URL), nil
+		}
+
+		return ep, gatewayURL(ep, fmt.Sprintf("https://%s", ep.Spec.Host)), nil
+
+	default:
+		return ep, "", fmt.Errorf("unknown exposure mode %v", ep.Spec.Exposure.Mode)
+	}
+}
+
+// gatewayURL appends the gateway path of the eventprovider to the base URL of its endpoint,
+// when its events go through the event gateway
+func gatewayURL(ep *v1alpha1.EventProvider, baseURL string) string {
+	if ep.Spec.Sink == nil || baseURL == "" {
+		return baseURL
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + ep.Status.Path
+}
+
+// syncPath makes sure the eventprovider has a path to receive events on. The path is
+// persisted before it is used, so that it never changes once it was handed out.
+func (c *Controller) syncPath(ep *v1alpha1.EventProvider) (*v1alpha1.EventProvider, error) {
+	if ep.Status.Path != "" {
+		return ep, nil
+	}
+
+	path, err := newPath(ep)
+	if err != nil {
+		return ep, err
+	}
+
+	ep, err = c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		status.Path = path
+	})
+	if err != nil {
+		return ep, fmt.Errorf("cannot update eventprovider status: %v", err)
+	}
+
+	return ep, nil
+}
+
+// newPath returns a new path for the eventprovider. The random token makes the path
+// of an eventprovider impossible to guess from its namespace and name.
+func newPath(ep *v1alpha1.EventProvider) (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("cannot generate path token: %v", err)
+	}
+
+	return fmt.Sprintf("/%s/%s/%s", ep.Namespace, ep.Name, hex.EncodeToString(token)), nil
+}
+
+// backendServiceName returns the service the eventprovider is exposed through:
+// the event gateway when it has a sink, its handler otherwise
+func backendServiceName(ep *v1alpha1.EventProvider, config ControllerConfig) string {
+	if ep.Spec.Sink != nil {
+		return config.GatewayService
+	}
+
+	return handlerServiceName(ep)
+}
+
+// backendPort returns the port of the service the eventprovider is exposed through
+func backendPort(ep *v1alpha1.EventProvider, config ControllerConfig) int32 {
+	if ep.Spec.Sink != nil {
+		return config.GatewayPort
+	}
+
+	return handlerPort(ep)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// finalizerName holds the deletion of eventproviders until the operator removed
+// what it registered with their provider, such as webhooks
+const finalizerName = "eventprovider.k8s.io/finalizer"
+
+// ensureFinalizer adds the finalizer to the eventprovider, and returns the updated eventprovider
+func (c *Controller) ensureFinalizer(ep *v1alpha1.EventProvider) (*v1alpha1.EventProvider, error) {
+	if hasFinalizer(ep) {
+		return ep, nil
+	}
+
+	epCopy := ep.DeepCopy()
+	epCopy.Finalizers = append(epCopy.Finalizers, finalizerName)
+	updated, err := c.epclientset.EventproviderV1alpha1().EventProviders(ep.Namespace).Update(context.TODO(), epCopy, metav1.UpdateOptions{})
+	if err != nil {
+		return ep, fmt.Errorf("cannot add finalizer: %v", err)
+	}
+
+	return updated, nil
+}
+
+// finalize cleans up after a deleted eventprovider, and removes its finalizer
+func (c *Controller) finalize(ep *v1alpha1.EventProvider) error {
+	if !hasFinalizer(ep) {
+		return nil
+	}
+
+	var err error
+	switch ep.Spec.ProviderName {
+	case gateway.ProviderGitHub:
+		err = c.finalizeGitHub(ep)
+	case gateway.ProviderGitLab:
+		err = c.finalizeGitLab(ep)
+	case gateway.ProviderGitea:
+		err = c.finalizeGitea(ep)
+	case gateway.ProviderSNS:
+		err = c.finalizeSNS(ep)
+	case gateway.ProviderPubSub:
+		err = c.finalizePubSub(ep)
+	case gateway.ProviderS3:
+		err = c.finalizeS3(ep)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.gateway.DeleteRoute(ep.Namespace, ep.Name)
+	c.sources.Stop(ep.Namespace, ep.Name)
+
+	epCopy := ep.DeepCopy()
+	epCopy.Finalizers = nil
+	for _, f := range ep.Finalizers {
+		if f != finalizerName {
+			epCopy.Finalizers = append(epCopy.Finalizers, f)
+		}
+	}
+	if _, err := c.epclientset.EventproviderV1alpha1().EventProviders(ep.Namespace).Update(context.TODO(), epCopy, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("cannot remove finalizer: %v", err)
+	}
+	glog.Infof("finalized eventprovider %s/%s", ep.Namespace, ep.Name)
+
+	return nil
+}
+
+// hasFinalizer returns true when the eventprovider has the operator finalizer
+func hasFinalizer(ep *v1alpha1.EventProvider) bool {
+	for _, f := range ep.Finalizers {
+		if f == finalizerName {
+			return true
+		}
+	}
+
+	return false
+}
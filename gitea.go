@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/gitea"
+)
+
+// giteaEvents maps the event names of eventproviders to Gitea hook events
+var giteaEvents = map[string]string{
+	"push":          "push",
+	"merge_request": "pull_request",
+	"tag":           "create",
+}
+
+// syncGitea makes sure the Gitea repository hook of the eventprovider delivers the events
+// it is subscribed to to the event gateway, which verifies their signature
+func (c *Controller) syncGitea(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.Gitea == nil {
+		return fmt.Errorf("the gitea provider requires a gitea spec")
+	}
+	if ep.Spec.Gitea.APIURL == "" {
+		return fmt.Errorf("a Gitea API URL is required")
+	}
+
+	reg, err := giteaRegistration(ep)
+	if err != nil {
+		return err
+	}
+
+	return c.syncForgeHook(ep, reg)
+}
+
+// finalizeGitea deletes the repository hook of a deleted eventprovider
+func (c *Controller) finalizeGitea(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.Gitea == nil {
+		return nil
+	}
+
+	reg, err := giteaRegistration(ep)
+	if err != nil {
+		return err
+	}
+
+	return c.finalizeForgeHook(ep, reg)
+}
+
+// giteaRegistration registers the webhook of the eventprovider on its repository
+func giteaRegistration(ep *v1alpha1.EventProvider) (hookRegistration, error) {
+	spec := ep.Spec.Gitea
+
+	events, err := gitProjectEvents(spec)
+	if err != nil {
+		return hookRegistration{}, err
+	}
+
+	return hookRegistration{
+		secretName: spec.SecretName,
+		route: func(webhookSecret []byte) (gateway.Route, error) {
+			auth, err := gateway.NewHMACAuthenticator(gateway.GiteaSignatureHeader, "", "sha256", "hex", webhookSecret)
+			if err != nil {
+				return gateway.Route{}, err
+			}
+
+			return gateway.Route{
+				Auth:   auth,
+				Source: fmt.Sprintf("%s/repos/%s", spec.APIURL, spec.Project),
+				Events: events,
+			}, nil
+		},
+		api: func(token []byte, endpointURL string, webhookSecret []byte) (hookAPI, error) {
+			client, err := gitea.NewClient(spec.APIURL, string(token), spec.Project)
+			if err != nil {
+				return nil, err
+			}
+
+			hookEvents := make([]string, 0, len(events))
+			for _, e := range events {
+				hookEvents = append(hookEvents, giteaEvents[e])
+			}
+
+			return &giteaHooks{
+				client: client,
+				hook:   gitea.NewHook(endpointURL, string(webhookSecret), hookEvents),
+			}, nil
+		},
+	}, nil
+}
+
+// giteaHooks implements hookAPI for Gitea
+type giteaHooks struct {
+	client *gitea.Client
+	hook   gitea.Hook
+}
+
+func (h *giteaHooks) get(ctx context.Context, id string) (bool, bool, error) {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, false, fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	current, err := h.client.GetHook(ctx, hookID)
+	if gitea.IsNotFound(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	// the API never returns the secret
+	upToDate := current.Active &&
+		current.Config.URL == h.hook.Config.URL &&
+		current.Config.ContentType == h.hook.Config.ContentType &&
+		sameEvents(current.Events, h.hook.Events)
+
+	return true, upToDate, nil
+}
+
+func (h *giteaHooks) find(ctx context.Context) (string, error) {
+	hooks, err := h.client.ListHooks(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, hook := range hooks {
+		if hook.Config.URL == h.hook.Config.URL {
+			return strconv.FormatInt(hook.ID, 10), nil
+		}
+	}
+
+	return "", nil
+}
+
+func (h *giteaHooks) create(ctx context.Context) (string, error) {
+	created, err := h.client.CreateHook(ctx, h.hook)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(created.ID, 10), nil
+}
+
+func (h *giteaHooks) update(ctx context.Context, id string) error {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	_, err = h.client.UpdateHook(ctx, hookID, h.hook)
+	return err
+}
+
+func (h *giteaHooks) delete(ctx context.Context, id string) error {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	if err := h.client.DeleteHook(ctx, hookID); err != nil && !gitea.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
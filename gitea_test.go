@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+)
+
+func TestGiteaHook(t *testing.T) {
+	forge := newFakeForge(t, "/repos/owner/repo/hooks")
+
+	ep := newTestEventProvider("gitea")
+	ep.Spec.ProviderName = "gitea"
+	ep.Spec.Gitea = &v1alpha1.GitProjectSpec{Project: "owner/repo", SecretName: "gitea", Events: []string{"push", "merge_request"}, APIURL: forge.URL}
+	c, _ := newTestController(t, ep, forgeSecret("gitea"))
+
+	reg, err := giteaRegistration(ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testForgeHook(t, c, forge, ep, reg, "https://events.example.com/default/gitea/token", func(hook map[string]interface{}) {
+		hook["active"] = false
+	})
+
+	// events are named as Gitea does, and the secret is never returned
+	api, err := reg.api([]byte("token"), "https://events.example.com/default/gitea/token", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := api.create(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := forge.hook(id)
+	config := hook["config"].(map[string]interface{})
+	if config["secret"] != "secret" || hook["type"] != "gitea" || !reflect.DeepEqual(hook["events"], []interface{}{"push", "pull_request"}) {
+		t.Errorf("got hook %v", hook)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/github"
+)
+
+// syncGitHub makes sure the GitHub webhook of the eventprovider delivers the events
+// it is subscribed to to the event gateway, which verifies their signature
+func (c *Controller) syncGitHub(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.GitHub == nil {
+		return fmt.Errorf("the github provider requires a github spec")
+	}
+
+	return c.syncForgeHook(ep, c.githubRegistration(ep))
+}
+
+// finalizeGitHub deletes the webhook of a deleted eventprovider
+func (c *Controller) finalizeGitHub(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.GitHub == nil {
+		return nil
+	}
+
+	return c.finalizeForgeHook(ep, c.githubRegistration(ep))
+}
+
+// githubRegistration registers the webhook of the eventprovider on its repository or organization
+func (c *Controller) githubRegistration(ep *v1alpha1.EventProvider) hookRegistration {
+	spec := ep.Spec.GitHub
+	apiURL := spec.APIURL
+	if apiURL == "" {
+		apiURL = c.config.GitHubAPIURL
+	}
+
+	events := spec.Events
+	if len(events) == 0 {
+		events = []string{"push"}
+	}
+
+	return hookRegistration{
+		secretName: spec.SecretName,
+		route: func(webhookSecret []byte) (gateway.Route, error) {
+			auth, err := gateway.NewHMACAuthenticator(gateway.GitHubSignatureHeader, gateway.GitHubSignaturePrefix, "sha256", "hex", webhookSecret)
+			if err != nil {
+				return gateway.Route{}, err
+			}
+
+			source := fmt.Sprintf("%s/orgs/%s", apiURL, spec.Organization)
+			if spec.Repository != "" {
+				source = fmt.Sprintf("%s/repos/%s", apiURL, spec.Repository)
+			}
+
+			return gateway.Route{Auth: auth, Source: source, Events: events}, nil
+		},
+		api: func(token []byte, endpointURL string, webhookSecret []byte) (hookAPI, error) {
+			var client *github.Client
+			switch {
+			case spec.Repository != "":
+				var err error
+				if client, err = github.NewRepositoryClient(apiURL, string(token), spec.Repository); err != nil {
+					return nil, err
+				}
+			case spec.Organization != "":
+				client = github.NewOrganizationClient(apiURL, string(token), spec.Organization)
+			default:
+				return nil, fmt.Errorf("either a GitHub repository or organization is required")
+			}
+
+			return &githubHooks{
+				client: client,
+				hook:   github.NewHook(endpointURL, string(webhookSecret), events),
+			}, nil
+		},
+	}
+}
+
+// githubHooks implements hookAPI for GitHub
+type githubHooks struct {
+	client *github.Client
+	hook   github.Hook
+}
+
+func (h *githubHooks) get(ctx context.Context, id string) (bool, bool, error) {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, false, fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	current, err := h.client.GetHook(ctx, hookID)
+	if github.IsNotFound(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	// the API never returns the secret
+	upToDate := current.Active &&
+		current.Config.URL == h.hook.Config.URL &&
+		current.Config.ContentType == h.hook.Config.ContentType &&
+		sameEvents(current.Events, h.hook.Events)
+
+	return true, upToDate, nil
+}
+
+func (h *githubHooks) find(ctx context.Context) (string, error) {
+	hooks, err := h.client.ListHooks(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, hook := range hooks {
+		if hook.Config.URL == h.hook.Config.URL {
+			return strconv.FormatInt(hook.ID, 10), nil
+		}
+	}
+
+	return "", nil
+}
+
+func (h *githubHooks) create(ctx context.Context) (string, error) {
+	created, err := h.client.CreateHook(ctx, h.hook)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(created.ID, 10), nil
+}
+
+func (h *githubHooks) update(ctx context.Context, id string) error {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	_, err = h.client.UpdateHook(ctx, hookID, h.hook)
+	return err
+}
+
+func (h *githubHooks) delete(ctx context.Context, id string) error {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	if err := h.client.DeleteHook(ctx, hookID); err != nil && !github.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+)
+
+func TestGitHubHook(t *testing.T) {
+	forge := newFakeForge(t, "/repos/owner/repo/hooks")
+
+	ep := newTestEventProvider("github")
+	ep.Spec.ProviderName = "github"
+	ep.Spec.GitHub = &v1alpha1.GitHubSpec{Repository: "owner/repo", SecretName: "github", Events: []string{"push", "issues"}}
+	c, _ := newTestController(t, ep, forgeSecret("github"))
+	c.config.GitHubAPIURL = forge.URL
+
+	reg := c.githubRegistration(ep)
+	testForgeHook(t, c, forge, ep, reg, "https://events.example.com/default/github/token", func(hook map[string]interface{}) {
+		hook["events"] = []interface{}{"push"}
+	})
+
+	// the secret signs deliveries, and is never returned
+	api, err := reg.api([]byte("token"), "https://events.example.com/default/github/token", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := api.create(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := forge.hook(id)["config"].(map[string]interface{})
+	if config["secret"] != "secret" || config["content_type"] != "json" || !reflect.DeepEqual(forge.hook(id)["events"], []interface{}{"push", "issues"}) {
+		t.Errorf("got hook %v", forge.hook(id))
+	}
+}
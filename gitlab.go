@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/gitlab"
+)
+
+// syncGitLab makes sure the GitLab project hook of the eventprovider delivers the events
+// it is subscribed to to the event gateway, which checks their token
+func (c *Controller) syncGitLab(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.GitLab == nil {
+		return fmt.Errorf("the gitlab provider requires a gitlab spec")
+	}
+	if ep.Spec.GitLab.Project == "" {
+		return fmt.Errorf("a GitLab project is required")
+	}
+
+	reg, err := gitlabRegistration(ep)
+	if err != nil {
+		return err
+	}
+
+	return c.syncForgeHook(ep, reg)
+}
+
+// finalizeGitLab deletes the project hook of a deleted eventprovider
+func (c *Controller) finalizeGitLab(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.GitLab == nil {
+		return nil
+	}
+
+	reg, err := gitlabRegistration(ep)
+	if err != nil {
+		return err
+	}
+
+	return c.finalizeForgeHook(ep, reg)
+}
+
+// gitlabRegistration registers the webhook of the eventprovider on its project
+func gitlabRegistration(ep *v1alpha1.EventProvider) (hookRegistration, error) {
+	spec := ep.Spec.GitLab
+	apiURL := spec.APIURL
+	if apiURL == "" {
+		apiURL = gitlab.DefaultAPIURL
+	}
+
+	events, err := gitProjectEvents(spec)
+	if err != nil {
+		return hookRegistration{}, err
+	}
+
+	return hookRegistration{
+		secretName: spec.SecretName,
+		route: func(webhookSecret []byte) (gateway.Route, error) {
+			return gateway.Route{
+				Auth:   gateway.TokenAuthenticator{Header: gateway.GitLabTokenHeader, Token: string(webhookSecret)},
+				Source: fmt.Sprintf("%s/projects/%s", apiURL, spec.Project),
+				Events: events,
+			}, nil
+		},
+		api: func(token []byte, endpointURL string, webhookSecret []byte) (hookAPI, error) {
+			hook := gitlab.Hook{
+				URL:                   endpointURL,
+				Token:                 string(webhookSecret),
+				EnableSSLVerification: true,
+			}
+			for _, e := range events {
+				switch e {
+				case "push":
+					hook.PushEvents = true
+				case "merge_request":
+					hook.MergeRequestsEvents = true
+				case "tag":
+					hook.TagPushEvents = true
+				}
+			}
+
+			return &gitlabHooks{
+				client: gitlab.NewClient(apiURL, string(token), spec.Project),
+				hook:   hook,
+			}, nil
+		},
+	}, nil
+}
+
+// gitlabHooks implements hookAPI for GitLab
+type gitlabHooks struct {
+	client *gitlab.Client
+	hook   gitlab.Hook
+}
+
+func (h *gitlabHooks) get(ctx context.Context, id string) (bool, bool, error) {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, false, fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	current, err := h.client.GetHook(ctx, hookID)
+	if gitlab.IsNotFound(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	// the API never returns the token
+	upToDate := current.URL == h.hook.URL &&
+		current.PushEvents == h.hook.PushEvents &&
+		current.MergeRequestsEvents == h.hook.MergeRequestsEvents &&
+		current.TagPushEvents == h.hook.TagPushEvents &&
+		current.EnableSSLVerification == h.hook.EnableSSLVerification
+
+	return true, upToDate, nil
+}
+
+func (h *gitlabHooks) find(ctx context.Context) (string, error) {
+	hooks, err := h.client.ListHooks(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, hook := range hooks {
+		if hook.URL == h.hook.URL {
+			return strconv.FormatInt(hook.ID, 10), nil
+		}
+	}
+
+	return "", nil
+}
+
+func (h *gitlabHooks) create(ctx context.Context) (string, error) {
+	created, err := h.client.CreateHook(ctx, h.hook)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(created.ID, 10), nil
+}
+
+func (h *gitlabHooks) update(ctx context.Context, id string) error {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	_, err = h.client.UpdateHook(ctx, hookID, h.hook)
+	return err
+}
+
+func (h *gitlabHooks) delete(ctx context.Context, id string) error {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hook ID %s: %v", id, err)
+	}
+
+	if err := h.client.DeleteHook(ctx, hookID); err != nil && !gitlab.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// gitProjectEvents returns the events a GitLab or Gitea webhook subscribes to, defaulting to push
+func gitProjectEvents(spec *v1alpha1.GitProjectSpec) ([]string, error) {
+	if len(spec.Events) == 0 {
+		return []string{"push"}, nil
+	}
+
+	for _, e := range spec.Events {
+		switch e {
+		case "push", "merge_request", "tag":
+		default:
+			return nil, fmt.Errorf("unknown event %q, expected push, merge_request or tag", e)
+		}
+	}
+
+	return spec.Events, nil
+}
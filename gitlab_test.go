@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+)
+
+func TestGitLabHook(t *testing.T) {
+	// the project path is escaped in the URL, and unescaped by the server
+	forge := newFakeForge(t, "/projects/group/project/hooks")
+
+	ep := newTestEventProvider("gitlab")
+	ep.Spec.ProviderName = "gitlab"
+	ep.Spec.GitLab = &v1alpha1.GitProjectSpec{Project: "group/project", SecretName: "gitlab", Events: []string{"push", "tag"}, APIURL: forge.URL}
+	c, _ := newTestController(t, ep, forgeSecret("gitlab"))
+
+	reg, err := gitlabRegistration(ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testForgeHook(t, c, forge, ep, reg, "https://events.example.com/default/gitlab/token", func(hook map[string]interface{}) {
+		hook["merge_requests_events"] = true
+	})
+
+	// the token authenticates deliveries, and is never returned
+	api, err := reg.api([]byte("token"), "https://events.example.com/default/gitlab/token", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := api.create(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := forge.hook(id)
+	if hook["token"] != "secret" || hook["push_events"] != true || hook["tag_push_events"] != true || hook["merge_requests_events"] != false || hook["enable_ssl_verification"] != true {
+		t.Errorf("got hook %v", hook)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build tools
+// +build tools
+
+// Package tools pins the version of the code generators hack/update-codegen.sh runs
+package tools
+
+import _ "k8s.io/code-generator"
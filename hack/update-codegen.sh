@@ -5,10 +5,17 @@ set -o nounset
 set -o pipefail
 
 SCRIPT_ROOT=$(dirname ${BASH_SOURCE})/..
-CODEGEN_PKG=${CODEGEN_PKG:-$(cd ${SCRIPT_ROOT}; ls -d -1 ./vendor/k8s.io/code-generator 2>/dev/null || echo ${GOPATH}/src/k8s.io/code-generator)}
+CODEGEN_PKG=${CODEGEN_PKG:-$(cd ${SCRIPT_ROOT}; go list -m -f '{{.Dir}}' k8s.io/code-generator)}
 
-vendor/k8s.io/code-generator/generate-groups.sh all \
-  github.com/radu-matei/events-operator/pkg/client \
-  github.com/radu-matei/events-operator/pkg/apis \
-  "eventprovider:v1alpha1" \
-  --go-header-file ${SCRIPT_ROOT}/hack/custom-boilerplate.go.txt
+source "${CODEGEN_PKG}/kube_codegen.sh"
+
+kube::codegen::gen_helpers \
+  --boilerplate "${SCRIPT_ROOT}/hack/custom-boilerplate.go.txt" \
+  "${SCRIPT_ROOT}/pkg/apis"
+
+kube::codegen::gen_client \
+  --with-watch \
+  --output-dir "${SCRIPT_ROOT}/pkg/client" \
+  --output-pkg github.com/radu-matei/events-operator/pkg/client \
+  --boilerplate "${SCRIPT_ROOT}/hack/custom-boilerplate.go.txt" \
+  "${SCRIPT_ROOT}/pkg/apis"
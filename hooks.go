@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// hookAPI registers the webhook of an eventprovider with a git forge, such as GitHub.
+// Implementations know the desired hook, delivering to the endpoint of the eventprovider.
+type hookAPI interface {
+	// get returns whether the hook with the given ID exists, and whether it matches the desired hook
+	get(ctx context.Context, id string) (found, upToDate bool, err error)
+	// find returns the ID of a hook delivering to the endpoint, empty when there is none
+	find(ctx context.Context) (string, error)
+	// create creates the desired hook, and returns its ID
+	create(ctx context.Context) (string, error)
+	// update replaces the hook with the given ID with the desired hook
+	update(ctx context.Context, id string) error
+	// delete deletes the hook with the given ID, deleting a missing hook is not an error
+	delete(ctx context.Context, id string) error
+}
+
+// hookStatus is implemented by hook APIs recording more than the hook ID in the status
+type hookStatus interface {
+	// updateStatus records the desired hook in the status
+	updateStatus(status *v1alpha1.EventProviderStatus)
+}
+
+// hookRegistration is how a forge provider registers its webhook
+type hookRegistration struct {
+	// secretName is the secret holding the token and secret keys
+	secretName string
+	// route holds the provider specific settings of the gateway route
+	route func(webhookSecret []byte) (gateway.Route, error)
+	// api returns the hook API of the eventprovider, for a hook delivering to endpointURL
+	api func(token []byte, endpointURL string, webhookSecret []byte) (hookAPI, error)
+}
+
+// syncForgeHook exposes the endpoint of an eventprovider of a git forge, and makes
+// sure the forge webhook delivers to it. The webhook is deleted with the eventprovider.
+func (c *Controller) syncForgeHook(ep *v1alpha1.EventProvider, reg hookRegistration) error {
+	if ep.Spec.Sink == nil {
+		return fmt.Errorf("the %s provider requires a sink", ep.Spec.ProviderName)
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(reg.secretName)
+	if err != nil {
+		return fmt.Errorf("cannot get %s secret %s/%s: %v", ep.Spec.ProviderName, ep.Namespace, reg.secretName, err)
+	}
+	token, err := secretKey(secret, "token")
+	if err != nil {
+		return err
+	}
+	webhookSecret, err := secretKey(secret, "secret")
+	if err != nil {
+		return err
+	}
+
+	// the webhook must be deleted with the eventprovider
+	if ep, err = c.ensureFinalizer(ep); err != nil {
+		return err
+	}
+
+	route, err := reg.route(webhookSecret)
+	if err != nil {
+		return err
+	}
+	ep, endpointURL, err := c.syncEndpoint(ep, route)
+	if err != nil || endpointURL == "" {
+		return err
+	}
+
+	api, err := reg.api(token, endpointURL, webhookSecret)
+	if err != nil {
+		return err
+	}
+
+	return c.syncHook(ep, api, secret.ResourceVersion)
+}
+
+// syncHook creates the webhook of the eventprovider, or updates it when it drifted
+// or its secret changed, and records its ID in the status
+func (c *Controller) syncHook(ep *v1alpha1.EventProvider, api hookAPI, secretVersion string) error {
+	ctx := context.TODO()
+
+	id := ep.Status.HookID
+	found, upToDate := false, false
+	if id != "" {
+		var err error
+		if found, upToDate, err = api.get(ctx, id); err != nil {
+			return fmt.Errorf("cannot get %s webhook %s: %v", ep.Spec.ProviderName, id, err)
+		}
+		if !found {
+			glog.Infof("%s webhook %s of eventprovider %s/%s was deleted, creating it again", ep.Spec.ProviderName, id, ep.Namespace, ep.Name)
+		}
+	}
+
+	// the hook may have been created before its ID was recorded,
+	// its secret is unknown so it is updated
+	if !found {
+		var err error
+		if id, err = api.find(ctx); err != nil {
+			return fmt.Errorf("cannot list %s webhooks: %v", ep.Spec.ProviderName, err)
+		}
+		found = id != ""
+	}
+
+	switch {
+	case !found:
+		var err error
+		if id, err = api.create(ctx); err != nil {
+			return fmt.Errorf("cannot create %s webhook: %v", ep.Spec.ProviderName, err)
+		}
+		glog.Infof("created %s webhook %s for eventprovider %s/%s", ep.Spec.ProviderName, id, ep.Namespace, ep.Name)
+
+	case !upToDate || secretVersion != ep.Status.HookSecretVersion:
+		if err := api.update(ctx, id); err != nil {
+			return fmt.Errorf("cannot update %s webhook %s: %v", ep.Spec.ProviderName, id, err)
+		}
+		glog.Infof("updated %s webhook %s for eventprovider %s/%s", ep.Spec.ProviderName, id, ep.Namespace, ep.Name)
+	}
+
+	_, err := c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		status.HookID = id
+		status.HookSecretVersion = secretVersion
+		if api, ok := api.(hookStatus); ok {
+			api.updateStatus(status)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("cannot update eventprovider status: %v", err)
+	}
+
+	return nil
+}
+
+// finalizeForgeHook deletes the webhook of a deleted eventprovider
+func (c *Controller) finalizeForgeHook(ep *v1alpha1.EventProvider, reg hookRegistration) error {
+	if ep.Status.HookID == "" {
+		return nil
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(reg.secretName)
+	if errors.IsNotFound(err) {
+		// without the token the hook can never be deleted, do not hold the eventprovider forever
+		glog.Warningf("cannot delete %s webhook %s of eventprovider %s/%s, its secret is gone", ep.Spec.ProviderName, ep.Status.HookID, ep.Namespace, ep.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot get %s secret %s/%s: %v", ep.Spec.ProviderName, ep.Namespace, reg.secretName, err)
+	}
+	token, err := secretKey(secret, "token")
+	if err != nil {
+		return err
+	}
+
+	api, err := reg.api(token, "", nil)
+	if err != nil {
+		return err
+	}
+	if err := api.delete(context.TODO(), ep.Status.HookID); err != nil {
+		return fmt.Errorf("cannot delete %s webhook %s: %v", ep.Spec.ProviderName, ep.Status.HookID, err)
+	}
+	glog.Infof("deleted %s webhook %s of eventprovider %s/%s", ep.Spec.ProviderName, ep.Status.HookID, ep.Namespace, ep.Name)
+
+	return nil
+}
+
+// secretKey returns a non-empty key of a secret
+func secretKey(secret *corev1.Secret, key string) ([]byte, error) {
+	value, ok := secret.Data[key]
+	if !ok || len(value) == 0 {
+		return nil, fmt.Errorf("secret %s/%s has no %s key", secret.Namespace, secret.Name, key)
+	}
+
+	return value, nil
+}
+
+// sameEvents compares two lists of event names, ignoring their order
+func sameEvents(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	count := map[string]int{}
+	for _, e := range a {
+		count[e]++
+	}
+	for _, e := range b {
+		if count[e] == 0 {
+			return false
+		}
+		count[e]--
+	}
+
+	return true
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeForgePageSize is the page size of hook lists of the fake forge, small enough to paginate
+const fakeForgePageSize = 2
+
+// fakeForge is an in-memory hooks API of a git forge. Hooks are stored as decoded JSON objects,
+// secrets are never returned, and lists are paginated with Link headers.
+type fakeForge struct {
+	*httptest.Server
+
+	// collection is the path of the hooks collection
+	collection string
+
+	mu     sync.Mutex
+	hooks  map[int64]map[string]interface{}
+	nextID int64
+	// writes are the hook changes, such as "POST" or "PATCH 1"
+	writes []string
+}
+
+func newFakeForge(t *testing.T, collection string) *fakeForge {
+	f := &fakeForge{collection: collection, hooks: map[int64]map[string]interface{}{}, nextID: 1}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeForge) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+	}
+
+	if r.URL.Path == f.collection {
+		switch r.Method {
+		case http.MethodGet:
+			f.list(w, r)
+		case http.MethodPost:
+			hook := map[string]interface{}{}
+			if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			id := f.add(hook)
+			f.writes = append(f.writes, "POST")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(public(f.hooks[id]))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, f.collection+"/") {
+		notFound()
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, f.collection+"/"), 10, 64)
+	hook, ok := f.hooks[id]
+	if err != nil || !ok {
+		notFound()
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(public(hook))
+	case http.MethodPatch, http.MethodPut:
+		changes := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for k, v := range changes {
+			hook[k] = v
+		}
+		f.writes = append(f.writes, fmt.Sprintf("%s %d", r.Method, id))
+		json.NewEncoder(w).Encode(public(hook))
+	case http.MethodDelete:
+		delete(f.hooks, id)
+		f.writes = append(f.writes, fmt.Sprintf("DELETE %d", id))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list answers a page of hooks, with the Link header of the next page
+func (f *fakeForge) list(w http.ResponseWriter, r *http.Request) {
+	ids := make([]int64, 0, len(f.hooks))
+	for id := range f.hooks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	hooks := []map[string]interface{}{}
+	for i := (page - 1) * fakeForgePageSize; i < len(ids) && i < page*fakeForgePageSize; i++ {
+		hooks = append(hooks, public(f.hooks[ids[i]]))
+	}
+
+	if page*fakeForgePageSize < len(ids) {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, f.URL, f.collection, query.Encode()))
+	}
+	json.NewEncoder(w).Encode(hooks)
+}
+
+// add stores a hook, and returns its ID
+func (f *fakeForge) add(hook map[string]interface{}) int64 {
+	id := f.nextID
+	f.nextID++
+	hook["id"] = id
+	f.hooks[id] = hook
+
+	return id
+}
+
+// addHooks stores n hooks delivering elsewhere, and then hook, and returns its ID
+func (f *fakeForge) addHooks(n int, hook map[string]interface{}) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		f.add(map[string]interface{}{"url": fmt.Sprintf("https://elsewhere.example.com/%d", i), "config": map[string]interface{}{"url": fmt.Sprintf("https://elsewhere.example.com/%d", i)}})
+	}
+
+	return f.add(hook)
+}
+
+// hook returns the stored hook with the given ID, with its secret
+func (f *fakeForge) hook(id string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, _ := strconv.ParseInt(id, 10, 64)
+	return f.hooks[n]
+}
+
+// changes returns the hook changes, and forgets them
+func (f *fakeForge) changes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	writes := f.writes
+	f.writes = nil
+
+	return writes
+}
+
+// public returns a hook as forges answer it, without its secret
+func public(hook map[string]interface{}) map[string]interface{} {
+	answer := map[string]interface{}{}
+	for k, v := range hook {
+		if k == "token" {
+			continue
+		}
+		if config, ok := v.(map[string]interface{}); ok {
+			c := map[string]interface{}{}
+			for ck, cv := range config {
+				if ck != "secret" {
+					c[ck] = cv
+				}
+			}
+			v = c
+		}
+		answer[k] = v
+	}
+
+	return answer
+}
+
+// forgeSecret returns the secret of a forge eventprovider
+func forgeSecret(name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, ResourceVersion: "1"},
+		Data: map[string][]byte{
+			"token":  []byte("token"),
+			"secret": []byte("secret"),
+		},
+	}
+}
+
+// syncTestHook syncs the hook of the eventprovider with api, and returns the eventprovider
+// with its updated status
+func syncTestHook(t *testing.T, c *Controller, ep *v1alpha1.EventProvider, api hookAPI, secretVersion string) *v1alpha1.EventProvider {
+	if err := c.syncHook(ep, api, secretVersion); err != nil {
+		t.Fatalf("cannot sync hook: %v", err)
+	}
+
+	updated, err := c.epclientset.EventproviderV1alpha1().EventProviders(ep.Namespace).Get(context.TODO(), ep.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return updated
+}
+
+// testForgeHook runs the hook life cycle of a forge provider: create, no-op, update on drift and on
+// secret rotation, adoption of a hook whose ID was not recorded, recreation, and deletion. url is
+// where hooks deliver to, and drift changes a stored hook so that it is not up to date anymore.
+func testForgeHook(t *testing.T, c *Controller, forge *fakeForge, ep *v1alpha1.EventProvider, reg hookRegistration, url string, drift func(hook map[string]interface{})) {
+	api, err := reg.api([]byte("token"), url, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// created, with the secret
+	ep = syncTestHook(t, c, ep, api, "1")
+	if changes := forge.changes(); len(changes) != 1 || changes[0] != "POST" || ep.Status.HookID == "" || ep.Status.HookSecretVersion != "1" {
+		t.Fatalf("create: got changes %v and status %+v", changes, ep.Status)
+	}
+	created := ep.Status.HookID
+
+	// up to date
+	ep = syncTestHook(t, c, ep, api, "1")
+	if changes := forge.changes(); len(changes) != 0 {
+		t.Errorf("up to date: got changes %v", changes)
+	}
+
+	// drifted
+	drift(forge.hook(created))
+	ep = syncTestHook(t, c, ep, api, "1")
+	if changes := forge.changes(); len(changes) != 1 || !strings.HasSuffix(changes[0], " "+created) {
+		t.Errorf("drift: got changes %v", changes)
+	}
+
+	// the secret was rotated
+	ep = syncTestHook(t, c, ep, api, "2")
+	if changes := forge.changes(); len(changes) != 1 || !strings.HasSuffix(changes[0], " "+created) || ep.Status.HookSecretVersion != "2" {
+		t.Errorf("rotation: got changes %v and status %+v", changes, ep.Status)
+	}
+
+	// the hook was created, but its ID was not recorded: it is found on a later page, and updated
+	// as its secret is unknown
+	existing := public(forge.hook(created))
+	delete(existing, "id")
+	if err := api.delete(context.TODO(), created); err != nil {
+		t.Fatal(err)
+	}
+	adopted := fmt.Sprint(forge.addHooks(2*fakeForgePageSize, existing))
+	forge.changes()
+	ep, err = c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		status.HookID = ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep = syncTestHook(t, c, ep, api, "2")
+	if changes := forge.changes(); ep.Status.HookID != adopted || len(changes) != 1 || !strings.HasSuffix(changes[0], " "+adopted) {
+		t.Errorf("adoption: got changes %v and status %+v, want hook %s adopted", changes, ep.Status, adopted)
+	}
+
+	// deleted behind the back of the operator: created again
+	if err := api.delete(context.TODO(), adopted); err != nil {
+		t.Fatal(err)
+	}
+	forge.changes()
+	ep = syncTestHook(t, c, ep, api, "2")
+	if changes := forge.changes(); len(changes) != 1 || changes[0] != "POST" || ep.Status.HookID == adopted {
+		t.Errorf("recreation: got changes %v and status %+v", changes, ep.Status)
+	}
+
+	// deleted with the eventprovider
+	if err := c.finalizeForgeHook(ep, reg); err != nil {
+		t.Fatalf("cannot finalize hook: %v", err)
+	}
+	if changes := forge.changes(); len(changes) != 1 || changes[0] != "DELETE "+ep.Status.HookID {
+		t.Errorf("finalize: got changes %v", changes)
+	}
+	if err := c.finalizeForgeHook(ep, reg); err != nil {
+		t.Errorf("deleting a missing hook failed: %v", err)
+	}
+	forge.changes()
+
+	// the secret is gone from the cache: the eventprovider is not held forever
+	withoutSecret, _ := newTestController(t)
+	c.secretsLister = withoutSecret.secretsLister
+	if err := c.finalizeForgeHook(ep, reg); err != nil {
+		t.Errorf("finalizing without the secret failed: %v", err)
+	}
+	if changes := forge.changes(); len(changes) != 0 {
+		t.Errorf("finalize without the secret: got changes %v", changes)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// httpRouteResource is the Gateway API HTTPRoute resource
+var httpRouteResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+// discoverHTTPRouteAPI checks whether the Gateway API CRDs are installed in the cluster
+func discoverHTTPRouteAPI(client discovery.DiscoveryInterface) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(httpRouteResource.GroupVersion().String())
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot discover resources for %s: %v", httpRouteResource.GroupVersion(), err)
+	}
+
+	for _, r := range resources.APIResources {
+		if r.Name == httpRouteResource.Resource {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// syncHTTPRoute makes sure the HTTPRoute for an eventprovider exists and is up to date, and returns
+// the public URL of the handler once the parent Gateway accepted the route.
+// An empty URL means the route is not accepted yet - the eventprovider is queued
+// again when the route status changes.
+func (c *Controller) syncHTTPRoute(ep *v1alpha1.EventProvider, routeName, serviceName string) (string, error) {
+	if c.httpRouteLister == nil {
+		return "", fmt.Errorf("the Gateway API is not installed in the cluster")
+	}
+
+	parentRef := httpRouteParentRef(ep, c.config)
+	if parentRef.Name == "" {
+		return "", fmt.Errorf("no parent gateway configured for the HTTPRoute")
+	}
+
+	hostnames := httpRouteHostnames(ep)
+	if len(hostnames) == 0 {
+		return "", fmt.Errorf("no hostname configured for the HTTPRoute")
+	}
+
+	desired := newHTTPRoute(ep, routeName, serviceName, backendPort(ep, c.config), parentRef, hostnames)
+	var route *unstructured.Unstructured
+	obj, err := c.httpRouteLister.ByNamespace(ep.Namespace).Get(routeName)
+	if errors.IsNotFound(err) {
+		route, err = c.dynamicclientset.Resource(httpRouteResource).Namespace(ep.Namespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+	} else if err == nil {
+		var ok bool
+		if route, ok = obj.(*unstructured.Unstructured); !ok {
+			return "", fmt.Errorf("unexpected object in the HTTPRoute cache: %#v", obj)
+		}
+		if httpRouteDrifted(route, desired) {
+			// NEVER modify objects from the store. It's a read-only, local cache.
+			routeCopy := route.DeepCopy()
+			for _, field := range []string{"parentRefs", "hostnames", "rules"} {
+				value, _, _ := unstructured.NestedFieldCopy(desired.Object, "spec", field)
+				if err = unstructured.SetNestedField(routeCopy.Object, value, "spec", field); err != nil {
+					return "", fmt.Errorf("cannot set httproute %s: %v", field, err)
+				}
+			}
+			route, err = c.dynamicclientset.Resource(httpRouteResource).Namespace(ep.Namespace).Update(context.TODO(), routeCopy, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	glog.V(4).Infof("httproute name: %v", route.GetName())
+
+	if !httpRouteAccepted(route, parentRef) {
+		glog.Infof("HTTPRoute %s/%s not accepted by gateway %s/%s yet", ep.Namespace, routeName, parentRef.Namespace, parentRef.Name)
+		return "", nil
+	}
+
+	return fmt.Sprintf("https://%s", hostnames[0]), nil
+}
+
+// newHTTPRoute creates an HTTPRoute owned by the eventprovider, routing its hostnames to serviceName
+func newHTTPRoute(ep *v1alpha1.EventProvider, routeName, serviceName string, port int32, parentRef v1alpha1.ParentReference, hostnames []string) *unstructured.Unstructured {
+	parent := map[string]interface{}{
+		"name":      parentRef.Name,
+		"namespace": parentRef.Namespace,
+	}
+	if parentRef.SectionName != "" {
+		parent["sectionName"] = parentRef.SectionName
+	}
+
+	routeHostnames := make([]interface{}, 0, len(hostnames))
+	for _, h := range hostnames {
+		routeHostnames = append(routeHostnames, h)
+	}
+
+	route := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"parentRefs": []interface{}{parent},
+				"hostnames":  routeHostnames,
+				"rules": []interface{}{
+					map[string]interface{}{
+						"matches": []interface{}{
+							map[string]interface{}{
+								"path": map[string]interface{}{
+									"type":  "PathPrefix",
+									"value": "/",
+								},
+							},
+						},
+						"backendRefs": []interface{}{
+							map[string]interface{}{
+								"name": serviceName,
+								"port": int64(port),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	route.SetAPIVersion(httpRouteResource.GroupVersion().String())
+	route.SetKind("HTTPRoute")
+	route.SetName(routeName)
+	route.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(ep, v1alpha1.SchemeGroupVersion.WithKind("EventProvider")),
+	})
+
+	return route
+}
+
+// httpRouteDrifted returns true when the parentRefs, hostnames or backendRefs of the route differ from
+// the desired ones. Fields defaulted by the API server, such as the group and kind of a reference, are ignored.
+func httpRouteDrifted(current, desired *unstructured.Unstructured) bool {
+	currentParents, currentHostnames, currentBackends := httpRouteTargets(current)
+	desiredParents, desiredHostnames, desiredBackends := httpRouteTargets(desired)
+
+	return !equality.Semantic.DeepEqual(currentParents, desiredParents) ||
+		!equality.Semantic.DeepEqual(currentHostnames, desiredHostnames) ||
+		!equality.Semantic.DeepEqual(currentBackends, desiredBackends)
+}
+
+// httpRouteTargets returns the parentRefs of a route as namespace/name/sectionName, its hostnames,
+// and the backendRefs of its rules as name:port
+func httpRouteTargets(route *unstructured.Unstructured) (parents, hostnames, backends []string) {
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	for _, p := range parentRefs {
+		parent, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(parent, "name")
+		namespace, _, _ := unstructured.NestedString(parent, "namespace")
+		sectionName, _, _ := unstructured.NestedString(parent, "sectionName")
+		parents = append(parents, fmt.Sprintf("%s/%s/%s", namespace, name, sectionName))
+	}
+
+	hostnames, _, _ = unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		backendRefs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, b := range backendRefs {
+			backend, ok := b.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(backend, "name")
+			port, _, _ := unstructured.NestedInt64(backend, "port")
+			backends = append(backends, fmt.Sprintf("%s:%d", name, port))
+		}
+	}
+
+	return parents, hostnames, backends
+}
+
+// httpRouteAccepted checks the route status for an Accepted condition set by the parent gateway
+func httpRouteAccepted(route *unstructured.Unstructured, parentRef v1alpha1.ParentReference) bool {
+	parents, _, _ := unstructured.NestedSlice(route.Object, "status", "parents")
+	for _, p := range parents {
+		parent, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(parent, "parentRef", "name")
+		namespace, _, _ := unstructured.NestedString(parent, "parentRef", "namespace")
+		if namespace == "" {
+			namespace = route.GetNamespace()
+		}
+		if name != parentRef.Name || namespace != parentRef.Namespace {
+			continue
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(parent, "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if condition["type"] == "Accepted" && condition["status"] == "True" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// httpRouteParentRef returns the gateway the eventprovider route attaches to,
+// falling back to the operator-wide parent gateway
+func httpRouteParentRef(ep *v1alpha1.EventProvider, config ControllerConfig) v1alpha1.ParentReference {
+	parentRef := ep.Spec.Exposure.HTTPRoute.ParentRef
+	if parentRef.Name == "" {
+		parentRef = config.HTTPRouteParent
+	}
+	if parentRef.Namespace == "" {
+		parentRef.Namespace = ep.Namespace
+	}
+
+	return parentRef
+}
+
+// httpRouteHostnames returns the hostnames of the eventprovider route, defaulting to its host
+func httpRouteHostnames(ep *v1alpha1.EventProvider) []string {
+	if len(ep.Spec.Exposure.HTTPRoute.Hostnames) > 0 {
+		return ep.Spec.Exposure.HTTPRoute.Hostnames
+	}
+	if ep.Spec.Host != "" {
+		return []string{ep.Spec.Host}
+	}
+
+	return nil
+}
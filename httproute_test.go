@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSyncHTTPRoute(t *testing.T) {
+	ep := newTestEventProvider("handler")
+	ep.Spec.Host = "events.example.com"
+	ep.Spec.Exposure.HTTPRoute.ParentRef = v1alpha1.ParentReference{Name: "public", Namespace: "gateways"}
+
+	sync := func(existing *unstructured.Unstructured) (*unstructured.Unstructured, int) {
+		var objects []runtime.Object
+		if existing != nil {
+			objects = append(objects, existing)
+		}
+		c, _ := newTestController(t)
+		client, lister := newTestDynamicClient(t, c, httpRouteResource, "HTTPRouteList", objects...)
+		c.httpRouteLister = lister
+		if _, err := c.syncHTTPRoute(ep, "handlerroute", "handlerservice"); err != nil {
+			t.Fatalf("cannot sync httproute: %v", err)
+		}
+		route, err := client.Resource(httpRouteResource).Namespace("default").Get(context.TODO(), "handlerroute", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var writes int
+		for _, action := range client.Actions() {
+			if action.GetVerb() != "get" {
+				writes++
+			}
+		}
+		return route, writes
+	}
+
+	route, _ := sync(nil)
+	parents, hostnames, backends := httpRouteTargets(route)
+	if len(parents) != 1 || parents[0] != "gateways/public/" || len(hostnames) != 1 || hostnames[0] != "events.example.com" || len(backends) != 1 || backends[0] != "handlerservice:80" {
+		t.Fatalf("got parents %v, hostnames %v and backends %v", parents, hostnames, backends)
+	}
+
+	// fields defaulted by the API server are not drift
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	parentRefs[0].(map[string]interface{})["kind"] = "Gateway"
+	if err := unstructured.SetNestedSlice(route.Object, parentRefs, "spec", "parentRefs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, writes := sync(route); writes != 0 {
+		t.Errorf("up to date: got %d writes", writes)
+	}
+
+	tests := []struct {
+		name   string
+		update func(ep *v1alpha1.EventProvider)
+		want   func(parents, hostnames, backends []string) bool
+	}{
+		{
+			name:   "parent",
+			update: func(ep *v1alpha1.EventProvider) { ep.Spec.Exposure.HTTPRoute.ParentRef.SectionName = "https" },
+			want:   func(parents, _, _ []string) bool { return len(parents) == 1 && parents[0] == "gateways/public/https" },
+		},
+		{
+			name:   "hostnames",
+			update: func(ep *v1alpha1.EventProvider) { ep.Spec.Exposure.HTTPRoute.Hostnames = []string{"hooks.example.com"} },
+			want:   func(_, hostnames, _ []string) bool { return len(hostnames) == 1 && hostnames[0] == "hooks.example.com" },
+		},
+		{
+			name:   "backend",
+			update: func(ep *v1alpha1.EventProvider) { ep.Spec.Handler.Port = 8080 },
+			want:   func(_, _, backends []string) bool { return len(backends) == 1 && backends[0] == "handlerservice:8080" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := ep.DeepCopy()
+			defer func() { ep = saved }()
+
+			tt.update(ep)
+			updated, writes := sync(route)
+			parents, hostnames, backends := httpRouteTargets(updated)
+			if writes != 1 || !tt.want(parents, hostnames, backends) {
+				t.Errorf("got %d writes, parents %v, hostnames %v and backends %v", writes, parents, hostnames, backends)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	// ingressAPINetworkingV1 is the Ingress API served by current clusters
+	ingressAPINetworkingV1 = "networking.k8s.io/v1"
+	// ingressAPIExtensionsV1beta1 is the legacy Ingress API, only used when
+	// the cluster does not serve networking.k8s.io/v1 Ingresses
+	ingressAPIExtensionsV1beta1 = "extensions/v1beta1"
+
+	// legacyIngressClassAnnotation selects the ingress controller for
+	// clusters without IngressClass support
+	legacyIngressClassAnnotation = "kubernetes.io/ingress.class"
+
+	// managedAnnotationsAnnotation lists the annotations set by the operator, so that
+	// the ones no longer desired are removed without touching the ones added by others
+	managedAnnotationsAnnotation = "eventprovider.k8s.io/managed-annotations"
+)
+
+// discoverIngressAPI asks the API server which Ingress API it serves,
+// preferring networking.k8s.io/v1
+func discoverIngressAPI(client discovery.DiscoveryInterface) (string, error) {
+	for _, groupVersion := range []string{ingressAPINetworkingV1, ingressAPIExtensionsV1beta1} {
+		resources, err := client.ServerResourcesForGroupVersion(groupVersion)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot discover resources for %s: %v", groupVersion, err)
+		}
+
+		for _, r := range resources.APIResources {
+			if r.Name == "ingresses" {
+				return groupVersion, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("the cluster does not serve any supported Ingress API")
+}
+
+// syncIngress makes sure the Ingress for an eventprovider exists and is up to date, using
+// whichever Ingress API was discovered when the controller started
+func (c *Controller) syncIngress(ep *v1alpha1.EventProvider, ingressName, serviceName string) error {
+	if c.ingressAPI == ingressAPIExtensionsV1beta1 {
+		desired := newLegacyIngress(ep, ingressName, serviceName, c.config)
+		ingress, err := c.legacyIngressLister.Ingresses(ep.Namespace).Get(ingressName)
+		switch {
+		case errors.IsNotFound(err):
+			_, err = c.kubeclientset.ExtensionsV1beta1().Ingresses(ep.Namespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+		case err != nil:
+		case annotationsDrifted(ingress.Annotations, desired.Annotations) || !equality.Semantic.DeepEqual(ingress.Spec, desired.Spec):
+			ingressCopy := ingress.DeepCopy()
+			ingressCopy.Annotations = mergeAnnotations(ingress.Annotations, desired.Annotations)
+			ingressCopy.Spec = desired.Spec
+			_, err = c.kubeclientset.ExtensionsV1beta1().Ingresses(ep.Namespace).Update(context.TODO(), ingressCopy, metav1.UpdateOptions{})
+		default:
+			glog.V(4).Infof("ingress %s/%s is up to date", ep.Namespace, ingressName)
+		}
+
+		return err
+	}
+
+	desired := newIngress(ep, ingressName, serviceName, c.config)
+	ingress, err := c.ingressLister.Ingresses(ep.Namespace).Get(ingressName)
+	switch {
+	case errors.IsNotFound(err):
+		_, err = c.kubeclientset.NetworkingV1().Ingresses(ep.Namespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+	case err != nil:
+	case annotationsDrifted(ingress.Annotations, desired.Annotations) || !equality.Semantic.DeepEqual(ingress.Spec, desired.Spec):
+		ingressCopy := ingress.DeepCopy()
+		ingressCopy.Annotations = mergeAnnotations(ingress.Annotations, desired.Annotations)
+		ingressCopy.Spec = desired.Spec
+		_, err = c.kubeclientset.NetworkingV1().Ingresses(ep.Namespace).Update(context.TODO(), ingressCopy, metav1.UpdateOptions{})
+	default:
+		glog.V(4).Infof("ingress %s/%s is up to date", ep.Namespace, ingressName)
+	}
+
+	return err
+}
+
+// annotationsDrifted returns true when an annotation set by the operator is missing or has another
+// value, or when an annotation it no longer manages is still set. Annotations added by others,
+// such as ingress controllers, are not drift.
+func annotationsDrifted(current, desired map[string]string) bool {
+	for k, v := range desired {
+		if value, ok := current[k]; !ok || value != v {
+			return true
+		}
+	}
+	for _, k := range managedAnnotations(current) {
+		if _, ok := desired[k]; ok {
+			continue
+		}
+		if _, ok := current[k]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeAnnotations returns the current annotations, overridden by the desired ones.
+// Annotations previously set by the operator and no longer desired are removed.
+func mergeAnnotations(current, desired map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range current {
+		merged[k] = v
+	}
+	for _, k := range managedAnnotations(current) {
+		delete(merged, k)
+	}
+	for k, v := range desired {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// withManagedAnnotations records the keys of the desired annotations in the managed annotations annotation
+func withManagedAnnotations(annotations map[string]string) map[string]string {
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		if k != managedAnnotationsAnnotation {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	annotations[managedAnnotationsAnnotation] = strings.Join(keys, ",")
+
+	return annotations
+}
+
+// managedAnnotations returns the keys of the annotations the operator set on an object
+func managedAnnotations(annotations map[string]string) []string {
+	value := annotations[managedAnnotationsAnnotation]
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
+
+// ingressName returns the name of the Ingress of an eventprovider, which is also
+// the name of the secret holding its certificate
+func ingressName(ep *v1alpha1.EventProvider) string {
+	return fmt.Sprintf("%s%singress", ep.Name, ep.Spec.Host)
+}
+
+// newIngress creates a networking.k8s.io/v1 Ingress routing the eventprovider host to its service
+func newIngress(ep *v1alpha1.EventProvider, ingressName, serviceName string, config ControllerConfig) *networkingv1.Ingress {
+	pathType := networkingv1.PathTypePrefix
+	backend := networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{
+			Name: serviceName,
+			Port: networkingv1.ServiceBackendPort{
+				Number: backendPort(ep, config),
+			},
+		},
+	}
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ingressName,
+			Annotations: withManagedAnnotations(ingressAnnotations(ep, config)),
+		},
+		Spec: networkingv1.IngressSpec{
+			DefaultBackend: &backend,
+			TLS: []networkingv1.IngressTLS{
+				{
+					Hosts:      []string{ep.Spec.Host},
+					SecretName: ingressName,
+				},
+			},
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: ep.Spec.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend:  backend,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if className := ingressClassName(ep, config); className != "" {
+		ingress.Spec.IngressClassName = &className
+	}
+
+	return ingress
+}
+
+// newLegacyIngress creates an extensions/v1beta1 Ingress for clusters that do not serve networking.k8s.io/v1.
+// These clusters predate IngressClass, so the class is set through the legacy annotation.
+func newLegacyIngress(ep *v1alpha1.EventProvider, ingressName, serviceName string, config ControllerConfig) *extensionsv1beta1.Ingress {
+	annotations := ingressAnnotations(ep, config)
+	if className := ingressClassName(ep, config); className != "" {
+		annotations[legacyIngressClassAnnotation] = className
+	}
+
+	backend := extensionsv1beta1.IngressBackend{
+		ServiceName: serviceName,
+		ServicePort: intstr.FromInt(int(backendPort(ep, config))),
+	}
+
+	return &extensionsv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ingressName,
+			Annotations: withManagedAnnotations(annotations),
+		},
+		Spec: extensionsv1beta1.IngressSpec{
+			Backend: &backend,
+			TLS: []extensionsv1beta1.IngressTLS{
+				{
+					Hosts:      []string{ep.Spec.Host},
+					SecretName: ingressName,
+				},
+			},
+			Rules: []extensionsv1beta1.IngressRule{
+				{
+					Host: ep.Spec.Host,
+					IngressRuleValue: extensionsv1beta1.IngressRuleValue{
+						HTTP: &extensionsv1beta1.HTTPIngressRuleValue{
+							Paths: []extensionsv1beta1.HTTPIngressPath{
+								{
+									Path:    "/",
+									Backend: backend,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ingressClassName returns the IngressClass of the eventprovider, falling back
+// to the operator-wide class. An empty class means the cluster default is used.
+func ingressClassName(ep *v1alpha1.EventProvider, config ControllerConfig) string {
+	if ep.Spec.Ingress.ClassName != "" {
+		return ep.Spec.Ingress.ClassName
+	}
+
+	return config.IngressClass
+}
+
+// ingressAnnotations merges the operator-wide annotations with the ones set on the eventprovider.
+// When the operator manages the certificate itself, the legacy tls-acme annotation is dropped
+// so that the certificate is not issued twice.
+func ingressAnnotations(ep *v1alpha1.EventProvider, config ControllerConfig) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range config.IngressAnnotations {
+		annotations[k] = v
+	}
+	for k, v := range ep.Spec.Ingress.Annotations {
+		annotations[k] = v
+	}
+	if managesCertificate(ep, config) {
+		delete(annotations, tlsAcmeAnnotation)
+	}
+
+	return annotations
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSyncIngress(t *testing.T) {
+	ep := newTestEventProvider("handler")
+	ep.Spec.Host = "events.example.com"
+	ep.Spec.Ingress.ClassName = "nginx"
+	ep.Spec.Ingress.Annotations = map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "1m"}
+
+	sync := func(existing *networkingv1.Ingress) (*networkingv1.Ingress, int) {
+		c, client := newTestController(t)
+		if existing != nil {
+			c, client = newTestController(t, existing)
+		}
+		if err := c.syncIngress(ep, "handleringress", "handlerservice"); err != nil {
+			t.Fatalf("cannot sync ingress: %v", err)
+		}
+		ingress, err := client.NetworkingV1().Ingresses("default").Get(context.TODO(), "handleringress", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var writes int
+		for _, action := range client.Actions() {
+			if action.GetVerb() != "get" {
+				writes++
+			}
+		}
+		return ingress, writes
+	}
+
+	ingress, _ := sync(nil)
+	if *ingress.Spec.IngressClassName != "nginx" || ingress.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"] != "1m" {
+		t.Fatalf("got ingress %+v", ingress)
+	}
+
+	// annotations added by others are not drift
+	ingress.Annotations["ingress.kubernetes.io/status"] = "ready"
+	if _, writes := sync(ingress); writes != 0 {
+		t.Errorf("up to date: got %d writes", writes)
+	}
+
+	// the class and an annotation changed
+	ep.Spec.Ingress.ClassName = "traefik"
+	ep.Spec.Ingress.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"] = "8m"
+	updated, writes := sync(ingress)
+	if writes != 1 || *updated.Spec.IngressClassName != "traefik" || updated.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"] != "8m" || updated.Annotations["ingress.kubernetes.io/status"] != "ready" {
+		t.Errorf("got %d writes and ingress %+v", writes, updated)
+	}
+
+	// the backend port changed
+	ep.Spec.Handler.Port = 8080
+	updated, _ = sync(updated)
+	if port := updated.Spec.DefaultBackend.Service.Port.Number; port != 8080 {
+		t.Errorf("got backend port %d", port)
+	}
+}
+
+func TestSyncIngressRemovesAnnotations(t *testing.T) {
+	ep := newTestEventProvider("handler")
+	ep.Spec.Host = "events.example.com"
+	ep.Spec.Ingress.Annotations = map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "1m"}
+	extras := map[string]string{"example.com/team": "events"}
+
+	sync := func(existing *networkingv1.Ingress) *networkingv1.Ingress {
+		c, client := newTestController(t)
+		if existing != nil {
+			c, client = newTestController(t, existing)
+		}
+		c.config.IngressAnnotations = extras
+		if err := c.syncIngress(ep, "handleringress", "handlerservice"); err != nil {
+			t.Fatalf("cannot sync ingress: %v", err)
+		}
+		ingress, err := client.NetworkingV1().Ingresses("default").Get(context.TODO(), "handleringress", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ingress
+	}
+
+	ingress := sync(nil)
+	if got := ingress.Annotations[managedAnnotationsAnnotation]; got != "example.com/team,nginx.ingress.kubernetes.io/proxy-body-size" {
+		t.Fatalf("got managed annotations %q", got)
+	}
+	ingress.Annotations["ingress.kubernetes.io/status"] = "ready"
+
+	// both the eventprovider and the operator-level annotations are no longer desired
+	ep.Spec.Ingress.Annotations = nil
+	extras = nil
+	updated := sync(ingress)
+	for _, k := range []string{"example.com/team", "nginx.ingress.kubernetes.io/proxy-body-size"} {
+		if _, ok := updated.Annotations[k]; ok {
+			t.Errorf("annotation %s was not removed", k)
+		}
+	}
+	if updated.Annotations["ingress.kubernetes.io/status"] != "ready" {
+		t.Errorf("annotation added by others was removed: %+v", updated.Annotations)
+	}
+	if got := updated.Annotations[managedAnnotationsAnnotation]; got != "" {
+		t.Errorf("got managed annotations %q", got)
+	}
+}
+
+func TestSyncIngressDropsTLSAcme(t *testing.T) {
+	ep := newTestEventProvider("handler")
+	ep.Spec.Host = "events.example.com"
+
+	c, client := newTestController(t)
+	c.config.IngressAnnotations = map[string]string{tlsAcmeAnnotation: "true"}
+	if err := c.syncIngress(ep, "handleringress", "handlerservice"); err != nil {
+		t.Fatalf("cannot sync ingress: %v", err)
+	}
+	ingress, err := client.NetworkingV1().Ingresses("default").Get(context.TODO(), "handleringress", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations[tlsAcmeAnnotation] != "true" {
+		t.Fatalf("got annotations %+v", ingress.Annotations)
+	}
+
+	// once cert-manager issues the certificate, the Ingress must not ask for it again
+	ep.Spec.TLS.IssuerRef.Name = "letsencrypt"
+	c, client = newTestController(t, ingress)
+	c.config.IngressAnnotations = map[string]string{tlsAcmeAnnotation: "true"}
+	if err := c.syncIngress(ep, "handleringress", "handlerservice"); err != nil {
+		t.Fatalf("cannot sync ingress: %v", err)
+	}
+	ingress, err = client.NetworkingV1().Ingresses("default").Get(context.TODO(), "handleringress", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ingress.Annotations[tlsAcmeAnnotation]; ok {
+		t.Errorf("got annotations %+v", ingress.Annotations)
+	}
+}
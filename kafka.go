@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/kafka"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// providerKafka is the provider name of eventproviders consuming Kafka topics
+const providerKafka = "kafka"
+
+// syncKafka runs the consumer group of the eventprovider. Every replica of the operator
+// joins the group, so partitions are balanced across them.
+func (c *Controller) syncKafka(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.Kafka
+	if spec == nil {
+		return fmt.Errorf("the kafka provider requires a kafka spec")
+	}
+	if len(spec.Brokers) == 0 || len(spec.Topics) == 0 {
+		return fmt.Errorf("Kafka brokers and topics are required")
+	}
+
+	var earliest bool
+	switch spec.InitialOffset {
+	case "", "Latest":
+	case "Earliest":
+		earliest = true
+	default:
+		return fmt.Errorf("unknown initial offset %q, expected Latest or Earliest", spec.InitialOffset)
+	}
+
+	group := spec.ConsumerGroup
+	if group == "" {
+		group = fmt.Sprintf("%s-%s", ep.Namespace, ep.Name)
+	}
+
+	tlsConfig, tlsVersion, err := c.clientTLS(ep, spec.TLS)
+	if err != nil {
+		return err
+	}
+
+	var mechanism sasl.Mechanism
+	var saslVersion string
+	if spec.SASL != nil {
+		var username, password string
+		if username, password, saslVersion, err = c.userCredentials(ep, spec.SASL.SecretName); err != nil {
+			return err
+		}
+
+		switch strings.ToUpper(spec.SASL.Mechanism) {
+		case "", "PLAIN":
+			mechanism = plain.Auth{User: username, Pass: password}.AsMechanism()
+		case "SCRAM-SHA-256":
+			mechanism = scram.Auth{User: username, Pass: password}.AsSha256Mechanism()
+		case "SCRAM-SHA-512":
+			mechanism = scram.Auth{User: username, Pass: password}.AsSha512Mechanism()
+		default:
+			return fmt.Errorf("unknown SASL mechanism %q", spec.SASL.Mechanism)
+		}
+	}
+
+	return c.syncSource(ep, &kafka.Source{
+		Brokers:     spec.Brokers,
+		Topics:      spec.Topics,
+		Group:       group,
+		Earliest:    earliest,
+		TLS:         tlsConfig,
+		SASL:        mechanism,
+		Retry:       retryPolicy(spec.Retry),
+		EventSource: eventProviderURI(ep),
+	}, spec, tlsVersion, saslVersion)
+}
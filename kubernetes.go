@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/kubewatch"
+	"github.com/radu-matei/events-operator/pkg/source"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// providerKubernetes is the provider name of eventproviders watching objects of the cluster
+const providerKubernetes = "kubernetes"
+
+// syncKubernetes watches the objects selected by the eventprovider. The operator can watch
+// anything, so the service account of the eventprovider must be allowed to watch the objects:
+// an eventprovider only ever sees what its namespace could read anyway.
+func (c *Controller) syncKubernetes(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.Kubernetes
+	if spec == nil {
+		return fmt.Errorf("the kubernetes provider requires a kubernetes spec")
+	}
+	for _, event := range spec.Events {
+		if event != kubewatch.EventAdd && event != kubewatch.EventUpdate && event != kubewatch.EventDelete {
+			return fmt.Errorf("unknown kubernetes event %q, expected add, update or delete", event)
+		}
+	}
+	if spec.Data != "" && spec.Data != "Object" && spec.Data != "Patch" {
+		return fmt.Errorf("unknown kubernetes event data %q, expected Object or Patch", spec.Data)
+	}
+
+	mapping, err := c.kubernetesMapping(spec)
+	if err != nil {
+		return err
+	}
+
+	namespace := spec.Namespace
+	switch {
+	case mapping.Scope.Name() == meta.RESTScopeNameRoot, namespace == "*":
+		namespace = metav1.NamespaceAll
+	case namespace == "":
+		namespace = ep.Namespace
+	}
+
+	if err := c.authorizeWatch(ep, mapping.Resource, namespace); err != nil {
+		c.sources.Stop(ep.Namespace, ep.Name)
+		_, statusErr := c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:    v1alpha1.ConditionSourceReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Forbidden",
+				Message: err.Error(),
+			})
+		})
+		if statusErr != nil {
+			return fmt.Errorf("cannot update eventprovider status: %v", statusErr)
+		}
+		return err
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("cannot get the identity of the operator replica: %v", err)
+	}
+
+	// every replica would emit the same changes, so only the holder of the lease watches the objects
+	return c.syncSource(ep, &source.Elected{
+		Source: &kubewatch.Source{
+			Client:        c.dynamicclientset,
+			Resource:      mapping.Resource,
+			Namespace:     namespace,
+			LabelSelector: spec.LabelSelector,
+			FieldSelector: spec.FieldSelector,
+			Events:        spec.Events,
+			Patch:         spec.Data == "Patch",
+			EventSource:   eventProviderURI(ep),
+		},
+		Client:    c.kubeclientset,
+		Namespace: ep.Namespace,
+		Name:      fmt.Sprintf("%s-kubernetes", ep.Name),
+		Identity:  identity,
+	}, spec, namespace)
+}
+
+// kubernetesMapping returns the resource of the objects watched by the eventprovider, and its scope
+func (c *Controller) kubernetesMapping(spec *v1alpha1.KubernetesSpec) (*meta.RESTMapping, error) {
+	gv, err := schema.ParseGroupVersion(spec.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apiVersion %s: %v", spec.APIVersion, err)
+	}
+
+	kind := gv.WithKind(spec.Kind)
+	if spec.Resource != "" {
+		if kind, err = c.restMapper.KindFor(gv.WithResource(spec.Resource)); err != nil {
+			return nil, fmt.Errorf("cannot find resource %s in %s: %v", spec.Resource, spec.APIVersion, err)
+		}
+	} else if spec.Kind == "" {
+		return nil, fmt.Errorf("either a kind or a resource is required")
+	}
+
+	mapping, err := c.restMapper.RESTMapping(kind.GroupKind(), gv.Version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the resource of kind %s: %v", kind.Kind, err)
+	}
+
+	return mapping, nil
+}
+
+// authorizeWatch checks the service account of the eventprovider may list and watch resource in namespace
+func (c *Controller) authorizeWatch(ep *v1alpha1.EventProvider, resource schema.GroupVersionResource, namespace string) error {
+	serviceAccount := ep.Spec.Kubernetes.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+
+	where := "all namespaces"
+	if namespace != metav1.NamespaceAll {
+		where = "namespace " + namespace
+	}
+
+	for _, verb := range []string{"list", "watch"} {
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   fmt.Sprintf("system:serviceaccount:%s:%s", ep.Namespace, serviceAccount),
+				Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + ep.Namespace, "system:authenticated"},
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      verb,
+					Group:     resource.Group,
+					Version:   resource.Version,
+					Resource:  resource.Resource,
+				},
+			},
+		}
+
+		result, err := c.kubeclientset.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), review, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("cannot review access of service account %s/%s: %v", ep.Namespace, serviceAccount, err)
+		}
+		if !result.Status.Allowed {
+			return fmt.Errorf("service account %s/%s may not %s %s in %s", ep.Namespace, serviceAccount, verb, resource.GroupResource(), where)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSyncKubernetesForbidden(t *testing.T) {
+	ep := newTestEventProvider("configmaps")
+	ep.Spec.ProviderName = providerKubernetes
+	ep.Spec.Sink = &v1alpha1.SinkSpec{URI: "http://sink.default.svc.cluster.local"}
+	ep.Spec.Kubernetes = &v1alpha1.KubernetesSpec{APIVersion: "v1", Kind: "ConfigMap"}
+
+	c, client := newTestController(t, ep)
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	newTestDynamicClient(t, c, configMaps, "ConfigMapList")
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	c.restMapper = mapper
+
+	var allowed atomic.Bool
+	allowed.Store(true)
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = allowed.Load()
+		return true, review, nil
+	})
+	t.Cleanup(func() { c.sources.Stop(ep.Namespace, ep.Name) })
+
+	if err := c.syncKubernetes(ep); err != nil {
+		t.Fatalf("cannot sync: %v", err)
+	}
+	deadline := time.Now().Add(sourcetest.Timeout)
+	for !c.sources.State(ep.Namespace, ep.Name).Ready && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !c.sources.State(ep.Namespace, ep.Name).Ready {
+		t.Fatalf("the source is not ready after %s", sourcetest.Timeout)
+	}
+
+	// the service account may not watch configmaps anymore
+	allowed.Store(false)
+	if err := c.syncKubernetes(ep); err == nil {
+		t.Fatal("sync succeeded, want an error")
+	}
+	if state := c.sources.State(ep.Namespace, ep.Name); state.Ready || state.Err != nil {
+		t.Errorf("the source was not stopped: got state %+v", state)
+	}
+
+	updated, err := c.epclientset.EventproviderV1alpha1().EventProviders(ep.Namespace).Get(context.TODO(), ep.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.ConditionSourceReady)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "Forbidden" {
+		t.Errorf("got condition %+v", condition)
+	}
+}
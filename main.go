@@ -1,14 +1,26 @@
 package main
 
 import (
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
 	clientset "github.com/radu-matei/events-operator/pkg/client/clientset/versioned"
 	informers "github.com/radu-matei/events-operator/pkg/client/informers/externalversions"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/github"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -16,6 +28,35 @@ import (
 
 var (
 	kubeconfig = getEnvVarOrExit("KUBECONFIG")
+
+	// defaultIngressClass is the IngressClass used for eventproviders that do not set one
+	defaultIngressClass = os.Getenv("INGRESS_CLASS")
+	// defaultIngressAnnotations is a comma separated list of key=value annotations added to every Ingress
+	defaultIngressAnnotations = getEnvVarOrDefault("INGRESS_ANNOTATIONS", "kubernetes.io/tls-acme=true")
+	// defaultHTTPRouteParent is the namespace/name of the Gateway HTTPRoutes attach to by default
+	defaultHTTPRouteParent = os.Getenv("HTTPROUTE_PARENT")
+	// defaultTLSIssuer is the kind/name of the cert-manager issuer of handler certificates
+	defaultTLSIssuer = os.Getenv("TLS_ISSUER")
+	// sharedHost is the host eventproviders without a host of their own are exposed on
+	sharedHost = os.Getenv("SHARED_HOST")
+	// operatorNamespace is the namespace the operator runs in
+	operatorNamespace = getEnvVarOrDefault("OPERATOR_NAMESPACE", "default")
+	// clusterDomain is the DNS domain of the cluster
+	clusterDomain = getEnvVarOrDefault("CLUSTER_DOMAIN", "cluster.local")
+	// receiverImage is the image of the receiver sidecar, built from cmd/receiver
+	receiverImage = os.Getenv("RECEIVER_IMAGE")
+	// gatewayAddress is the address the event gateway listens on
+	gatewayAddress = getEnvVarOrDefault("GATEWAY_ADDR", ":8080")
+	// gatewayService is the name of the service in front of the event gateway
+	gatewayService = getEnvVarOrDefault("GATEWAY_SERVICE", "events-operator-gateway")
+	// gatewayPort is the port of the service in front of the event gateway
+	gatewayPort = getEnvVarOrDefault("GATEWAY_PORT", "80")
+	// metricsAddress is the address Prometheus metrics are served on
+	metricsAddress = getEnvVarOrDefault("METRICS_ADDR", ":9090")
+	// githubAPIURL is the default base URL of the GitHub REST API
+	githubAPIURL = getEnvVarOrDefault("GITHUB_API_URL", github.DefaultAPIURL)
+	// contentMode is the CloudEvents content mode the event gateway dispatches events in
+	contentMode = getEnvVarOrDefault("CLOUDEVENTS_MODE", string(cloudevents.ModeBinary))
 )
 
 func main() {
@@ -46,13 +87,86 @@ func main() {
 		glog.Fatalf("Error building example clientset: %s", err.Error())
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		glog.Fatalf("Error building dynamic client: %s", err.Error())
+	}
+
+	ingressAPI, err := discoverIngressAPI(kubeClient.Discovery())
+	if err != nil {
+		glog.Fatalf("Error discovering the Ingress API: %s", err.Error())
+	}
+	glog.Infof("Using Ingress API %s", ingressAPI)
+
+	annotations, err := parseAnnotations(defaultIngressAnnotations)
+	if err != nil {
+		glog.Fatalf("Error parsing INGRESS_ANNOTATIONS: %s", err.Error())
+	}
+
+	httpRouteAPI, err := discoverHTTPRouteAPI(kubeClient.Discovery())
+	if err != nil {
+		glog.Fatalf("Error discovering the Gateway API: %s", err.Error())
+	}
+
+	certificateAPI, err := discoverCertificateAPI(kubeClient.Discovery())
+	if err != nil {
+		glog.Fatalf("Error discovering cert-manager: %s", err.Error())
+	}
+
+	port, err := strconv.ParseInt(gatewayPort, 10, 32)
+	if err != nil {
+		glog.Fatalf("Error parsing GATEWAY_PORT: %s", err.Error())
+	}
+
+	mode, err := cloudevents.ParseMode(contentMode)
+	if err != nil {
+		glog.Fatalf("Error parsing CLOUDEVENTS_MODE: %s", err.Error())
+	}
+
+	config := ControllerConfig{
+		IngressAPI:         ingressAPI,
+		IngressClass:       defaultIngressClass,
+		IngressAnnotations: annotations,
+		HTTPRouteAPI:       httpRouteAPI,
+		HTTPRouteParent:    parseParentReference(defaultHTTPRouteParent),
+		CertificateAPI:     certificateAPI,
+		CertificateIssuer:  parseIssuerReference(defaultTLSIssuer),
+		SharedHost:         sharedHost,
+		OperatorNamespace:  operatorNamespace,
+		ClusterDomain:      clusterDomain,
+		ReceiverImage:      receiverImage,
+		GatewayService:     gatewayService,
+		GatewayPort:        int32(port),
+		ContentMode:        mode,
+		GitHubAPIURL:       githubAPIURL,
+	}
+
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
 	epInformerFactory := informers.NewSharedInformerFactory(epclientset, time.Second*30)
+	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, time.Second*30)
 
-	controller := NewController(kubeClient, epclientset, kubeInformerFactory, epInformerFactory)
+	dispatcher := gateway.NewDispatcher(30 * time.Second)
+	eventGateway := gateway.New(dispatcher)
+	sources := source.NewManager(dispatcher)
+	go func() {
+		glog.Infof("Event gateway listening on %s", gatewayAddress)
+		if err := http.ListenAndServe(gatewayAddress, eventGateway); err != nil {
+			glog.Fatalf("Error running event gateway: %s", err.Error())
+		}
+	}()
+
+	go func() {
+		glog.Infof("Serving metrics on %s", metricsAddress)
+		if err := http.ListenAndServe(metricsAddress, promhttp.Handler()); err != nil {
+			glog.Fatalf("Error serving metrics: %s", err.Error())
+		}
+	}()
+
+	controller := NewController(kubeClient, epclientset, dynamicClient, kubeInformerFactory, epInformerFactory, dynamicInformerFactory, config, eventGateway, sources)
 
 	go kubeInformerFactory.Start(stop)
 	go epInformerFactory.Start(stop)
+	go dynamicInformerFactory.Start(stop)
 
 	if err = controller.Run(2, stop); err != nil {
 		glog.Fatalf("Error running controller: %s", err.Error())
@@ -68,3 +182,51 @@ func getEnvVarOrExit(varName string) string {
 
 	return value
 }
+
+func getEnvVarOrDefault(varName, defaultValue string) string {
+	value, ok := os.LookupEnv(varName)
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
+
+// parseAnnotations parses a comma separated list of key=value pairs
+func parseAnnotations(value string) (map[string]string, error) {
+	annotations := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid annotation %q, expected key=value", pair)
+		}
+		annotations[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return annotations, nil
+}
+
+// parseParentReference parses a gateway reference in the namespace/name form.
+// The namespace is optional and defaults to the namespace of the eventprovider.
+func parseParentReference(value string) v1alpha1.ParentReference {
+	if i := strings.Index(value, "/"); i >= 0 {
+		return v1alpha1.ParentReference{Namespace: value[:i], Name: value[i+1:]}
+	}
+
+	return v1alpha1.ParentReference{Name: value}
+}
+
+// parseIssuerReference parses a cert-manager issuer reference in the kind/name form.
+// The kind is optional and defaults to Issuer.
+func parseIssuerReference(value string) v1alpha1.IssuerReference {
+	if i := strings.Index(value, "/"); i >= 0 {
+		return v1alpha1.IssuerReference{Kind: value[:i], Name: value[i+1:]}
+	}
+
+	return v1alpha1.IssuerReference{Name: value}
+}
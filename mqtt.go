@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/mqtt"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+// providerMQTT is the provider name of eventproviders subscribing to MQTT topics
+const providerMQTT = "mqtt"
+
+// syncMQTT runs the MQTT subscriptions of the eventprovider. A session belongs to a single client ID,
+// so only the replica of the operator holding the <name>-mqtt lease subscribes, the others stand by.
+func (c *Controller) syncMQTT(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.MQTT
+	if spec == nil {
+		return fmt.Errorf("the mqtt provider requires an mqtt spec")
+	}
+	if len(spec.Brokers) == 0 || len(spec.Topics) == 0 {
+		return fmt.Errorf("MQTT brokers and topics are required")
+	}
+
+	qos := int32(1)
+	if spec.QoS != nil {
+		qos = *spec.QoS
+	}
+	if qos < 0 || qos > 2 {
+		return fmt.Errorf("invalid QoS %d, expected 0, 1 or 2", qos)
+	}
+
+	src := &mqtt.Source{
+		Brokers:     spec.Brokers,
+		ClientID:    spec.ClientID,
+		Topics:      spec.Topics,
+		QoS:         byte(qos),
+		Retry:       retryPolicy(spec.Retry),
+		EventSource: eventProviderURI(ep),
+	}
+	if src.ClientID == "" {
+		src.ClientID = fmt.Sprintf("%s-%s", ep.Namespace, ep.Name)
+	}
+
+	var credentialsVersion string
+	if spec.SecretName != "" {
+		var err error
+		if src.Username, src.Password, credentialsVersion, err = c.userCredentials(ep, spec.SecretName); err != nil {
+			return err
+		}
+	}
+
+	tlsConfig, tlsVersion, err := c.clientTLS(ep, spec.TLS)
+	if err != nil {
+		return err
+	}
+	src.TLS = tlsConfig
+
+	identity, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("cannot get the identity of the operator replica: %v", err)
+	}
+
+	return c.syncSource(ep, &source.Elected{
+		Source:    src,
+		Client:    c.kubeclientset,
+		Namespace: ep.Namespace,
+		Name:      fmt.Sprintf("%s-mqtt", ep.Name),
+		Identity:  identity,
+	}, spec, credentialsVersion, tlsVersion)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	natssource "github.com/radu-matei/events-operator/pkg/nats"
+)
+
+// providerNATS is the provider name of eventproviders subscribing to NATS subjects
+const providerNATS = "nats"
+
+// syncNATS runs the NATS subscriptions of the eventprovider. Every replica of the operator
+// subscribes, in a queue group or as a pull consumer, so that messages are shared between them.
+func (c *Controller) syncNATS(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.NATS
+	if spec == nil {
+		return fmt.Errorf("the nats provider requires a nats spec")
+	}
+	if spec.URL == "" || len(spec.Subjects) == 0 {
+		return fmt.Errorf("a NATS URL and subjects are required")
+	}
+
+	defaultName := fmt.Sprintf("%s-%s", ep.Namespace, ep.Name)
+	src := &natssource.Source{
+		URL:         spec.URL,
+		Subjects:    spec.Subjects,
+		QueueGroup:  spec.QueueGroup,
+		Retry:       retryPolicy(spec.Retry),
+		EventSource: eventProviderURI(ep),
+	}
+	if src.QueueGroup == "" {
+		src.QueueGroup = defaultName
+	}
+
+	if js := spec.JetStream; js != nil {
+		if js.Stream == "" {
+			return fmt.Errorf("a JetStream stream is required")
+		}
+
+		src.JetStream = &natssource.JetStream{
+			Stream:     js.Stream,
+			Consumer:   js.Consumer,
+			MaxDeliver: int(js.MaxDeliver),
+		}
+		if src.JetStream.Consumer == "" {
+			src.JetStream.Consumer = defaultName
+		}
+		if js.AckWait != nil {
+			src.JetStream.AckWait = js.AckWait.Duration
+		}
+		switch js.DeliverPolicy {
+		case "", "All":
+			src.JetStream.DeliverPolicy = jetstream.DeliverAllPolicy
+		case "Last":
+			src.JetStream.DeliverPolicy = jetstream.DeliverLastPolicy
+		case "New":
+			src.JetStream.DeliverPolicy = jetstream.DeliverNewPolicy
+		default:
+			return fmt.Errorf("unknown JetStream deliver policy %q, expected All, Last or New", js.DeliverPolicy)
+		}
+	}
+
+	var credentialsVersion string
+	if spec.SecretName != "" {
+		option, version, err := c.natsCredentials(ep, spec.SecretName)
+		if err != nil {
+			return err
+		}
+		src.Options = append(src.Options, option)
+		credentialsVersion = version
+	}
+
+	tlsConfig, tlsVersion, err := c.clientTLS(ep, spec.TLS)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		src.Options = append(src.Options, nats.Secure(tlsConfig))
+	}
+
+	return c.syncSource(ep, src, spec, credentialsVersion, tlsVersion)
+}
+
+// natsCredentials returns the connection option authenticating with the token key of a secret,
+// or its username and password keys, and the resource version of the secret
+func (c *Controller) natsCredentials(ep *v1alpha1.EventProvider, secretName string) (nats.Option, string, error) {
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(secretName)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot get credentials secret %s: %v", secretName, err)
+	}
+
+	if token, ok := secret.Data["token"]; ok {
+		return nats.Token(string(token)), secret.ResourceVersion, nil
+	}
+
+	username, err := secretKey(secret, "username")
+	if err != nil {
+		return nil, "", err
+	}
+	password, err := secretKey(secret, "password")
+	if err != nil {
+		return nil, "", err
+	}
+
+	return nats.UserInfo(string(username), string(password)), secret.ResourceVersion, nil
+}
@@ -0,0 +1,345 @@
+// Package amqp implements the source of the amqp provider: it consumes an AMQP 0-9-1 queue,
+// such as a RabbitMQ queue bound to an exchange, and emits the messages as CloudEvents.
+package amqp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+const (
+	// MessageEventType is the CloudEvents type of AMQP messages
+	MessageEventType = "dev.events-operator.amqp.message"
+
+	// heartbeat is how often the connection is checked
+	heartbeat = 10 * time.Second
+
+	// deliveryCountHeader counts the deliveries of messages the source requeued. Quorum queues count
+	// them in x-delivery-count, but classic queues do not.
+	deliveryCountHeader = "x-events-operator-delivery-count"
+	// routingKeyHeader is the routing key a message was published with, before the source requeued it
+	// through the default exchange, whose routing key is the name of the queue
+	routingKeyHeader = "x-events-operator-routing-key"
+)
+
+// Source consumes a queue
+type Source struct {
+	// URL is the amqp or amqps URL of the broker, with the credentials and virtual host
+	URL string
+	// TLS is the TLS configuration of amqps URLs, and their client certificate
+	TLS *tls.Config
+	// Queue is the name of the consumed queue
+	Queue string
+	// Declare declares the queue, durable, when it does not exist. Otherwise the queue must exist.
+	Declare bool
+	// Exchange is the exchange the queue is bound to, with RoutingKeys, when not empty
+	Exchange string
+	// RoutingKeys are the routing keys of the bindings of the queue to Exchange
+	RoutingKeys []string
+	// DeadLetterExchange is the dead-letter exchange of the queue: when it is not empty, messages
+	// that could not be delivered are dead-lettered. They are rejected without being requeued when
+	// the queue was declared with it, and published to it explicitly otherwise, as the queue may not
+	// have been declared with it.
+	DeadLetterExchange string
+	// MaxDeliveryCount is how many times a message is delivered before it is dropped, when there is
+	// no dead-letter exchange
+	MaxDeliveryCount int
+	// Prefetch is how many unacknowledged messages the broker sends to the consumer
+	Prefetch int
+	// Retry is how the delivery of messages is retried, before they are requeued or dead-lettered
+	Retry source.Retry
+	// EventSource is the CloudEvents source of events, the queue is appended to it as a fragment
+	EventSource string
+}
+
+// Run implements source.Source. Messages are acked once they were delivered. When the connection
+// or the channel is closed, Run returns an error and the source connects again after a backoff:
+// the broker requeues the messages that were not acked in the meantime.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	conn, err := amqp.DialConfig(s.URL, amqp.Config{
+		TLSClientConfig: s.TLS,
+		Heartbeat:       heartbeat,
+		Properties:      amqp.Table{"connection_name": "events-operator"},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot connect to the AMQP broker: %v", err)
+	}
+	defer conn.Close()
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("cannot open AMQP channel: %v", err)
+	}
+	defer ch.Close()
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	if err := ch.Qos(s.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("cannot set the prefetch count: %v", err)
+	}
+	// requeued and dead-lettered messages are published again, and acked once the broker confirmed them
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("cannot enable publisher confirms: %v", err)
+	}
+	if err := s.setupQueue(ch); err != nil {
+		return err
+	}
+
+	deliveries, err := ch.ConsumeWithContext(ctx, s.Queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("cannot consume queue %s: %v", s.Queue, err)
+	}
+	sink.Ready()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-connClosed:
+			return fmt.Errorf("lost the connection to the AMQP broker: %v", err)
+		case err := <-chClosed:
+			return fmt.Errorf("AMQP channel closed: %v", err)
+		case d, ok := <-deliveries:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("the consumer of queue %s was cancelled", s.Queue)
+			}
+			if err := s.handle(ctx, sink, ch, d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// setupQueue declares the queue, or checks it exists, and binds it to the exchange
+func (s *Source) setupQueue(ch *amqp.Channel) error {
+	if s.Declare {
+		var args amqp.Table
+		if s.DeadLetterExchange != "" {
+			args = amqp.Table{"x-dead-letter-exchange": s.DeadLetterExchange}
+		}
+		if _, err := ch.QueueDeclare(s.Queue, true, false, false, false, args); err != nil {
+			return fmt.Errorf("cannot declare queue %s: %v", s.Queue, err)
+		}
+	} else if _, err := ch.QueueDeclarePassive(s.Queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("cannot find queue %s: %v", s.Queue, err)
+	}
+
+	if s.Exchange == "" {
+		return nil
+	}
+	keys := s.RoutingKeys
+	if len(keys) == 0 {
+		keys = []string{""}
+	}
+	for _, key := range keys {
+		if err := ch.QueueBind(s.Queue, key, s.Exchange, false, nil); err != nil {
+			return fmt.Errorf("cannot bind queue %s to exchange %s with routing key %q: %v", s.Queue, s.Exchange, key, err)
+		}
+	}
+
+	return nil
+}
+
+// handle delivers a message and acks it, or dead-letters it when it could not be delivered and the
+// queue has a dead-letter exchange. Otherwise it is requeued, until it was delivered MaxDeliveryCount
+// times and is dropped.
+func (s *Source) handle(ctx context.Context, sink source.Sink, ch *amqp.Channel, d amqp.Delivery) error {
+	e, err := s.event(d)
+	if err == nil {
+		err = s.Retry.Deliver(ctx, sink, e)
+	}
+	if ctx.Err() != nil {
+		// the broker requeues unacked messages once the channel is closed
+		return nil
+	}
+
+	if err == nil {
+		if err := d.Ack(false); err != nil {
+			return fmt.Errorf("cannot ack message %s: %v", e.ID, err)
+		}
+		return nil
+	}
+
+	switch count := deliveryCount(d) + 1; {
+	case s.DeadLetterExchange != "" && s.Declare:
+		glog.Errorf("dead-lettering message %s of queue %s: %v", e.ID, s.Queue, err)
+		if err := d.Nack(false, false); err != nil {
+			return fmt.Errorf("cannot reject message %s: %v", e.ID, err)
+		}
+		return nil
+	case s.DeadLetterExchange != "":
+		glog.Errorf("dead-lettering message %s of queue %s to exchange %s: %v", e.ID, s.Queue, s.DeadLetterExchange, err)
+		return s.republish(ctx, ch, d, s.DeadLetterExchange, routingKey(d), nil)
+	case count >= s.MaxDeliveryCount:
+		glog.Errorf("dropping message %s of queue %s after %d deliveries: %v", e.ID, s.Queue, count, err)
+		if err := d.Ack(false); err != nil {
+			return fmt.Errorf("cannot ack message %s: %v", e.ID, err)
+		}
+		return nil
+	default:
+		// published again to the queue through the default exchange, to count its deliveries
+		glog.Errorf("requeueing message %s of queue %s after %d deliveries: %v", e.ID, s.Queue, count, err)
+		return s.republish(ctx, ch, d, "", s.Queue, requeueHeaders(d, count))
+	}
+}
+
+// republish publishes a message again to exchange with routingKey, adding headers to its own,
+// and acks it once the broker confirmed the publication
+func (s *Source) republish(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, exchange, routingKey string, headers amqp.Table) error {
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, publishing(d, headers))
+	if err != nil {
+		return fmt.Errorf("cannot publish message %s to exchange %q: %v", d.MessageId, exchange, err)
+	}
+	if ok, err := confirmation.WaitContext(ctx); err != nil || !ok {
+		// the message is requeued by the broker once the channel is closed
+		return fmt.Errorf("the broker did not confirm message %s published to exchange %q: %v", d.MessageId, exchange, err)
+	}
+
+	if err := d.Ack(false); err != nil {
+		return fmt.Errorf("cannot ack message %s: %v", d.MessageId, err)
+	}
+
+	return nil
+}
+
+// publishing returns a message to publish again, with the properties of the delivered one and headers added to its own
+func publishing(d amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	table := amqp.Table{}
+	for name, value := range d.Headers {
+		table[name] = value
+	}
+	for name, value := range headers {
+		table[name] = value
+	}
+
+	return amqp.Publishing{
+		Headers:         table,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		Expiration:      d.Expiration,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		UserId:          d.UserId,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
+
+// requeueHeaders returns the headers of a message requeued after count deliveries: the count, and
+// the routing key it was published with, as the routing key of the requeued message is the queue
+func requeueHeaders(d amqp.Delivery, count int) amqp.Table {
+	return amqp.Table{
+		deliveryCountHeader: int64(count),
+		routingKeyHeader:    routingKey(d),
+	}
+}
+
+// routingKey returns the routing key a message was published with, before the source requeued it
+func routingKey(d amqp.Delivery) string {
+	if key, ok := d.Headers[routingKeyHeader].(string); ok {
+		return key
+	}
+
+	return d.RoutingKey
+}
+
+// deliveryCount returns how many times a message was delivered before, counted by quorum queues in
+// x-delivery-count, by the source when it requeued it, or by the broker when it was dead-lettered
+// back to the queue, in x-death
+func deliveryCount(d amqp.Delivery) int {
+	count := 0
+	for _, header := range []string{"x-delivery-count", deliveryCountHeader} {
+		if n, ok := intHeader(d.Headers[header]); ok && n > count {
+			count = n
+		}
+	}
+
+	deaths, _ := d.Headers["x-death"].([]interface{})
+	dead := 0
+	for _, death := range deaths {
+		table, ok := death.(amqp.Table)
+		if !ok {
+			continue
+		}
+		if n, ok := intHeader(table["count"]); ok {
+			dead += n
+		}
+	}
+	if dead > count {
+		count = dead
+	}
+
+	return count
+}
+
+// intHeader returns the value of an integer header, of any of the integer types of AMQP tables
+func intHeader(value interface{}) (int, bool) {
+	switch n := value.(type) {
+	case int:
+		return n, true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint8:
+		return int(n), true
+	case uint16:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
+
+// event returns the CloudEvent of a message. Its subject is the routing key it was published with, its data the body, of
+// the content type of the message, and the headers are extensions. The message ID is the event ID.
+func (s *Source) event(d amqp.Delivery) (cloudevents.Event, error) {
+	id := d.MessageId
+	if id == "" {
+		var err error
+		if id, err = cloudevents.NewID(); err != nil {
+			return cloudevents.Event{}, fmt.Errorf("cannot generate event id: %v", err)
+		}
+	}
+
+	e := cloudevents.New(id, s.EventSource+"#"+s.Queue, MessageEventType)
+	e.Subject = routingKey(d)
+	e.Time = d.Timestamp
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.SetData(d.ContentType, d.Body)
+
+	headers := map[string]string{}
+	for name, value := range d.Headers {
+		if strings.HasPrefix(name, "x-") {
+			// broker headers, such as x-death
+			continue
+		}
+		headers[name] = fmt.Sprint(value)
+	}
+	e.SetExtensions(headers)
+
+	return e, nil
+}
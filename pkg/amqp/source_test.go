@@ -0,0 +1,85 @@
+package amqp
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestDeliveryCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		count   int
+	}{
+		{name: "first delivery", count: 0},
+		{name: "quorum queue", headers: amqp.Table{"x-delivery-count": int64(3)}, count: 3},
+		{name: "requeued by the source", headers: amqp.Table{deliveryCountHeader: int32(2)}, count: 2},
+		{name: "requeued by a quorum queue and the source", headers: amqp.Table{"x-delivery-count": int64(1), deliveryCountHeader: int64(4)}, count: 4},
+		{
+			name: "dead-lettered back to the queue",
+			headers: amqp.Table{"x-death": []interface{}{
+				amqp.Table{"count": int64(2), "queue": "orders", "reason": "rejected"},
+				amqp.Table{"count": int64(2), "queue": "orders-retry", "reason": "expired"},
+			}},
+			count: 4,
+		},
+		{name: "invalid header", headers: amqp.Table{"x-delivery-count": "3"}, count: 0},
+	}
+
+	for _, tt := range tests {
+		if count := deliveryCount(amqp.Delivery{Headers: tt.headers}); count != tt.count {
+			t.Errorf("%s: got %d deliveries, want %d", tt.name, count, tt.count)
+		}
+	}
+}
+
+func TestEvent(t *testing.T) {
+	s := &Source{Queue: "orders", EventSource: "/apis/eventprovider.k8s.io/v1alpha1/namespaces/default/eventproviders/orders"}
+	e, err := s.event(amqp.Delivery{
+		MessageId:   "42",
+		RoutingKey:  "orders.created",
+		ContentType: "application/json",
+		Body:        []byte(`{"id":1}`),
+		Headers:     amqp.Table{"Tenant": "acme", deliveryCountHeader: int64(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.ID != "42" || e.Subject != "orders.created" || e.Source != s.EventSource+"#orders" || e.Type != MessageEventType {
+		t.Errorf("got event %+v", e)
+	}
+	if string(e.Data) != `{"id":1}` || e.DataContentType != "application/json" {
+		t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+	}
+	if len(e.Extensions) != 1 || e.Extensions["tenant"] != "acme" {
+		t.Errorf("got extensions %v", e.Extensions)
+	}
+}
+
+func TestRequeueKeepsRoutingKey(t *testing.T) {
+	s := &Source{Queue: "orders", EventSource: "/amqp"}
+	d := amqp.Delivery{MessageId: "42", RoutingKey: "orders.created", Headers: amqp.Table{"Tenant": "acme"}}
+
+	// requeued twice through the default exchange, whose routing key is the queue
+	for count := 1; count <= 2; count++ {
+		p := publishing(d, requeueHeaders(d, count))
+		d = amqp.Delivery{MessageId: p.MessageId, RoutingKey: s.Queue, Headers: p.Headers}
+		if n := deliveryCount(d); n != count {
+			t.Errorf("requeue %d: got %d deliveries", count, n)
+		}
+	}
+
+	// the routing key is the subject, and where the message is dead-lettered to
+	if key := routingKey(d); key != "orders.created" {
+		t.Errorf("got routing key %s", key)
+	}
+	e, err := s.event(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Subject != "orders.created" || len(e.Extensions) != 1 || e.Extensions["tenant"] != "acme" {
+		t.Errorf("got event %+v", e)
+	}
+}
@@ -1,11 +1,11 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // EventProvider is a specification for an EventProvider resource
@@ -13,7 +13,8 @@ type EventProvider struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec EventProviderSpec `json:"spec"`
+	Spec   EventProviderSpec   `json:"spec"`
+	Status EventProviderStatus `json:"status,omitempty"`
 }
 
 // EventProviderSpec is the spec for an EventProvider resource
@@ -23,8 +24,201 @@ type EventProviderSpec struct {
 	StorageAccount  string `json:"storageAccount"`
 	ResourceGroup   string `json:"resourceGroup"`
 	AzureSecretName string `json:"azureSecretName"`
-	Host            string `json:"host"`
+	Host            string `json:"host,omitempty"`
 	HostImage       string `json:"hostImage"`
+
+	Handler  HandlerSpec  `json:"handler,omitempty"`
+	Ingress  IngressSpec  `json:"ingress,omitempty"`
+	Exposure ExposureSpec `json:"exposure,omitempty"`
+	TLS      TLSSpec      `json:"tls,omitempty"`
+
+	// Sink is where the operator event gateway dispatches events to. When set, the
+	// operator does not deploy hostImage: events are received by the gateway, and
+	// the handler does not need to be exposed outside of the cluster.
+	Sink *SinkSpec `json:"sink,omitempty"`
+
+	// Webhook configures eventproviders of the webhook provider
+	Webhook *WebhookSpec `json:"webhook,omitempty"`
+	// GitHub configures eventproviders of the github provider
+	GitHub *GitHubSpec `json:"github,omitempty"`
+	// GitLab configures eventproviders of the gitlab provider
+	GitLab *GitProjectSpec `json:"gitlab,omitempty"`
+	// Gitea configures eventproviders of the gitea provider
+	Gitea *GitProjectSpec `json:"gitea,omitempty"`
+	// SNS configures eventproviders of the sns provider
+	SNS *SNSSpec `json:"sns,omitempty"`
+	// PubSub configures eventproviders of the pubsub provider
+	PubSub *PubSubSpec `json:"pubsub,omitempty"`
+	// Schedule configures eventproviders of the schedule provider
+	Schedule *ScheduleSpec `json:"schedule,omitempty"`
+	// Kubernetes configures eventproviders of the kubernetes provider
+	Kubernetes *KubernetesSpec `json:"kubernetes,omitempty"`
+	// Kafka configures eventproviders of the kafka provider
+	Kafka *KafkaSpec `json:"kafka,omitempty"`
+	// NATS configures eventproviders of the nats provider
+	NATS *NATSSpec `json:"nats,omitempty"`
+	// MQTT configures eventproviders of the mqtt provider
+	MQTT *MQTTSpec `json:"mqtt,omitempty"`
+	// RedisStreams configures eventproviders of the redis-streams provider
+	RedisStreams *RedisStreamsSpec `json:"redisStreams,omitempty"`
+	// AMQP configures eventproviders of the amqp provider
+	AMQP *AMQPSpec `json:"amqp,omitempty"`
+	// StorageQueue configures eventproviders of the storagequeue provider
+	StorageQueue *StorageQueueSpec `json:"storageQueue,omitempty"`
+	// ServiceBus configures eventproviders of the servicebus provider
+	ServiceBus *ServiceBusSpec `json:"serviceBus,omitempty"`
+	// EventHubs configures eventproviders of the eventhubs provider
+	EventHubs *EventHubsSpec `json:"eventHubs,omitempty"`
+	// S3 configures eventproviders of the s3 provider
+	S3 *S3Spec `json:"s3,omitempty"`
+}
+
+// EventProviderStatus is the status for an EventProvider resource
+type EventProviderStatus struct {
+	// EndpointURL is the public URL the provider delivers events to
+	EndpointURL string `json:"endpointURL,omitempty"`
+	// Path is the path of the handler on the shared host in SharedIngress mode,
+	// and the path events are delivered to when the eventprovider has a sink
+	Path string `json:"path,omitempty"`
+	// SinkURL is the resolved URL of the sink
+	SinkURL string `json:"sinkURL,omitempty"`
+	// HookID is the ID of the webhook the operator registered with the provider
+	HookID string `json:"hookID,omitempty"`
+	// HookSecretVersion is the resource version of the secret the webhook was last registered with
+	HookSecretVersion string `json:"hookSecretVersion,omitempty"`
+	// HookBucket is the bucket notifying the webhook, for eventproviders of the s3 provider
+	HookBucket string `json:"hookBucket,omitempty"`
+	// Conditions report the progress of the operator in setting up the eventprovider
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionCertificateReady is true once the certificate of the handler was issued
+	ConditionCertificateReady = "CertificateReady"
+	// ConditionSourceReady is true while the source run by the operator for the eventprovider produces events
+	ConditionSourceReady = "SourceReady"
+)
+
+// ServiceType is the kind of Service created in front of the handler
+type ServiceType string
+
+const (
+	// ServiceTypeClusterIP exposes the handler through a regular ClusterIP service
+	ServiceTypeClusterIP ServiceType = "ClusterIP"
+	// ServiceTypeHeadless exposes the handler through a headless service (clusterIP: None)
+	ServiceTypeHeadless ServiceType = "Headless"
+)
+
+// HandlerSpec describes how the handler container listens and how it is exposed.
+// All fields are optional and default to the values the operator used before
+// they were configurable: port 80, port name "http", TCP and a ClusterIP service.
+type HandlerSpec struct {
+	// Port is the port the handler container listens on
+	Port int32 `json:"port,omitempty"`
+	// PortName is the name of the container port. When set, the service
+	// targets the container port by name instead of by number.
+	PortName string `json:"portName,omitempty"`
+	// Protocol is the protocol of the container and service ports
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+	// ServiceType is either ClusterIP or Headless
+	ServiceType ServiceType `json:"serviceType,omitempty"`
+	// Receiver runs the operator-provided receiver next to the handler. The receiver
+	// answers the Event Grid validation handshakes, and only forwards actual events.
+	Receiver bool `json:"receiver,omitempty"`
+}
+
+// IngressSpec customizes the Ingress created for the handler.
+// Values set here take precedence over the operator-wide defaults.
+type IngressSpec struct {
+	// ClassName is the IngressClass used for the handler's Ingress
+	ClassName string `json:"className,omitempty"`
+	// Annotations are added to the handler's Ingress, on top of the operator-wide annotations
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ExposureMode is how the handler is exposed outside of the cluster
+type ExposureMode string
+
+const (
+	// ExposureModeIngress exposes the handler through an Ingress
+	ExposureModeIngress ExposureMode = "Ingress"
+	// ExposureModeSharedIngress exposes the handler on a path of the operator-wide shared host
+	ExposureModeSharedIngress ExposureMode = "SharedIngress"
+	// ExposureModeHTTPRoute exposes the handler through a Gateway API HTTPRoute
+	ExposureModeHTTPRoute ExposureMode = "HTTPRoute"
+	// ExposureModeNone does not expose the handler, the public URL is managed outside of the operator
+	ExposureModeNone ExposureMode = "None"
+)
+
+// ExposureSpec describes how the handler is exposed outside of the cluster
+type ExposureSpec struct {
+	// Mode is one of Ingress, SharedIngress, HTTPRoute or None. It defaults to Ingress,
+	// or to SharedIngress when the eventprovider has no host and the operator has a shared host.
+	Mode ExposureMode `json:"mode,omitempty"`
+	// HTTPRoute configures the route created in HTTPRoute mode
+	HTTPRoute HTTPRouteSpec `json:"httpRoute,omitempty"`
+	// URL is the public URL of the handler in None mode. Defaults to https://<host>.
+	URL string `json:"url,omitempty"`
+}
+
+// HTTPRouteSpec configures the HTTPRoute created for the handler
+type HTTPRouteSpec struct {
+	// ParentRef is the Gateway the route attaches to. Defaults to the operator-wide parent gateway.
+	ParentRef ParentReference `json:"parentRef,omitempty"`
+	// Hostnames are the hostnames of the route. Defaults to the eventprovider host.
+	Hostnames []string `json:"hostnames,omitempty"`
+}
+
+// ParentReference identifies a Gateway API Gateway
+type ParentReference struct {
+	// Name of the Gateway
+	Name string `json:"name,omitempty"`
+	// Namespace of the Gateway, defaults to the namespace of the eventprovider
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName is the name of the Gateway listener the route attaches to
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// TLSSpec configures the certificate of the handler
+type TLSSpec struct {
+	// IssuerRef is the cert-manager Issuer or ClusterIssuer of the handler certificate.
+	// Defaults to the operator-wide issuer. When no issuer is configured, the operator
+	// does not manage the certificate.
+	IssuerRef IssuerReference `json:"issuerRef,omitempty"`
+}
+
+// IssuerReference identifies a cert-manager Issuer or ClusterIssuer
+type IssuerReference struct {
+	// Name of the issuer
+	Name string `json:"name,omitempty"`
+	// Kind is either Issuer or ClusterIssuer, and defaults to Issuer
+	Kind string `json:"kind,omitempty"`
+}
+
+// SinkSpec is where the event gateway dispatches the events of an eventprovider:
+// a Service, any resource with an address (status.address.url), or a URI
+type SinkSpec struct {
+	// Ref references the sink resource
+	Ref *SinkReference `json:"ref,omitempty"`
+	// URI is the URL of the sink. When Ref is set, it is resolved relative to the URL of Ref.
+	URI string `json:"uri,omitempty"`
+	// ContentMode is the CloudEvents HTTP content mode events are dispatched in,
+	// binary or structured. Defaults to the operator content mode.
+	ContentMode string `json:"contentMode,omitempty"`
+}
+
+// SinkReference references the resource events are dispatched to
+type SinkReference struct {
+	// APIVersion of the sink, defaults to v1
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Kind of the sink, defaults to Service
+	Kind string `json:"kind,omitempty"`
+	// Name of the sink
+	Name string `json:"name"`
+	// Namespace of the sink, defaults to the namespace of the eventprovider
+	Namespace string `json:"namespace,omitempty"`
+	// Port of the service, when the sink is a Service. Defaults to 80.
+	Port int32 `json:"port,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -36,3 +230,399 @@ type EventProviderList struct {
 
 	Items []EventProvider `json:"items"`
 }
+
+// WebhookSpec configures a generic webhook: the operator exposes an endpoint, and
+// the event gateway dispatches the payloads of authenticated callers to the sink,
+// as CloudEvents of type eventType
+type WebhookSpec struct {
+	// Auth is how callers of the webhook are authenticated
+	Auth WebhookAuth `json:"auth"`
+	// Source is the CloudEvents source of the events, defaults to the path of the eventprovider in the API
+	Source string `json:"source,omitempty"`
+}
+
+// WebhookAuthType is how callers of a webhook are authenticated
+type WebhookAuthType string
+
+const (
+	// WebhookAuthBearer expects the token key of the secret in an Authorization: Bearer header
+	WebhookAuthBearer WebhookAuthType = "bearer"
+	// WebhookAuthHMAC expects a signature of the body, keyed with the secret key of the secret
+	WebhookAuthHMAC WebhookAuthType = "hmac"
+	// WebhookAuthBasic expects the username and password keys of the secret as basic auth credentials
+	WebhookAuthBasic WebhookAuthType = "basic"
+)
+
+// WebhookAuth configures the authentication of webhook callers
+type WebhookAuth struct {
+	// Type is bearer, hmac or basic
+	Type WebhookAuthType `json:"type"`
+	// SecretName is the name of the secret holding the credentials, in the namespace of the eventprovider
+	SecretName string `json:"secretName"`
+	// HMAC configures the signature check of the hmac type
+	HMAC HMACSpec `json:"hmac,omitempty"`
+}
+
+// HMACSpec configures how webhook payloads are signed
+type HMACSpec struct {
+	// Header carrying the signature, defaults to X-Signature
+	Header string `json:"header,omitempty"`
+	// Algorithm of the HMAC: sha1, sha256 or sha512. Defaults to sha256.
+	Algorithm string `json:"algorithm,omitempty"`
+	// Prefix of the signature in the header, such as sha256=
+	Prefix string `json:"prefix,omitempty"`
+	// Encoding of the signature, hex or base64. Defaults to hex.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// GitHubSpec configures the webhook the operator registers on a GitHub repository or organization
+type GitHubSpec struct {
+	// Repository to register the webhook on, in the owner/name form
+	Repository string `json:"repository,omitempty"`
+	// Organization to register the webhook on, when no repository is set
+	Organization string `json:"organization,omitempty"`
+	// Events the webhook is subscribed to, defaults to push. "*" subscribes to all events.
+	Events []string `json:"events,omitempty"`
+	// SecretName is the name of the secret holding the API token in its token key,
+	// and the secret signing deliveries in its secret key
+	SecretName string `json:"secretName"`
+	// APIURL is the base URL of the GitHub REST API, defaults to the operator one.
+	// For GitHub Enterprise Server, it is https://<host>/api/v3.
+	APIURL string `json:"apiURL,omitempty"`
+}
+
+// GitProjectSpec configures the webhook the operator registers on a GitLab project or a Gitea repository
+type GitProjectSpec struct {
+	// Project to register the webhook on: its ID or group/name path for GitLab, owner/name for Gitea
+	Project string `json:"project"`
+	// Events the webhook is subscribed to, among push, merge_request and tag. Defaults to push.
+	Events []string `json:"events,omitempty"`
+	// SecretName is the name of the secret holding the API token in its token key,
+	// and the secret authenticating deliveries in its secret key
+	SecretName string `json:"secretName"`
+	// APIURL is the base URL of the REST API, https://<host>/api/v4 for GitLab and
+	// https://<host>/api/v1 for Gitea. Defaults to https://gitlab.com/api/v4 for GitLab.
+	APIURL string `json:"apiURL,omitempty"`
+}
+
+// SNSSpec configures the subscription of the eventprovider endpoint to an Amazon SNS topic
+type SNSSpec struct {
+	// TopicARN is the ARN of the topic, arn:aws:sns:<region>:<account>:<name>
+	TopicARN string `json:"topicARN"`
+	// SecretName is the name of the secret holding the AWS credentials, in its
+	// accessKeyID, secretAccessKey and optional sessionToken keys
+	SecretName string `json:"secretName"`
+	// Endpoint overrides the SNS endpoint of the region of the topic, for SNS emulators.
+	// Signing certificates and subscription confirmations are then trusted from this endpoint only.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// PubSubSpec configures the Google Cloud Pub/Sub push subscription pushing the messages of a topic to the eventprovider
+type PubSubSpec struct {
+	// Project of the topic and the subscription
+	Project string `json:"project"`
+	// Topic is the name of the topic
+	Topic string `json:"topic"`
+	// Subscription is the name of the subscription, defaults to <namespace>-<name> of the eventprovider
+	Subscription string `json:"subscription,omitempty"`
+	// SecretName is the name of the secret holding the JSON key of the service account
+	// managing the subscription, in its key.json key. Optional with InsecureEmulator.
+	SecretName string `json:"secretName,omitempty"`
+	// ServiceAccount is the email of the service account pushes are authenticated as,
+	// defaults to the service account of the key
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// Audience of the OIDC token of pushes, defaults to the path of the eventprovider in the API
+	Audience string `json:"audience,omitempty"`
+	// Endpoint overrides the Pub/Sub endpoint, for the emulator
+	Endpoint string `json:"endpoint,omitempty"`
+	// InsecureEmulator declares Endpoint is the emulator, which does not authenticate pushes: their
+	// OIDC token is not checked, and only the secret path of the endpoint authenticates them
+	InsecureEmulator bool `json:"insecureEmulator,omitempty"`
+}
+
+// ScheduleSpec configures the ticks emitted by eventproviders of the schedule provider
+type ScheduleSpec struct {
+	// Crontab are the cron expressions of the ticks, in the standard five field format or
+	// descriptors such as @hourly. Ticks of several expressions at the same time are emitted once.
+	Crontab []string `json:"crontab"`
+	// TimeZone is the IANA time zone the expressions are evaluated in, defaults to UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// Data is the payload of tick events
+	Data string `json:"data,omitempty"`
+	// ContentType is the media type of Data, defaults to application/json
+	ContentType string `json:"contentType,omitempty"`
+	// MissedTicks is what happens to the ticks missed while the operator was down:
+	// Skip (the default), FireOnce or FireAll
+	MissedTicks string `json:"missedTicks,omitempty"`
+	// Jitter is the maximum random delay added to every tick
+	Jitter *metav1.Duration `json:"jitter,omitempty"`
+}
+
+// KubernetesSpec configures the objects watched by eventproviders of the kubernetes provider
+type KubernetesSpec struct {
+	// APIVersion of the watched objects
+	APIVersion string `json:"apiVersion"`
+	// Kind of the watched objects
+	Kind string `json:"kind,omitempty"`
+	// Resource is the plural name of the watched resource, an alternative to Kind
+	Resource string `json:"resource,omitempty"`
+	// Namespace is the namespace watched, defaults to the namespace of the eventprovider.
+	// "*" watches all the namespaces. Ignored for cluster scoped resources.
+	Namespace string `json:"namespace,omitempty"`
+	// LabelSelector selects the watched objects by label
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// FieldSelector selects the watched objects by field
+	FieldSelector string `json:"fieldSelector,omitempty"`
+	// Events are the changes emitted: add, update and delete. Defaults to all of them.
+	Events []string `json:"events,omitempty"`
+	// Data is the data of update events: Object, the default, or Patch for the JSON patch from the previous object
+	Data string `json:"data,omitempty"`
+	// ServiceAccountName is the service account, in the namespace of the eventprovider, that must be
+	// allowed to list and watch the objects. Defaults to default.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// KafkaSpec configures the consumer group of eventproviders of the kafka provider
+type KafkaSpec struct {
+	// Brokers are the host:port addresses of the bootstrap brokers
+	Brokers []string `json:"brokers"`
+	// Topics are the consumed topics
+	Topics []string `json:"topics"`
+	// ConsumerGroup is the ID of the consumer group, defaults to <namespace>-<name> of the eventprovider
+	ConsumerGroup string `json:"consumerGroup,omitempty"`
+	// InitialOffset is where the consumer group starts when it has no committed offset:
+	// Latest, the default, or Earliest
+	InitialOffset string `json:"initialOffset,omitempty"`
+	// TLS enables TLS connections to the brokers
+	TLS *ClientTLSSpec `json:"tls,omitempty"`
+	// SASL enables SASL authentication with the brokers
+	SASL *SASLSpec `json:"sasl,omitempty"`
+	// Retry configures the delivery of records, skipped once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// NATSSpec configures the subscriptions of eventproviders of the nats provider
+type NATSSpec struct {
+	// URL is the comma separated list of the URLs of the NATS servers
+	URL string `json:"url"`
+	// Subjects are the subjects subscribed to, with wildcards
+	Subjects []string `json:"subjects"`
+	// QueueGroup is the queue group of core NATS subscriptions, defaults to <namespace>-<name>
+	// of the eventprovider, so that the replicas of the operator share the messages
+	QueueGroup string `json:"queueGroup,omitempty"`
+	// JetStream consumes a durable JetStream consumer instead of core NATS subjects
+	JetStream *JetStreamSpec `json:"jetStream,omitempty"`
+	// SecretName is the name of a secret holding either the username and password keys, or a token key
+	SecretName string `json:"secretName,omitempty"`
+	// TLS enables TLS connections to the servers
+	TLS *ClientTLSSpec `json:"tls,omitempty"`
+	// Retry configures the delivery of messages, given up on once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// JetStreamSpec configures the durable JetStream consumer of an eventprovider
+type JetStreamSpec struct {
+	// Stream is the name of the stream
+	Stream string `json:"stream"`
+	// Consumer is the name of the durable consumer, created or updated by the operator.
+	// Defaults to <namespace>-<name> of the eventprovider.
+	Consumer string `json:"consumer,omitempty"`
+	// DeliverPolicy is where a new consumer starts in the stream: All, the default, Last or New
+	DeliverPolicy string `json:"deliverPolicy,omitempty"`
+	// AckWait is how long the server waits for the ack of a message before redelivering it
+	AckWait *metav1.Duration `json:"ackWait,omitempty"`
+	// MaxDeliver is how many times a message is delivered before the server gives up on it, unlimited by default
+	MaxDeliver int32 `json:"maxDeliver,omitempty"`
+}
+
+// MQTTSpec configures the subscriptions of eventproviders of the mqtt provider
+type MQTTSpec struct {
+	// Brokers are the URLs of the brokers, with a tcp, ssl, ws or wss scheme
+	Brokers []string `json:"brokers"`
+	// Topics are the topic filters subscribed to, with + and # wildcards
+	Topics []string `json:"topics"`
+	// QoS is the quality of service of the subscriptions: 0, 1, the default, or 2
+	QoS *int32 `json:"qos,omitempty"`
+	// ClientID identifies the persistent session on the brokers, defaults to <namespace>-<name> of the eventprovider
+	ClientID string `json:"clientID,omitempty"`
+	// SecretName is the name of a secret holding the username and password keys
+	SecretName string `json:"secretName,omitempty"`
+	// TLS configures the connections to ssl and wss brokers, and the client certificate
+	TLS *ClientTLSSpec `json:"tls,omitempty"`
+	// Retry configures the delivery of messages, redelivered by the broker once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// RedisStreamsSpec configures the consumer group of eventproviders of the redis-streams provider
+type RedisStreamsSpec struct {
+	// Address is the host:port address of the Redis server
+	Address string `json:"address"`
+	// DB is the database of the stream
+	DB int32 `json:"db,omitempty"`
+	// Stream is the key of the stream
+	Stream string `json:"stream"`
+	// ConsumerGroup is the consumer group, created when it does not exist, defaults to <namespace>-<name> of the eventprovider
+	ConsumerGroup string `json:"consumerGroup,omitempty"`
+	// InitialPosition is where a new consumer group starts in the stream: Latest, the default, or Earliest
+	InitialPosition string `json:"initialPosition,omitempty"`
+	// ClaimMinIdle is how long an entry stays pending before it is reclaimed and delivered again, 1m by default
+	ClaimMinIdle *metav1.Duration `json:"claimMinIdle,omitempty"`
+	// Count is the maximum number of entries read at once, 100 by default
+	Count int32 `json:"count,omitempty"`
+	// SecretName is the name of a secret holding the password key, and optionally the username key
+	SecretName string `json:"secretName,omitempty"`
+	// TLS enables TLS connections to the server
+	TLS *ClientTLSSpec `json:"tls,omitempty"`
+	// Retry configures the delivery of entries, left pending once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+	// MaxDeliveryCount is how many times an entry is delivered before it is given up on, 5 by default:
+	// it is then moved to deadLetterStream when it is set, and acknowledged and dropped otherwise
+	MaxDeliveryCount int32 `json:"maxDeliveryCount,omitempty"`
+	// DeadLetterStream is the key of the stream the entries given up on are added to
+	DeadLetterStream string `json:"deadLetterStream,omitempty"`
+}
+
+// AMQPSpec configures the queue consumed by eventproviders of the amqp provider
+type AMQPSpec struct {
+	// SecretName is the name of the secret holding the amqp or amqps URL of the broker, with
+	// the credentials and virtual host, in its url key
+	SecretName string `json:"secretName"`
+	// Queue is the name of the consumed queue
+	Queue string `json:"queue"`
+	// Declare declares the queue, durable, when it does not exist. Otherwise the queue must exist.
+	Declare bool `json:"declare,omitempty"`
+	// Exchange is the exchange the queue is bound to
+	Exchange string `json:"exchange,omitempty"`
+	// RoutingKeys are the routing keys the queue is bound to the exchange with
+	RoutingKeys []string `json:"routingKeys,omitempty"`
+	// DeadLetterExchange is the dead-letter exchange of the queue, set when the operator declares it.
+	// Messages that could not be delivered are dead-lettered when it is set, and requeued otherwise.
+	// When the operator does not declare the queue, messages are published to the exchange explicitly.
+	DeadLetterExchange string `json:"deadLetterExchange,omitempty"`
+	// MaxDeliveryCount is how many times a message is delivered before it is dropped, when there is no
+	// dead-letter exchange, 5 by default
+	MaxDeliveryCount int32 `json:"maxDeliveryCount,omitempty"`
+	// Prefetch is how many unacknowledged messages the broker sends to each replica of the operator, 10 by default
+	Prefetch int32 `json:"prefetch,omitempty"`
+	// TLS configures the connections to amqps URLs, and the client certificate
+	TLS *ClientTLSSpec `json:"tls,omitempty"`
+	// Retry configures the delivery of messages, requeued or dead-lettered once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// StorageQueueSpec configures the Azure Storage queue polled by eventproviders of the storagequeue
+// provider, in the storage account storageAccount of the eventprovider
+type StorageQueueSpec struct {
+	// Queue is the name of the queue
+	Queue string `json:"queue"`
+	// Endpoint is the URL of the queue service, defaults to https://<storageAccount>.queue.core.windows.net.
+	// For Azurite, it is the URL of the queue service and the account, such as http://azurite:10001/devstoreaccount1.
+	Endpoint string `json:"endpoint,omitempty"`
+	// VisibilityTimeout is how long dequeued messages are invisible to other consumers, 1m by default
+	VisibilityTimeout *metav1.Duration `json:"visibilityTimeout,omitempty"`
+	// PollInterval is how long the operator waits before polling an empty queue again, 10s by default
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+	// BatchSize is the maximum number of messages dequeued at once, 32 at most and by default
+	BatchSize int32 `json:"batchSize,omitempty"`
+	// MaxDequeueCount is how many times a message is dequeued before it is moved to the poison queue, 5 by default
+	MaxDequeueCount int32 `json:"maxDequeueCount,omitempty"`
+	// PoisonQueue is the queue poison messages are moved to, defaults to <queue>-poison
+	PoisonQueue string `json:"poisonQueue,omitempty"`
+	// EventGrid decodes the messages as the base64 encoded events of an Event Grid subscription
+	// whose destination is the queue
+	EventGrid bool `json:"eventGrid,omitempty"`
+	// Retry configures the delivery of messages, left in the queue once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// ServiceBusSpec configures the Azure Service Bus queue or topic subscription eventproviders
+// of the servicebus provider receive messages from
+type ServiceBusSpec struct {
+	// Namespace is the fully qualified namespace, such as <namespace>.servicebus.windows.net.
+	// It is not required when the secret azureSecretName has a connectionString key.
+	Namespace string `json:"namespace,omitempty"`
+	// Queue is the name of the queue, when messages are not received from a topic subscription
+	Queue string `json:"queue,omitempty"`
+	// Topic is the name of the topic of the subscription
+	Topic string `json:"topic,omitempty"`
+	// Subscription is the name of the topic subscription
+	Subscription string `json:"subscription,omitempty"`
+	// Sessions receives the messages of a session-enabled queue or subscription
+	Sessions bool `json:"sessions,omitempty"`
+	// MaxDeliveryCount is how many times a message is delivered before the operator dead-letters it, 5 by default
+	MaxDeliveryCount int32 `json:"maxDeliveryCount,omitempty"`
+	// BatchSize is the maximum number of messages received at once, 10 by default
+	BatchSize int32 `json:"batchSize,omitempty"`
+	// Retry configures the delivery of messages, abandoned once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// EventHubsSpec configures the Azure event hub consumed by eventproviders of the eventhubs provider
+type EventHubsSpec struct {
+	// Namespace is the fully qualified namespace, such as <namespace>.servicebus.windows.net.
+	// It is not required when the secret azureSecretName has a connectionString key.
+	Namespace string `json:"namespace,omitempty"`
+	// EventHub is the name of the event hub
+	EventHub string `json:"eventHub"`
+	// ConsumerGroup is the consumer group, $Default by default
+	ConsumerGroup string `json:"consumerGroup,omitempty"`
+	// StartPosition is where partitions without a checkpoint are consumed from: Latest, the default,
+	// Earliest, or an RFC 3339 time events were enqueued at
+	StartPosition string `json:"startPosition,omitempty"`
+	// MaxBatchSize is the maximum number of events received at once from a partition, 100 by default
+	MaxBatchSize int32 `json:"maxBatchSize,omitempty"`
+	// CheckpointContainer is the blob container of the storage account storageAccount the owners
+	// and checkpoints of the partitions are kept in. When it is empty, they are kept in the
+	// configmap <name>-eventhubs.
+	CheckpointContainer string `json:"checkpointContainer,omitempty"`
+	// Retry configures the delivery of events, skipped once all the attempts failed
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// S3Spec configures the notifications of the S3 bucket of eventproviders of the s3 provider, on
+// a MinIO deployment delivering them to the eventprovider endpoint through a webhook target
+type S3Spec struct {
+	// Endpoint is the URL of the deployment, such as http://minio.minio:9000
+	Endpoint string `json:"endpoint"`
+	// Region is the region of the deployment, empty when it has none
+	Region string `json:"region,omitempty"`
+	// Bucket is the name of the bucket
+	Bucket string `json:"bucket"`
+	// Events are the S3 event types notified, s3:ObjectCreated:* and s3:ObjectRemoved:* by default
+	Events []string `json:"events,omitempty"`
+	// Prefix restricts notifications to the objects whose key starts with it
+	Prefix string `json:"prefix,omitempty"`
+	// Suffix restricts notifications to the objects whose key ends with it
+	Suffix string `json:"suffix,omitempty"`
+	// SecretName is the name of the secret holding the access key of a user allowed to configure the
+	// deployment and the bucket, in its accessKeyID and secretAccessKey keys, and the bearer token of
+	// the webhook target in its webhookToken key
+	SecretName string `json:"secretName"`
+}
+
+// ClientTLSSpec configures the TLS connections of the operator to a broker
+type ClientTLSSpec struct {
+	// SecretName is the name of a secret holding the CA certificates of the broker in its ca.crt key,
+	// and a client certificate in its tls.crt and tls.key keys, all of them optional
+	SecretName string `json:"secretName,omitempty"`
+	// ServerName overrides the name the certificate of the broker is verified for
+	ServerName string `json:"serverName,omitempty"`
+	// InsecureSkipVerify disables the verification of the certificate of the broker
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// SASLSpec configures SASL authentication
+type SASLSpec struct {
+	// Mechanism is the SASL mechanism: PLAIN, the default, SCRAM-SHA-256 or SCRAM-SHA-512
+	Mechanism string `json:"mechanism,omitempty"`
+	// SecretName is the name of the secret holding the username and password keys
+	SecretName string `json:"secretName"`
+}
+
+// RetrySpec configures how the delivery of an event to the sink is retried before it is given up on
+type RetrySpec struct {
+	// Attempts is the number of delivery attempts, defaults to 5
+	Attempts int32 `json:"attempts,omitempty"`
+	// Backoff is the wait after the first failed attempt, doubled after every other one. Defaults to 1s.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+}
@@ -1,4 +0,0 @@
-/*
-github.com/radu-matei/event-operator
-*/ // This package has the automatically generated clientset.
-package versioned
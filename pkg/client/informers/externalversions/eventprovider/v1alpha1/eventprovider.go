@@ -1,27 +1,29 @@
 /*
 github.com/radu-matei/event-operator
 */
-// This file was automatically generated by informer-gen
+// Code generated by informer-gen. DO NOT EDIT.
 
 package v1alpha1
 
 import (
-	eventprovider_v1alpha1 "github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	context "context"
+	time "time"
+
+	apiseventproviderv1alpha1 "github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
 	versioned "github.com/radu-matei/events-operator/pkg/client/clientset/versioned"
 	internalinterfaces "github.com/radu-matei/events-operator/pkg/client/informers/externalversions/internalinterfaces"
-	v1alpha1 "github.com/radu-matei/events-operator/pkg/client/listers/eventprovider/v1alpha1"
+	eventproviderv1alpha1 "github.com/radu-matei/events-operator/pkg/client/listers/eventprovider/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	watch "k8s.io/apimachinery/pkg/watch"
 	cache "k8s.io/client-go/tools/cache"
-	time "time"
 )
 
 // EventProviderInformer provides access to a shared informer and lister for
 // EventProviders.
 type EventProviderInformer interface {
 	Informer() cache.SharedIndexInformer
-	Lister() v1alpha1.EventProviderLister
+	Lister() eventproviderv1alpha1.EventProviderLister
 }
 
 type eventProviderInformer struct {
@@ -47,16 +49,28 @@ func NewFilteredEventProviderInformer(client versioned.Interface, namespace stri
 				if tweakListOptions != nil {
 					tweakListOptions(&options)
 				}
-				return client.EventproviderV1alpha1().EventProviders(namespace).List(options)
+				return client.EventproviderV1alpha1().EventProviders(namespace).List(context.Background(), options)
 			},
 			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
 				if tweakListOptions != nil {
 					tweakListOptions(&options)
 				}
-				return client.EventproviderV1alpha1().EventProviders(namespace).Watch(options)
+				return client.EventproviderV1alpha1().EventProviders(namespace).Watch(context.Background(), options)
+			},
+			ListWithContextFunc: func(ctx context.Context, options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.EventproviderV1alpha1().EventProviders(namespace).List(ctx, options)
+			},
+			WatchFuncWithContext: func(ctx context.Context, options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.EventproviderV1alpha1().EventProviders(namespace).Watch(ctx, options)
 			},
 		},
-		&eventprovider_v1alpha1.EventProvider{},
+		&apiseventproviderv1alpha1.EventProvider{},
 		resyncPeriod,
 		indexers,
 	)
@@ -67,9 +81,9 @@ func (f *eventProviderInformer) defaultInformer(client versioned.Interface, resy
 }
 
 func (f *eventProviderInformer) Informer() cache.SharedIndexInformer {
-	return f.factory.InformerFor(&eventprovider_v1alpha1.EventProvider{}, f.defaultInformer)
+	return f.factory.InformerFor(&apiseventproviderv1alpha1.EventProvider{}, f.defaultInformer)
 }
 
-func (f *eventProviderInformer) Lister() v1alpha1.EventProviderLister {
-	return v1alpha1.NewEventProviderLister(f.Informer().GetIndexer())
+func (f *eventProviderInformer) Lister() eventproviderv1alpha1.EventProviderLister {
+	return eventproviderv1alpha1.NewEventProviderLister(f.Informer().GetIndexer())
 }
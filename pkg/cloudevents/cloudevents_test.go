@@ -0,0 +1,159 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want Event
+	}{
+		{
+			name: "attributes",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","subject":"sub","time":"2020-01-02T03:04:05Z","dataschema":"/schema"}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", Subject: "sub", Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), DataSchema: "/schema"},
+		},
+		{
+			name: "extensions",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","str":"a","num":42,"flag":true}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", Extensions: map[string]string{"str": "a", "num": "42", "flag": "true"}},
+		},
+		{
+			name: "json object",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"application/json","data":{"a":1}}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", DataContentType: "application/json", Data: []byte(`{"a":1}`)},
+		},
+		{
+			name: "json string",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"application/json","data":"hello"}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", DataContentType: "application/json", Data: []byte(`"hello"`)},
+		},
+		{
+			name: "json string of a number",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","data":"42"}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", Data: []byte(`"42"`)},
+		},
+		{
+			name: "json string of a boolean",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"application/cloudevents+json","data":"true"}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", DataContentType: "application/cloudevents+json", Data: []byte(`"true"`)},
+		},
+		{
+			name: "json number",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","data":42}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", Data: []byte(`42`)},
+		},
+		{
+			name: "xml string",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"application/xml","data":"<a>1</a>"}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", DataContentType: "application/xml", Data: []byte(`<a>1</a>`)},
+		},
+		{
+			name: "text string of a number",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"text/plain","data":"42"}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", DataContentType: "text/plain", Data: []byte(`42`)},
+		},
+		{
+			name: "base64",
+			json: `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"application/octet-stream","data_base64":"AAEC"}`,
+			want: Event{ID: "1", Source: "/s", SpecVersion: "1.0", Type: "t", DataContentType: "application/octet-stream", Data: []byte{0, 1, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Event
+			if err := json.Unmarshal([]byte(tt.json), &e); err != nil {
+				t.Fatalf("cannot decode event: %v", err)
+			}
+			if !reflect.DeepEqual(e, tt.want) {
+				t.Errorf("got %+v (data %q), want %+v (data %q)", e, e.Data, tt.want, tt.want.Data)
+			}
+
+			// encoding and decoding again must return the same event
+			b, err := json.Marshal(e)
+			if err != nil {
+				t.Fatalf("cannot encode event: %v", err)
+			}
+			var again Event
+			if err := json.Unmarshal(b, &again); err != nil {
+				t.Fatalf("cannot decode encoded event %s: %v", b, err)
+			}
+			if !reflect.DeepEqual(again, tt.want) {
+				t.Errorf("round trip of %s: got %+v (data %q)", b, again, again.Data)
+			}
+		})
+	}
+}
+
+func TestRequest(t *testing.T) {
+	e := New("1", "/s", "t")
+	e.Subject = "a subject"
+	e.Time = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	e.SetExtension("partitionkey", "100%")
+
+	tests := []struct {
+		name        string
+		mode        Mode
+		contentType string
+		data        []byte
+	}{
+		{name: "binary json", mode: ModeBinary, contentType: "application/json", data: []byte(`"hello"`)},
+		{name: "binary xml", mode: ModeBinary, contentType: "application/xml", data: []byte(`<a>1</a>`)},
+		{name: "binary without data", mode: ModeBinary},
+		{name: "structured json", mode: ModeStructured, contentType: "application/json", data: []byte(`{"a":1}`)},
+		{name: "structured json string", mode: ModeStructured, contentType: "application/json", data: []byte(`"42"`)},
+		{name: "structured xml", mode: ModeStructured, contentType: "application/xml", data: []byte(`<a>1</a>`)},
+		{name: "structured binary", mode: ModeStructured, contentType: "application/octet-stream", data: []byte{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := e
+			want.SetData(tt.contentType, tt.data)
+
+			req, err := NewRequest(context.Background(), "http://sink", want, tt.mode)
+			if err != nil {
+				t.Fatalf("cannot create request: %v", err)
+			}
+			if !IsCloudEvent(req) {
+				t.Errorf("request is not a CloudEvent: %v", req.Header)
+			}
+			if tt.mode == ModeBinary && req.Header.Get("Ce-Subject") != "a%20subject" {
+				t.Errorf("got subject header %q", req.Header.Get("Ce-Subject"))
+			}
+
+			events, err := ReadRequest(req)
+			if err != nil {
+				t.Fatalf("cannot read request: %v", err)
+			}
+			if len(events) != 1 || !reflect.DeepEqual(events[0], want) {
+				t.Errorf("got %+v, want %+v", events, want)
+			}
+		})
+	}
+}
+
+func TestReadRequestInvalid(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://sink", strings.NewReader(""))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := ReadRequest(req); err == nil {
+		t.Errorf("a request without ce- headers is not a CloudEvent")
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "http://sink", strings.NewReader(""))
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Source", "/s")
+	req.Header.Set("Ce-Type", "t")
+	if _, err := ReadRequest(req); err == nil {
+		t.Errorf("an event without an id is invalid")
+	}
+}
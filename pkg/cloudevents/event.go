@@ -0,0 +1,162 @@
+// Package cloudevents implements the subset of CloudEvents 1.0 the operator needs:
+// the event model, the JSON event format and the HTTP protocol binding, in both
+// binary and structured content modes.
+// See https://github.com/cloudevents/spec/tree/v1.0.2/cloudevents
+package cloudevents
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SpecVersion is the version of the CloudEvents specification implemented by this package
+const SpecVersion = "1.0"
+
+// extensionNamePattern is the format of the name of extension attributes
+var extensionNamePattern = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// Event is a CloudEvent
+type Event struct {
+	// ID identifies the event, and is unique within the scope of its source
+	ID string
+	// Source identifies the context in which the event happened
+	Source string
+	// SpecVersion is the version of the CloudEvents specification of the event
+	SpecVersion string
+	// Type is the type of the event, usually a reverse-DNS name
+	Type string
+
+	// Subject is the subject of the event in the context of its source
+	Subject string
+	// Time is when the event happened
+	Time time.Time
+	// DataContentType is the media type of Data
+	DataContentType string
+	// DataSchema is the URI of the schema Data adheres to
+	DataSchema string
+
+	// Data is the payload of the event
+	Data []byte
+
+	// Extensions are the extension attributes of the event
+	Extensions map[string]string
+}
+
+// New returns a new event with the current spec version and the required attributes
+func New(id, source, eventType string) Event {
+	return Event{
+		ID:          id,
+		Source:      source,
+		SpecVersion: SpecVersion,
+		Type:        eventType,
+	}
+}
+
+// NewID returns a random event id, for events of providers that do not identify them
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// SetExtension sets an extension attribute of the event
+func (e *Event) SetExtension(name, value string) {
+	if e.Extensions == nil {
+		e.Extensions = map[string]string{}
+	}
+	e.Extensions[name] = value
+}
+
+// SetExtensions sets the attributes of a provider message as extensions of the event. Names are
+// lowercased, and attributes that are not valid extension names, or collide with an attribute of
+// the specification, are dropped.
+func (e *Event) SetExtensions(attributes map[string]string) {
+	for name, value := range attributes {
+		extension := strings.ToLower(name)
+		if _, ok := e.Extensions[extension]; ok {
+			continue
+		}
+		if !extensionNamePattern.MatchString(extension) || isContextAttribute(extension) {
+			continue
+		}
+
+		e.SetExtension(extension, value)
+	}
+}
+
+// SetData sets the data of the event. Without a content type, JSON data is application/json,
+// and anything else application/octet-stream.
+func (e *Event) SetData(contentType string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	e.Data = data
+	e.DataContentType = contentType
+	if contentType == "" {
+		if json.Valid(data) {
+			e.DataContentType = "application/json"
+		} else {
+			e.DataContentType = "application/octet-stream"
+		}
+	}
+}
+
+// Validate checks the event has all the required attributes, and valid extension names
+func (e Event) Validate() error {
+	if e.SpecVersion != SpecVersion {
+		return fmt.Errorf("unsupported specversion %q", e.SpecVersion)
+	}
+	if e.ID == "" {
+		return fmt.Errorf("missing id")
+	}
+	if e.Source == "" {
+		return fmt.Errorf("missing source")
+	}
+	if e.Type == "" {
+		return fmt.Errorf("missing type")
+	}
+	for name := range e.Extensions {
+		if !extensionNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid extension name %q", name)
+		}
+		if isContextAttribute(name) {
+			return fmt.Errorf("extension %q conflicts with a context attribute", name)
+		}
+	}
+
+	return nil
+}
+
+// isJSON returns true when the data of the event is JSON - events without a content type are JSON
+func (e Event) isJSON() bool {
+	if e.DataContentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(e.DataContentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isContextAttribute returns true for the names of the attributes defined by the specification
+func isContextAttribute(name string) bool {
+	switch name {
+	case "id", "source", "specversion", "type", "subject", "time", "datacontenttype", "dataschema", "data", "data_base64":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,228 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Mode is the HTTP content mode events are sent in
+type Mode string
+
+const (
+	// ModeBinary sends the event attributes as ce- headers and the data as the request body
+	ModeBinary Mode = "binary"
+	// ModeStructured sends the whole event in the JSON event format as the request body
+	ModeStructured Mode = "structured"
+
+	// StructuredContentType is the content type of events in structured mode
+	StructuredContentType = "application/cloudevents+json"
+	// BatchContentType is the content type of batches of events
+	BatchContentType = "application/cloudevents-batch+json"
+
+	headerPrefix = "Ce-"
+)
+
+// ParseMode returns the content mode named s, defaulting to binary
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(s)) {
+	case "", ModeBinary:
+		return ModeBinary, nil
+	case ModeStructured:
+		return ModeStructured, nil
+	default:
+		return "", fmt.Errorf("unknown content mode %q", s)
+	}
+}
+
+// NewRequest returns a POST request to url carrying the event in the given content mode
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md
+func NewRequest(ctx context.Context, url string, e Event, mode Mode) (*http.Request, error) {
+	if err := e.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event: %v", err)
+	}
+
+	var body []byte
+	header := http.Header{}
+	switch mode {
+	case ModeStructured:
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode event: %v", err)
+		}
+		body = b
+		header.Set("Content-Type", StructuredContentType+"; charset=utf-8")
+	case ModeBinary, "":
+		body = e.Data
+		for name, value := range binaryHeaders(e) {
+			header.Set(name, value)
+		}
+		if e.DataContentType != "" {
+			header.Set("Content-Type", e.DataContentType)
+		} else if e.Data != nil {
+			header.Set("Content-Type", "application/json")
+		}
+	default:
+		return nil, fmt.Errorf("unknown content mode %q", mode)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	return req, nil
+}
+
+// ReadRequest decodes the events of a request in binary mode, structured mode, or as a batch
+func ReadRequest(r *http.Request) ([]Event, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read request body: %v", err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == BatchContentType:
+		var events []Event
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, fmt.Errorf("cannot decode batch: %v", err)
+		}
+		for _, e := range events {
+			if err := e.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid event: %v", err)
+			}
+		}
+		return events, nil
+
+	case mediaType == StructuredContentType:
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			return nil, fmt.Errorf("cannot decode event: %v", err)
+		}
+		if err := e.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid event: %v", err)
+		}
+		return []Event{e}, nil
+
+	case r.Header.Get(headerPrefix+"Specversion") != "":
+		e, err := readBinary(r.Header, body)
+		if err != nil {
+			return nil, err
+		}
+		return []Event{e}, nil
+
+	default:
+		return nil, fmt.Errorf("request is not a CloudEvent")
+	}
+}
+
+// IsCloudEvent returns true when the request carries CloudEvents in any content mode
+func IsCloudEvent(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == StructuredContentType || mediaType == BatchContentType || r.Header.Get(headerPrefix+"Specversion") != ""
+}
+
+// binaryHeaders returns the ce- headers of an event in binary mode
+func binaryHeaders(e Event) map[string]string {
+	headers := map[string]string{
+		headerPrefix + "Id":          e.ID,
+		headerPrefix + "Source":      e.Source,
+		headerPrefix + "Specversion": e.SpecVersion,
+		headerPrefix + "Type":        e.Type,
+	}
+	if e.Subject != "" {
+		headers[headerPrefix+"Subject"] = e.Subject
+	}
+	if !e.Time.IsZero() {
+		headers[headerPrefix+"Time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if e.DataSchema != "" {
+		headers[headerPrefix+"Dataschema"] = e.DataSchema
+	}
+	for name, value := range e.Extensions {
+		headers[headerPrefix+name] = value
+	}
+
+	for name, value := range headers {
+		headers[name] = encodeHeaderValue(value)
+	}
+
+	return headers
+}
+
+// readBinary decodes an event in binary mode
+func readBinary(header http.Header, body []byte) (Event, error) {
+	e := Event{}
+	for name, values := range header {
+		if len(values) == 0 || !strings.HasPrefix(http.CanonicalHeaderKey(name), headerPrefix) {
+			continue
+		}
+
+		value, err := url.PathUnescape(values[0])
+		if err != nil {
+			return e, fmt.Errorf("invalid header %s: %v", name, err)
+		}
+
+		attribute := strings.ToLower(name[len(headerPrefix):])
+		switch attribute {
+		case "id":
+			e.ID = value
+		case "source":
+			e.Source = value
+		case "specversion":
+			e.SpecVersion = value
+		case "type":
+			e.Type = value
+		case "subject":
+			e.Subject = value
+		case "dataschema":
+			e.DataSchema = value
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return e, fmt.Errorf("invalid header %s: %v", name, err)
+			}
+			e.Time = t
+		default:
+			e.SetExtension(attribute, value)
+		}
+	}
+
+	e.DataContentType = header.Get("Content-Type")
+	if len(body) > 0 {
+		e.Data = body
+	}
+
+	if err := e.Validate(); err != nil {
+		return e, fmt.Errorf("invalid event: %v", err)
+	}
+
+	return e, nil
+}
+
+// encodeHeaderValue percent-encodes the characters the HTTP binding does not allow in header values:
+// spaces, double quotes, percent signs, and anything outside of printable ASCII
+func encodeHeaderValue(value string) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		if c <= ' ' || c >= 0x7f || c == '"' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package cloudevents
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MarshalJSON encodes the event in the JSON event format. JSON data is embedded
+// as is, any other data is base64 encoded in data_base64.
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/formats/json-format.md
+func (e Event) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"id":          e.ID,
+		"source":      e.Source,
+		"specversion": e.SpecVersion,
+		"type":        e.Type,
+	}
+	if e.Subject != "" {
+		m["subject"] = e.Subject
+	}
+	if !e.Time.IsZero() {
+		m["time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if e.DataContentType != "" {
+		m["datacontenttype"] = e.DataContentType
+	}
+	if e.DataSchema != "" {
+		m["dataschema"] = e.DataSchema
+	}
+	for name, value := range e.Extensions {
+		m[name] = value
+	}
+
+	if e.Data != nil {
+		if e.isJSON() && json.Valid(e.Data) {
+			m["data"] = json.RawMessage(e.Data)
+		} else {
+			m["data_base64"] = base64.StdEncoding.EncodeToString(e.Data)
+		}
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes an event in the JSON event format. Attributes that are
+// not defined by the specification are decoded as extensions.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	*e = Event{}
+	var data json.RawMessage
+	for name, raw := range m {
+		var err error
+		switch name {
+		case "id":
+			err = json.Unmarshal(raw, &e.ID)
+		case "source":
+			err = json.Unmarshal(raw, &e.Source)
+		case "specversion":
+			err = json.Unmarshal(raw, &e.SpecVersion)
+		case "type":
+			err = json.Unmarshal(raw, &e.Type)
+		case "subject":
+			err = json.Unmarshal(raw, &e.Subject)
+		case "datacontenttype":
+			err = json.Unmarshal(raw, &e.DataContentType)
+		case "dataschema":
+			err = json.Unmarshal(raw, &e.DataSchema)
+		case "time":
+			var t string
+			if err = json.Unmarshal(raw, &t); err == nil {
+				e.Time, err = time.Parse(time.RFC3339Nano, t)
+			}
+		case "data":
+			// decoded once the content type is known
+			if string(raw) != "null" {
+				data = raw
+			}
+		case "data_base64":
+			var data string
+			if err = json.Unmarshal(raw, &data); err == nil {
+				e.Data, err = base64.StdEncoding.DecodeString(data)
+			}
+		default:
+			var value string
+			value, err = unmarshalExtension(raw)
+			e.SetExtension(name, value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid attribute %s: %v", name, err)
+		}
+	}
+
+	if data != nil {
+		var err error
+		if e.Data, err = unmarshalData(data, e.isJSON()); err != nil {
+			return fmt.Errorf("invalid attribute data: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// unmarshalData returns the raw JSON of data when the content type is JSON, so that JSON strings
+// keep their quotes. Any other content type, such as XML, is carried in a string, returned unquoted.
+func unmarshalData(raw json.RawMessage, isJSON bool) ([]byte, error) {
+	if !isJSON && len(raw) > 0 && raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+
+	return []byte(raw), nil
+}
+
+// unmarshalExtension returns the canonical string representation of an extension value.
+// Extensions can be strings, integers or booleans in the JSON format.
+func unmarshalExtension(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	switch v.(type) {
+	case float64, bool:
+		return string(raw), nil
+	default:
+		return "", fmt.Errorf("unsupported extension value %s", raw)
+	}
+}
@@ -0,0 +1,50 @@
+package eventgrid
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+// Credentials are the credentials of the Azure AD service principal the operator authenticates with
+type Credentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+}
+
+// DefaultCredentials returns the credentials of the operator, from its AZ_TENANT_ID,
+// AZ_CLIENT_ID and AZ_CLIENT_SECRET environment variables
+func DefaultCredentials() Credentials {
+	return Credentials{
+		TenantID:     tenantID,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}
+}
+
+// Token returns a token of the service principal for resource, such as https://storage.azure.com/
+func (c Credentials) Token(resource string) (*adal.ServicePrincipalToken, error) {
+	oAuthConfig, err := adal.NewOAuthConfig(defaultActiveDirectoryEndpoint, c.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get oauth config: %v", err)
+	}
+	token, err := adal.NewServicePrincipalToken(*oAuthConfig, c.ClientID, c.ClientSecret, resource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get service principal token: %v", err)
+	}
+
+	return token, nil
+}
+
+// TokenCredential returns the credential of the service principal for the clients of the Azure SDK
+func (c Credentials) TokenCredential() (azcore.TokenCredential, error) {
+	credential, err := azidentity.NewClientSecretCredential(c.TenantID, c.ClientID, c.ClientSecret, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get service principal credential: %v", err)
+	}
+
+	return credential, nil
+}
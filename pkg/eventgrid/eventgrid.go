@@ -9,7 +9,6 @@ import (
 	"github.com/Azure/azure-sdk-for-go/services/eventgrid/mgmt/2018-01-01/eventgrid"
 
 	"github.com/Azure/go-autorest/autorest"
-	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/to"
 )
@@ -29,13 +28,9 @@ var (
 func getEventGridClient() (eventgrid.EventSubscriptionsClient, error) {
 	var subscriptionsClient eventgrid.EventSubscriptionsClient
 
-	oAuthConfig, err := adal.NewOAuthConfig(defaultActiveDirectoryEndpoint, tenantID)
+	token, err := DefaultCredentials().Token(defaultResourceManagerEndpoint)
 	if err != nil {
-		return subscriptionsClient, fmt.Errorf("cannot get oauth config: %v", err)
-	}
-	token, err := adal.NewServicePrincipalToken(*oAuthConfig, clientID, clientSecret, defaultResourceManagerEndpoint)
-	if err != nil {
-		return subscriptionsClient, fmt.Errorf("cannot get service principal token: %v", err)
+		return subscriptionsClient, err
 	}
 
 	subscriptionsClient = eventgrid.NewEventSubscriptionsClient(subscriptionID)
@@ -90,7 +85,7 @@ func CreateOrUpdateEventSubscription(resourceGroup, storageAccountName, tlsWebho
 		return fmt.Errorf("cannot create event subscription: %v", err)
 	}
 
-	err = f.WaitForCompletion(ctx, c.Client)
+	err = f.WaitForCompletionRef(ctx, c.Client)
 	if err != nil {
 		return fmt.Errorf("cannot get the subscription create or update future response: %v", err)
 	}
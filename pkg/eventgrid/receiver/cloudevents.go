@@ -0,0 +1,31 @@
+package receiver
+
+import (
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+// CloudEvent maps an event in the Event Grid schema to CloudEvents, the same way
+// Event Grid does for subscriptions using the CloudEvents schema. For Storage events:
+//   - id is the event id
+//   - source is the topic, the storage account resource ID
+//   - type is the event type, such as Microsoft.Storage.BlobCreated
+//   - subject is the path of the blob, /blobServices/default/containers/<container>/blobs/<blob>
+//   - time is the event time
+//   - data is the event data, as JSON
+//
+// The data version is kept in the dataversion extension.
+// See https://learn.microsoft.com/azure/event-grid/cloud-event-schema
+func (e Event) CloudEvent() cloudevents.Event {
+	ce := cloudevents.New(e.ID, e.Topic, e.EventType)
+	ce.Subject = e.Subject
+	ce.Time = e.EventTime
+	if len(e.Data) > 0 {
+		ce.DataContentType = "application/json"
+		ce.Data = e.Data
+	}
+	if e.DataVersion != "" {
+		ce.SetExtension("dataversion", e.DataVersion)
+	}
+
+	return ce
+}
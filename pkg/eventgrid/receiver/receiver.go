@@ -0,0 +1,177 @@
+// Package receiver implements the webhook side of Azure Event Grid subscriptions:
+// it answers the subscription validation handshake, the CloudEvents abuse protection
+// handshake, and only forwards actual events to the handler.
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+const (
+	// SubscriptionValidationEventType is the type of the event Event Grid sends
+	// before activating a webhook subscription
+	SubscriptionValidationEventType = "Microsoft.EventGrid.SubscriptionValidationEvent"
+	// SubscriptionDeletedEventType is the type of the event Event Grid sends
+	// when a webhook subscription is deleted
+	SubscriptionDeletedEventType = "Microsoft.EventGrid.SubscriptionDeletedEvent"
+
+	// eventTypeHeader is set by Event Grid on every delivery
+	eventTypeHeader = "aeg-event-type"
+
+	// maxBodySize is the maximum size of an Event Grid delivery (1 MB) plus some headroom
+	maxBodySize = 2 << 20
+)
+
+// Event is an event in the Event Grid schema
+type Event struct {
+	ID              string          `json:"id"`
+	Topic           string          `json:"topic,omitempty"`
+	Subject         string          `json:"subject"`
+	EventType       string          `json:"eventType"`
+	EventTime       time.Time       `json:"eventTime"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataVersion     string          `json:"dataVersion,omitempty"`
+	MetadataVersion string          `json:"metadataVersion,omitempty"`
+}
+
+// SubscriptionValidationEventData is the data of a subscription validation event
+type SubscriptionValidationEventData struct {
+	ValidationCode string `json:"validationCode"`
+	ValidationURL  string `json:"validationUrl,omitempty"`
+}
+
+// SubscriptionValidationResponse is the answer to a subscription validation event
+type SubscriptionValidationResponse struct {
+	ValidationResponse string `json:"validationResponse"`
+}
+
+// Handler answers the Event Grid and CloudEvents handshakes, and forwards the
+// remaining deliveries to next. Deliveries in the Event Grid schema are stripped
+// of subscription lifecycle events, so next only ever receives actual events.
+type Handler struct {
+	next http.Handler
+
+	// AllowedOrigin is the origin allowed to deliver CloudEvents, "*" allows any origin
+	AllowedOrigin string
+}
+
+// NewHandler returns a new Handler forwarding events to next
+func NewHandler(next http.Handler) *Handler {
+	return &Handler{
+		next:          next,
+		AllowedOrigin: "*",
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		h.serveAbuseProtection(w, r)
+	case http.MethodPost:
+		h.serveDelivery(w, r)
+	default:
+		w.Header().Set("Allow", "OPTIONS, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveAbuseProtection answers the CloudEvents webhook validation handshake, sent by
+// Event Grid for subscriptions using the CloudEvents schema.
+// See https://github.com/cloudevents/spec/blob/v1.0/http-webhook.md#4-abuse-protection
+func (h *Handler) serveAbuseProtection(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("WebHook-Request-Origin")
+	if origin == "" {
+		// not a validation request, let the handler answer it
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	if h.AllowedOrigin != "*" && !strings.EqualFold(h.AllowedOrigin, origin) {
+		glog.Warningf("rejecting webhook validation request from origin %s", origin)
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Allow", "POST")
+	w.Header().Set("WebHook-Allowed-Origin", origin)
+	if rate := r.Header.Get("WebHook-Request-Rate"); rate != "" {
+		w.Header().Set("WebHook-Allowed-Rate", "*")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveDelivery handles a POST from Event Grid
+func (h *Handler) serveDelivery(w http.ResponseWriter, r *http.Request) {
+	// deliveries in the CloudEvents schema do not carry any lifecycle event
+	if r.Header.Get(eventTypeHeader) == "" || cloudevents.IsCloudEvent(r) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var events []Event
+	if err := json.Unmarshal(body, &events); err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode events: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var forward []Event
+	for _, e := range events {
+		switch e.EventType {
+		case SubscriptionValidationEventType:
+			h.serveValidation(w, e)
+			return
+		case SubscriptionDeletedEventType:
+			glog.Infof("event subscription for topic %s was deleted", e.Topic)
+		default:
+			forward = append(forward, e)
+		}
+	}
+
+	if len(forward) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// only re-encode the body when lifecycle events were dropped
+	if len(forward) != len(events) {
+		if body, err = json.Marshal(forward); err != nil {
+			http.Error(w, fmt.Sprintf("cannot encode events: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	h.next.ServeHTTP(w, r)
+}
+
+// serveValidation echoes the validation code of a subscription validation event
+func (h *Handler) serveValidation(w http.ResponseWriter, e Event) {
+	var data SubscriptionValidationEventData
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode validation event: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	glog.Infof("validating event subscription for topic %s", e.Topic)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SubscriptionValidationResponse{ValidationResponse: data.ValidationCode})
+}
@@ -0,0 +1,207 @@
+package receiver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// handler records the requests forwarded to it
+type handler struct {
+	bodies        []string
+	contentLength []string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.bodies = append(h.bodies, string(body))
+	h.contentLength = append(h.contentLength, r.Header.Get("Content-Length"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// deliver sends a delivery of Event Grid to h, and returns its response
+func deliver(h http.Handler, method, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", strings.NewReader(body))
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestSubscriptionValidation(t *testing.T) {
+	next := &handler{}
+	h := NewHandler(next)
+
+	w := deliver(h, http.MethodPost, `[{
+		"id": "2d1781af-3a4c-4d7c-bd0c-e34b19da4e66",
+		"topic": "/subscriptions/s/resourceGroups/g/providers/Microsoft.Storage/storageAccounts/a",
+		"subject": "",
+		"eventType": "Microsoft.EventGrid.SubscriptionValidationEvent",
+		"eventTime": "2018-01-25T22:12:19.4556811Z",
+		"data": {"validationCode": "512d38b6-c7b8-40c8-89fe-f46f9e9622b6", "validationUrl": "https://rp-eastus2.eventgrid.azure.net/validate"},
+		"dataVersion": "1"
+	}]`, map[string]string{"aeg-event-type": "SubscriptionValidation", "Content-Type": "application/json"})
+
+	var resp SubscriptionValidationResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || resp.ValidationResponse != "512d38b6-c7b8-40c8-89fe-f46f9e9622b6" {
+		t.Errorf("got status %d and validation response %q", w.Code, resp.ValidationResponse)
+	}
+	if len(next.bodies) != 0 {
+		t.Errorf("the validation was forwarded: %v", next.bodies)
+	}
+
+	w = deliver(h, http.MethodPost, `[{"id":"1","eventType":"Microsoft.EventGrid.SubscriptionValidationEvent","data":"code"}]`, map[string]string{"aeg-event-type": "SubscriptionValidation"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for an invalid validation event", w.Code)
+	}
+}
+
+func TestAbuseProtection(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowedOrigin string
+		header        map[string]string
+		status        int
+		allowedRate   string
+		forwarded     bool
+	}{
+		{
+			name:          "any origin",
+			allowedOrigin: "*",
+			header:        map[string]string{"WebHook-Request-Origin": "eventgrid.azure.net"},
+			status:        http.StatusOK,
+		},
+		{
+			name:          "rate",
+			allowedOrigin: "*",
+			header:        map[string]string{"WebHook-Request-Origin": "eventgrid.azure.net", "WebHook-Request-Rate": "120"},
+			status:        http.StatusOK,
+			allowedRate:   "*",
+		},
+		{
+			name:          "allowed origin",
+			allowedOrigin: "eventgrid.azure.net",
+			header:        map[string]string{"WebHook-Request-Origin": "EventGrid.Azure.net"},
+			status:        http.StatusOK,
+		},
+		{
+			name:          "other origin",
+			allowedOrigin: "eventgrid.azure.net",
+			header:        map[string]string{"WebHook-Request-Origin": "attacker.example.com"},
+			status:        http.StatusForbidden,
+		},
+		{
+			name:          "not a validation request",
+			allowedOrigin: "eventgrid.azure.net",
+			status:        http.StatusAccepted,
+			forwarded:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		next := &handler{}
+		h := NewHandler(next)
+		h.AllowedOrigin = tt.allowedOrigin
+
+		w := deliver(h, http.MethodOptions, "", tt.header)
+		if w.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if (len(next.bodies) == 1) != tt.forwarded {
+			t.Errorf("%s: forwarded %d requests", tt.name, len(next.bodies))
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if origin := w.Header().Get("WebHook-Allowed-Origin"); origin != tt.header["WebHook-Request-Origin"] {
+			t.Errorf("%s: got allowed origin %q", tt.name, origin)
+		}
+		if rate := w.Header().Get("WebHook-Allowed-Rate"); rate != tt.allowedRate {
+			t.Errorf("%s: got allowed rate %q", tt.name, rate)
+		}
+	}
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	const (
+		created = `{"id":"1","topic":"/t","subject":"/blobs/a","eventType":"Microsoft.Storage.BlobCreated","eventTime":"2020-01-02T03:04:05Z","data":{"api":"PutBlob"},"dataVersion":"1"}`
+		deleted = `{"id":"2","topic":"/t","subject":"","eventType":"Microsoft.EventGrid.SubscriptionDeletedEvent","eventTime":"2020-01-02T03:04:05Z","data":{}}`
+	)
+	notification := map[string]string{"aeg-event-type": "Notification", "Content-Type": "application/json"}
+
+	tests := []struct {
+		name      string
+		body      string
+		header    map[string]string
+		status    int
+		forwarded []string
+	}{
+		{name: "events", body: "[" + created + "]", header: notification, status: http.StatusAccepted, forwarded: []string{"Microsoft.Storage.BlobCreated"}},
+		{name: "lifecycle events stripped", body: "[" + deleted + "," + created + "]", header: notification, status: http.StatusAccepted, forwarded: []string{"Microsoft.Storage.BlobCreated"}},
+		{name: "lifecycle events only", body: "[" + deleted + "]", header: notification, status: http.StatusOK},
+		{name: "invalid events", body: "{", header: notification, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		next := &handler{}
+		w := deliver(NewHandler(next), http.MethodPost, tt.body, tt.header)
+		if w.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if len(tt.forwarded) == 0 {
+			if len(next.bodies) != 0 {
+				t.Errorf("%s: forwarded %v", tt.name, next.bodies)
+			}
+			continue
+		}
+
+		if len(next.bodies) != 1 {
+			t.Fatalf("%s: forwarded %d requests", tt.name, len(next.bodies))
+		}
+		var events []Event
+		if err := json.Unmarshal([]byte(next.bodies[0]), &events); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var types []string
+		for _, e := range events {
+			types = append(types, e.EventType)
+		}
+		if strings.Join(types, ",") != strings.Join(tt.forwarded, ",") {
+			t.Errorf("%s: forwarded events %v", tt.name, types)
+		}
+		if next.contentLength[0] != strconv.Itoa(len(next.bodies[0])) {
+			t.Errorf("%s: forwarded a body of %d bytes with a Content-Length of %s", tt.name, len(next.bodies[0]), next.contentLength[0])
+		}
+	}
+
+	// deliveries without lifecycle events are forwarded as is
+	next := &handler{}
+	body := "[ " + created + " ]"
+	deliver(NewHandler(next), http.MethodPost, body, notification)
+	if len(next.bodies) != 1 || next.bodies[0] != body {
+		t.Errorf("forwarded %v", next.bodies)
+	}
+
+	// deliveries in the CloudEvents schema are not decoded
+	next = &handler{}
+	cloudEvent := `{"specversion":"1.0","id":"1","source":"/t","type":"Microsoft.Storage.BlobCreated"}`
+	deliver(NewHandler(next), http.MethodPost, cloudEvent, map[string]string{"aeg-event-type": "Notification", "Content-Type": "application/cloudevents+json"})
+	if len(next.bodies) != 1 || next.bodies[0] != cloudEvent {
+		t.Errorf("forwarded %v", next.bodies)
+	}
+
+	if w := deliver(NewHandler(next), http.MethodGet, "", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d for a GET", w.Code)
+	}
+}
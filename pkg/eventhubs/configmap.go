@@ -0,0 +1,257 @@
+package eventhubs
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ownershipPrefix prefixes the keys of the configmap holding the owner of a partition,
+	// ownership.<scope>.<partition>
+	ownershipPrefix = "ownership."
+	// checkpointPrefix prefixes the keys of the configmap holding the checkpoint of a partition,
+	// checkpoint.<scope>.<partition>
+	checkpointPrefix = "checkpoint."
+	// checkpointAttempts is how many times a checkpoint is attempted when replicas race to update the configmap
+	checkpointAttempts = 5
+)
+
+// ConfigMapStore is a checkpoint store keeping the owners and checkpoints of the partitions
+// of a consumer group in a ConfigMap. Keys are scoped to the namespace, event hub and consumer
+// group, so that changing any of them does not resume from the checkpoints of another. Updates of the configmap are conditional on its resource
+// version, and ownerships carry their own ETag, so that a partition is claimed by a single
+// replica at a time.
+type ConfigMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	owner     metav1.OwnerReference
+}
+
+// ownership is the owner of a partition, as recorded in the configmap
+type ownership struct {
+	OwnerID          string    `json:"ownerID"`
+	ETag             string    `json:"etag"`
+	LastModifiedTime time.Time `json:"lastModifiedTime"`
+}
+
+// checkpoint is the checkpoint of a partition, as recorded in the configmap
+type checkpoint struct {
+	Offset         *int64 `json:"offset,omitempty"`
+	SequenceNumber *int64 `json:"sequenceNumber,omitempty"`
+}
+
+// NewConfigMapStore returns a checkpoint store recording ownerships and checkpoints in the
+// configmap namespace/name, created owned by owner
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string, owner metav1.OwnerReference) *ConfigMapStore {
+	return &ConfigMapStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		owner:     owner,
+	}
+}
+
+// ClaimOwnership implements azeventhubs.CheckpointStore. Partitions whose ownership changed since
+// it was listed are not claimed, and neither are any partitions when another replica updated the
+// configmap concurrently.
+func (s *ConfigMapStore) ClaimOwnership(ctx context.Context, partitionOwnership []azeventhubs.Ownership, _ *azeventhubs.ClaimOwnershipOptions) ([]azeventhubs.Ownership, error) {
+	cm, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cm = cm.DeepCopy()
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+
+	var claimed []azeventhubs.Ownership
+	for _, o := range partitionOwnership {
+		key := scopePrefix(ownershipPrefix, o.FullyQualifiedNamespace, o.EventHubName, o.ConsumerGroup) + o.PartitionID
+		if value, ok := cm.Data[key]; ok {
+			var current ownership
+			if err := json.Unmarshal([]byte(value), &current); err != nil {
+				return nil, fmt.Errorf("invalid ownership of partition %s in configmap %s/%s: %v", o.PartitionID, s.namespace, s.name, err)
+			}
+			if o.ETag == nil || string(*o.ETag) != current.ETag {
+				continue
+			}
+		} else if o.ETag != nil {
+			continue
+		}
+
+		etag, err := cloudevents.NewID()
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate ETag: %v", err)
+		}
+		record := ownership{OwnerID: o.OwnerID, ETag: etag, LastModifiedTime: time.Now().UTC()}
+		b, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		cm.Data[key] = string(b)
+
+		o.ETag = (*azcore.ETag)(&record.ETag)
+		o.LastModifiedTime = record.LastModifiedTime
+		claimed = append(claimed, o)
+	}
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	if err := s.save(ctx, cm); err != nil {
+		if errors.IsConflict(err) || errors.IsAlreadyExists(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// ListOwnership implements azeventhubs.CheckpointStore
+func (s *ConfigMapStore) ListOwnership(ctx context.Context, fullyQualifiedNamespace, eventHubName, consumerGroup string, _ *azeventhubs.ListOwnershipOptions) ([]azeventhubs.Ownership, error) {
+	cm, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := scopePrefix(ownershipPrefix, fullyQualifiedNamespace, eventHubName, consumerGroup)
+	var ownerships []azeventhubs.Ownership
+	for key, value := range cm.Data {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		var o ownership
+		if err := json.Unmarshal([]byte(value), &o); err != nil {
+			return nil, fmt.Errorf("invalid ownership %s in configmap %s/%s: %v", key, s.namespace, s.name, err)
+		}
+		etag := azcore.ETag(o.ETag)
+		ownerships = append(ownerships, azeventhubs.Ownership{
+			ConsumerGroup:           consumerGroup,
+			EventHubName:            eventHubName,
+			FullyQualifiedNamespace: fullyQualifiedNamespace,
+			PartitionID:             strings.TrimPrefix(key, prefix),
+			OwnerID:                 o.OwnerID,
+			ETag:                    &etag,
+			LastModifiedTime:        o.LastModifiedTime,
+		})
+	}
+
+	return ownerships, nil
+}
+
+// ListCheckpoints implements azeventhubs.CheckpointStore
+func (s *ConfigMapStore) ListCheckpoints(ctx context.Context, fullyQualifiedNamespace, eventHubName, consumerGroup string, _ *azeventhubs.ListCheckpointsOptions) ([]azeventhubs.Checkpoint, error) {
+	cm, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := scopePrefix(checkpointPrefix, fullyQualifiedNamespace, eventHubName, consumerGroup)
+	var checkpoints []azeventhubs.Checkpoint
+	for key, value := range cm.Data {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		var c checkpoint
+		if err := json.Unmarshal([]byte(value), &c); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint %s in configmap %s/%s: %v", key, s.namespace, s.name, err)
+		}
+		checkpoints = append(checkpoints, azeventhubs.Checkpoint{
+			ConsumerGroup:           consumerGroup,
+			EventHubName:            eventHubName,
+			FullyQualifiedNamespace: fullyQualifiedNamespace,
+			PartitionID:             strings.TrimPrefix(key, prefix),
+			Offset:                  c.Offset,
+			SequenceNumber:          c.SequenceNumber,
+		})
+	}
+
+	return checkpoints, nil
+}
+
+// SetCheckpoint implements azeventhubs.CheckpointStore
+func (s *ConfigMapStore) SetCheckpoint(ctx context.Context, c azeventhubs.Checkpoint, _ *azeventhubs.SetCheckpointOptions) error {
+	b, err := json.Marshal(checkpoint{Offset: c.Offset, SequenceNumber: c.SequenceNumber})
+	if err != nil {
+		return err
+	}
+	key := scopePrefix(checkpointPrefix, c.FullyQualifiedNamespace, c.EventHubName, c.ConsumerGroup) + c.PartitionID
+
+	for attempt := 0; attempt < checkpointAttempts; attempt++ {
+		cm, err := s.get(ctx)
+		if err != nil {
+			return err
+		}
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(b)
+
+		err = s.save(ctx, cm)
+		if errors.IsConflict(err) || errors.IsAlreadyExists(err) {
+			continue
+		}
+		return err
+	}
+
+	return fmt.Errorf("configmap %s/%s kept changing", s.namespace, s.name)
+}
+
+// get returns the configmap, an empty one that does not exist yet when it is not found
+func (s *ConfigMapStore) get(ctx context.Context) (*corev1.ConfigMap, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            s.name,
+				OwnerReferences: []metav1.OwnerReference{s.owner},
+			},
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get configmap %s/%s: %v", s.namespace, s.name, err)
+	}
+
+	return cm, nil
+}
+
+// save creates the configmap when it does not exist yet, and updates it otherwise
+func (s *ConfigMapStore) save(ctx context.Context, cm *corev1.ConfigMap) error {
+	var err error
+	if cm.ResourceVersion == "" {
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+	} else {
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	}
+
+	return err
+}
+
+// scopePrefix returns the prefix of the keys of a consumer group. Consumer groups, such as $Default,
+// can hold characters configmap keys cannot, so the scope is a hash of the namespace, event hub
+// and consumer group, which are case insensitive.
+func scopePrefix(prefix, fullyQualifiedNamespace, eventHubName, consumerGroup string) string {
+	scope := strings.ToLower(fmt.Sprintf("%s/%s/%s", fullyQualifiedNamespace, eventHubName, consumerGroup))
+	hash := sha1.Sum([]byte(scope))
+
+	return prefix + hex.EncodeToString(hash[:])[:16] + "."
+}
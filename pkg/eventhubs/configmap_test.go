@@ -0,0 +1,164 @@
+package eventhubs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testNamespace = "events.servicebus.windows.net"
+	testEventHub  = "telemetry"
+)
+
+func newTestStore() *ConfigMapStore {
+	// the fake clientset does not set resource versions, so the configmap exists from the start
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hub-eventhubs", ResourceVersion: "1"},
+	})
+
+	return NewConfigMapStore(client, "default", "hub-eventhubs", metav1.OwnerReference{Name: "hub"})
+}
+
+func claim(t *testing.T, s *ConfigMapStore, group, partition, owner string, current *azeventhubs.Ownership) []azeventhubs.Ownership {
+	o := azeventhubs.Ownership{
+		FullyQualifiedNamespace: testNamespace,
+		EventHubName:            testEventHub,
+		ConsumerGroup:           group,
+		PartitionID:             partition,
+		OwnerID:                 owner,
+	}
+	if current != nil {
+		o.ETag = current.ETag
+	}
+
+	claimed, err := s.ClaimOwnership(context.Background(), []azeventhubs.Ownership{o}, nil)
+	if err != nil {
+		t.Fatalf("cannot claim partition %s: %v", partition, err)
+	}
+
+	return claimed
+}
+
+func TestClaimOwnership(t *testing.T) {
+	s := newTestStore()
+	ctx := context.Background()
+
+	claimed := claim(t, s, azeventhubs.DefaultConsumerGroup, "0", "a", nil)
+	if len(claimed) != 1 || claimed[0].ETag == nil {
+		t.Fatalf("got claimed %+v", claimed)
+	}
+
+	// a claim without the current ETag fails
+	if claimed := claim(t, s, azeventhubs.DefaultConsumerGroup, "0", "b", nil); len(claimed) != 0 {
+		t.Errorf("partition 0 was claimed twice: %+v", claimed)
+	}
+
+	ownerships, err := s.ListOwnership(ctx, testNamespace, testEventHub, azeventhubs.DefaultConsumerGroup, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ownerships) != 1 || ownerships[0].PartitionID != "0" || ownerships[0].OwnerID != "a" || ownerships[0].ConsumerGroup != azeventhubs.DefaultConsumerGroup {
+		t.Fatalf("got ownerships %+v", ownerships)
+	}
+
+	// stealing the partition requires its current ETag
+	if claimed := claim(t, s, azeventhubs.DefaultConsumerGroup, "0", "b", &ownerships[0]); len(claimed) != 1 || claimed[0].OwnerID != "b" {
+		t.Errorf("partition 0 was not stolen: %+v", claimed)
+	}
+	if claimed := claim(t, s, azeventhubs.DefaultConsumerGroup, "0", "a", &ownerships[0]); len(claimed) != 0 {
+		t.Errorf("partition 0 was claimed with a stale ETag: %+v", claimed)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	s := newTestStore()
+	ctx := context.Background()
+
+	claim(t, s, azeventhubs.DefaultConsumerGroup, "0", "a", nil)
+	claim(t, s, "analytics", "0", "b", nil)
+	claim(t, s, "analytics", "1", "b", nil)
+
+	for _, tt := range []struct {
+		namespace, eventHub, group string
+		ownerships                 int
+	}{
+		{namespace: testNamespace, eventHub: testEventHub, group: azeventhubs.DefaultConsumerGroup, ownerships: 1},
+		{namespace: testNamespace, eventHub: testEventHub, group: "analytics", ownerships: 2},
+		{namespace: testNamespace, eventHub: "other", group: "analytics", ownerships: 0},
+		{namespace: "other.servicebus.windows.net", eventHub: testEventHub, group: "analytics", ownerships: 0},
+	} {
+		ownerships, err := s.ListOwnership(ctx, tt.namespace, tt.eventHub, tt.group, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ownerships) != tt.ownerships {
+			t.Errorf("%s/%s/%s: got ownerships %+v", tt.namespace, tt.eventHub, tt.group, ownerships)
+		}
+	}
+
+	cm, err := s.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key := range cm.Data {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			t.Errorf("invalid configmap key %s: %v", key, errs)
+		}
+	}
+}
+
+func TestCheckpoints(t *testing.T) {
+	s := newTestStore()
+	ctx := context.Background()
+
+	for _, c := range []struct {
+		group     string
+		partition string
+		sequence  int64
+	}{
+		{group: azeventhubs.DefaultConsumerGroup, partition: "0", sequence: 10},
+		{group: azeventhubs.DefaultConsumerGroup, partition: "0", sequence: 12},
+		{group: azeventhubs.DefaultConsumerGroup, partition: "1", sequence: 3},
+		{group: "analytics", partition: "0", sequence: 100},
+	} {
+		sequence, offset := c.sequence, c.sequence*10
+		err := s.SetCheckpoint(ctx, azeventhubs.Checkpoint{
+			FullyQualifiedNamespace: testNamespace,
+			EventHubName:            testEventHub,
+			ConsumerGroup:           c.group,
+			PartitionID:             c.partition,
+			SequenceNumber:          &sequence,
+			Offset:                  &offset,
+		}, nil)
+		if err != nil {
+			t.Fatalf("cannot checkpoint partition %s: %v", c.partition, err)
+		}
+	}
+
+	checkpoints, err := s.ListCheckpoints(ctx, testNamespace, testEventHub, azeventhubs.DefaultConsumerGroup, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequences := map[string]int64{}
+	for _, c := range checkpoints {
+		sequences[c.PartitionID] = *c.SequenceNumber
+	}
+	if len(sequences) != 2 || sequences["0"] != 12 || sequences["1"] != 3 {
+		t.Errorf("got checkpoints %v", sequences)
+	}
+
+	checkpoints, err = s.ListCheckpoints(ctx, testNamespace, testEventHub, "analytics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 1 || *checkpoints[0].SequenceNumber != 100 {
+		t.Errorf("got checkpoints %+v", checkpoints)
+	}
+}
@@ -0,0 +1,204 @@
+// Package eventhubs implements the source of the eventhubs provider: it consumes all the partitions
+// of an event hub in a consumer group, balancing them across the replicas of the operator, and emits
+// the events as CloudEvents, checkpointing them once they were delivered.
+package eventhubs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+const (
+	// EventType is the CloudEvents type of Event Hubs events
+	EventType = "dev.events-operator.eventhubs.event"
+
+	// receiveWait is how long a partition waits for a full batch before delivering the events it received
+	receiveWait = 5 * time.Second
+)
+
+// Source consumes an event hub
+type Source struct {
+	// Namespace is the fully qualified namespace, such as <namespace>.servicebus.windows.net,
+	// authenticated with Credential
+	Namespace string
+	// Credential authenticates with Namespace
+	Credential azcore.TokenCredential
+	// ConnectionString is the connection string of the namespace, used instead of Namespace when not empty
+	ConnectionString string
+	// EventHub is the name of the event hub
+	EventHub string
+	// ConsumerGroup is the consumer group
+	ConsumerGroup string
+	// CheckpointStore records the owners of the partitions and their checkpoints
+	CheckpointStore azeventhubs.CheckpointStore
+	// StartPosition is where partitions without a checkpoint are consumed from
+	StartPosition azeventhubs.StartPosition
+	// MaxBatchSize is the maximum number of events received at once from a partition
+	MaxBatchSize int
+	// Identity identifies the replica of the operator as the owner of the partitions it consumes
+	Identity string
+	// Retry is how the delivery of events is retried, before they are skipped
+	Retry source.Retry
+	// EventSource is the CloudEvents source of events, the event hub is appended to it as a fragment
+	EventSource string
+}
+
+// Run implements source.Source. Partitions are consumed concurrently, each of them in order, and
+// checkpointed after every batch of events once they were all delivered or skipped, so that events
+// are delivered at least once.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	var client *azeventhubs.ConsumerClient
+	var err error
+	options := &azeventhubs.ConsumerClientOptions{InstanceID: s.Identity}
+	if s.ConnectionString != "" {
+		client, err = azeventhubs.NewConsumerClientFromConnectionString(s.ConnectionString, s.EventHub, s.ConsumerGroup, options)
+	} else {
+		client, err = azeventhubs.NewConsumerClient(s.Namespace, s.EventHub, s.ConsumerGroup, s.Credential, options)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot create Event Hubs client: %v", err)
+	}
+	defer client.Close(context.Background())
+
+	if _, err := client.GetEventHubProperties(ctx, nil); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("cannot get event hub %s: %v", s.EventHub, err)
+	}
+
+	processor, err := azeventhubs.NewProcessor(client, s.CheckpointStore, &azeventhubs.ProcessorOptions{
+		LoadBalancingStrategy: azeventhubs.ProcessorStrategyBalanced,
+		StartPositions:        azeventhubs.StartPositions{Default: s.StartPosition},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create Event Hubs processor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the processor hands out the partitions it claims until it stops
+	var consumers sync.WaitGroup
+	consumers.Add(1)
+	go func() {
+		defer consumers.Done()
+		for {
+			partition := processor.NextPartitionClient(ctx)
+			if partition == nil {
+				return
+			}
+
+			consumers.Add(1)
+			go func() {
+				defer consumers.Done()
+				defer partition.Close(context.Background())
+				s.consume(ctx, partition, sink)
+			}()
+		}
+	}()
+	sink.Ready()
+
+	err = processor.Run(ctx)
+	stopped := ctx.Err() != nil
+	cancel()
+	consumers.Wait()
+	if err != nil && !stopped {
+		return fmt.Errorf("Event Hubs processor stopped: %v", err)
+	}
+
+	return nil
+}
+
+// consume delivers the events of a partition until its ownership is lost or ctx is done
+func (s *Source) consume(ctx context.Context, partition *azeventhubs.ProcessorPartitionClient, sink source.Sink) {
+	glog.Infof("consuming partition %s of event hub %s", partition.PartitionID(), s.EventHub)
+
+	for ctx.Err() == nil {
+		events, err := s.receive(ctx, partition)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var ehErr *azeventhubs.Error
+			if errors.As(err, &ehErr) && ehErr.Code == azeventhubs.ErrorCodeOwnershipLost {
+				glog.Infof("partition %s of event hub %s is owned by another replica", partition.PartitionID(), s.EventHub)
+				return
+			}
+			// the processor hands the partition out again once its ownership expires
+			glog.Errorf("cannot receive events of partition %s of event hub %s: %v", partition.PartitionID(), s.EventHub, err)
+			return
+		}
+		if len(events) == 0 {
+			continue
+		}
+
+		for _, event := range events {
+			e := s.event(partition.PartitionID(), event)
+			if err := s.Retry.Deliver(ctx, sink, e); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				glog.Errorf("skipping event %s of event hub %s: %v", e.ID, s.EventHub, err)
+			}
+		}
+
+		if err := partition.UpdateCheckpoint(ctx, events[len(events)-1], nil); err != nil && ctx.Err() == nil {
+			glog.Errorf("cannot checkpoint partition %s of event hub %s: %v", partition.PartitionID(), s.EventHub, err)
+		}
+	}
+}
+
+// receive receives the next events of a partition, waiting receiveWait at most for a full batch
+func (s *Source) receive(ctx context.Context, partition *azeventhubs.ProcessorPartitionClient) ([]*azeventhubs.ReceivedEventData, error) {
+	receiveCtx, cancel := context.WithTimeout(ctx, receiveWait)
+	defer cancel()
+
+	events, err := partition.ReceiveEvents(receiveCtx, s.MaxBatchSize, nil)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		// the wait expired, with the events received so far
+		return events, nil
+	}
+
+	return events, err
+}
+
+// event returns the CloudEvent of an event. Its data is the event body, of the content type of
+// the event, its properties are extensions, and its partition key the partitionkey extension.
+func (s *Source) event(partitionID string, event *azeventhubs.ReceivedEventData) cloudevents.Event {
+	e := cloudevents.New(fmt.Sprintf("%s-%s-%d", s.EventHub, partitionID, event.SequenceNumber), s.EventSource+"#"+s.EventHub, EventType)
+	e.Subject = fmt.Sprintf("partition:%s#%d", partitionID, event.SequenceNumber)
+	if event.EnqueuedTime != nil {
+		e.Time = *event.EnqueuedTime
+	}
+
+	var contentType string
+	if event.ContentType != nil {
+		contentType = *event.ContentType
+	}
+	e.SetData(contentType, event.Body)
+
+	properties := map[string]string{}
+	for name, value := range event.Properties {
+		if strings.EqualFold(name, "content-type") {
+			continue
+		}
+		properties[name] = fmt.Sprint(value)
+	}
+	e.SetExtensions(properties)
+	if event.PartitionKey != nil && *event.PartitionKey != "" {
+		e.SetExtension("partitionkey", *event.PartitionKey)
+	}
+
+	return e
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/eventgrid/receiver"
+)
+
+// maxBodySize is the maximum size of an Event Grid delivery (1 MB) plus some headroom
+const maxBodySize = 2 << 20
+
+// Decode returns the events of a request, either a batch in the Event Grid schema,
+// or CloudEvents in any content mode
+func Decode(r *http.Request) ([]Event, error) {
+	body := io.LimitReader(r.Body, maxBodySize)
+
+	if cloudevents.IsCloudEvent(r) {
+		r.Body = io.NopCloser(body)
+		ces, err := cloudevents.ReadRequest(r)
+		if err != nil {
+			return nil, err
+		}
+
+		events := make([]Event, 0, len(ces))
+		for _, ce := range ces {
+			e, err := FromCloudEvent(ce)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, e)
+		}
+		return events, nil
+	}
+
+	var batch []receiver.Event
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("cannot decode events: %v", err)
+	}
+
+	events := make([]Event, 0, len(batch))
+	for _, eg := range batch {
+		e, err := FromEventGrid(eg)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// FromEventGrid returns the storage event of an event in the Event Grid schema
+func FromEventGrid(e receiver.Event) (Event, error) {
+	return newEvent(e.ID, e.Topic, e.EventType, e.Subject, e.EventTime, e.Data)
+}
+
+// FromCloudEvent returns the storage event of a CloudEvent
+func FromCloudEvent(e cloudevents.Event) (Event, error) {
+	return newEvent(e.ID, e.Source, e.Type, e.Subject, e.Time, e.Data)
+}
+
+func newEvent(id, source, eventType, subject string, t time.Time, raw []byte) (Event, error) {
+	e := Event{
+		ID:      id,
+		Source:  source,
+		Type:    eventType,
+		Subject: subject,
+		Time:    t,
+	}
+
+	var err error
+	switch eventType {
+	case BlobCreatedEventType:
+		data := &BlobCreatedData{}
+		err = json.Unmarshal(raw, data)
+		e.Data = data
+	case BlobDeletedEventType:
+		data := &BlobDeletedData{}
+		err = json.Unmarshal(raw, data)
+		e.Data = data
+	default:
+		e.Data = json.RawMessage(raw)
+	}
+	if err != nil {
+		return e, fmt.Errorf("cannot decode data of event %s: %v", id, err)
+	}
+
+	return e, nil
+}
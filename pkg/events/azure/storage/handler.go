@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/eventgrid/receiver"
+)
+
+// Handler is an http.Handler decoding storage events and calling the function
+// matching their type. Functions left nil ignore their events, and so do event
+// types without a function. Handlers are meant to be embedded:
+//
+//	type handler struct {
+//		storage.Handler
+//	}
+//
+//	h := &handler{}
+//	h.OnBlobCreated = h.process
+//	http.ListenAndServe(":8080", h)
+//
+// The Event Grid and CloudEvents validation handshakes are answered, so the handler
+// can be exposed directly. Any error answers 500, for the event to be delivered again.
+type Handler struct {
+	// OnBlobCreated is called for each Microsoft.Storage.BlobCreated event
+	OnBlobCreated func(ctx context.Context, e Event, data *BlobCreatedData) error
+	// OnBlobDeleted is called for each Microsoft.Storage.BlobDeleted event
+	OnBlobDeleted func(ctx context.Context, e Event, data *BlobDeletedData) error
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.NewHandler(http.HandlerFunc(h.serveEvents)).ServeHTTP(w, r)
+}
+
+func (h *Handler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "OPTIONS, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	events, err := Decode(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, e := range events {
+		if err := h.handle(r.Context(), e); err != nil {
+			glog.Errorf("cannot handle event %s of type %s: %v", e.ID, e.Type, err)
+			http.Error(w, "cannot handle event", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) handle(ctx context.Context, e Event) error {
+	switch data := e.Data.(type) {
+	case *BlobCreatedData:
+		if h.OnBlobCreated != nil {
+			return h.OnBlobCreated(ctx, e, data)
+		}
+	case *BlobDeletedData:
+		if h.OnBlobDeleted != nil {
+			return h.OnBlobDeleted(ctx, e, data)
+		}
+	}
+
+	glog.V(4).Infof("ignoring event %s of type %s", e.ID, e.Type)
+	return nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"strings"
+)
+
+const (
+	containersSegment = "/containers/"
+	blobsSegment      = "/blobs/"
+)
+
+// BlobURL returns the URL of the blob the event is about, empty for non-blob events
+func (e Event) BlobURL() string {
+	switch data := e.Data.(type) {
+	case *BlobCreatedData:
+		return data.URL
+	case *BlobDeletedData:
+		return data.URL
+	default:
+		return ""
+	}
+}
+
+// Container returns the name of the container of the blob, parsed from the subject
+func (e Event) Container() string {
+	container, _ := e.splitSubject()
+	return container
+}
+
+// Blob returns the name of the blob, including its virtual directories, parsed from the subject
+func (e Event) Blob() string {
+	_, blob := e.splitSubject()
+	return blob
+}
+
+// splitSubject returns the container and the blob of a subject, parsed by position, as
+// containers and blobs can be named after the segments: /containers/<container>/blobs/<blob>
+func (e Event) splitSubject() (string, string) {
+	i := strings.Index(e.Subject, containersSegment)
+	if i < 0 {
+		return "", ""
+	}
+
+	container := e.Subject[i+len(containersSegment):]
+	j := strings.Index(container, "/")
+	if j < 0 {
+		return container, ""
+	}
+	if !strings.HasPrefix(container[j:], blobsSegment) {
+		return container[:j], ""
+	}
+
+	return container[:j], container[j+len(blobsSegment):]
+}
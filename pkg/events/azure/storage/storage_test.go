@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+func request(t *testing.T, fixture, contentType string) *http.Request {
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	if contentType == "application/json" {
+		r.Header.Set("aeg-event-type", "Notification")
+	}
+
+	return r
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		contentType string
+		eventType   string
+		container   string
+		blob        string
+		url         string
+	}{
+		{
+			fixture:     "blob_created.json",
+			contentType: "application/json",
+			eventType:   BlobCreatedEventType,
+			container:   "blobs",
+			blob:        "images/blobs/cat.png",
+			url:         "https://eventsaccount.blob.core.windows.net/blobs/images/blobs/cat.png",
+		},
+		{
+			fixture:     "blob_deleted.json",
+			contentType: "application/json",
+			eventType:   BlobDeletedEventType,
+			container:   "uploads",
+			blob:        "report.csv",
+			url:         "https://eventsaccount.blob.core.windows.net/uploads/report.csv",
+		},
+		{
+			fixture:     "blob_created.cloudevent.json",
+			contentType: cloudevents.StructuredContentType,
+			eventType:   BlobCreatedEventType,
+			container:   "uploads",
+			blob:        "2020/report.csv",
+			url:         "https://eventsaccount.blob.core.windows.net/uploads/2020/report.csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			events, err := Decode(request(t, tt.fixture, tt.contentType))
+			if err != nil {
+				t.Fatalf("cannot decode events: %v", err)
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+
+			e := events[0]
+			if e.Type != tt.eventType {
+				t.Errorf("got type %s, want %s", e.Type, tt.eventType)
+			}
+			if e.Container() != tt.container {
+				t.Errorf("got container %q, want %q", e.Container(), tt.container)
+			}
+			if e.Blob() != tt.blob {
+				t.Errorf("got blob %q, want %q", e.Blob(), tt.blob)
+			}
+			if e.BlobURL() != tt.url {
+				t.Errorf("got URL %q, want %q", e.BlobURL(), tt.url)
+			}
+		})
+	}
+}
+
+func TestSplitSubject(t *testing.T) {
+	tests := []struct {
+		subject   string
+		container string
+		blob      string
+	}{
+		{subject: "/blobServices/default/containers/uploads/blobs/a.txt", container: "uploads", blob: "a.txt"},
+		{subject: "/blobServices/default/containers/blobs/blobs/a.txt", container: "blobs", blob: "a.txt"},
+		{subject: "/blobServices/default/containers/containers/blobs/x/containers/y", container: "containers", blob: "x/containers/y"},
+		{subject: "/blobServices/default/containers/uploads", container: "uploads"},
+		{subject: "/blobServices/default/containers/uploads/other/a.txt", container: "uploads"},
+		{subject: "/resourceGroups/events"},
+	}
+
+	for _, tt := range tests {
+		e := Event{Subject: tt.subject}
+		if e.Container() != tt.container || e.Blob() != tt.blob {
+			t.Errorf("%s: got container %q and blob %q, want %q and %q", tt.subject, e.Container(), e.Blob(), tt.container, tt.blob)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var created, deleted []string
+	h := &Handler{
+		OnBlobCreated: func(ctx context.Context, e Event, data *BlobCreatedData) error {
+			created = append(created, e.Blob())
+			if data.ContentLength != 524288 {
+				t.Errorf("got content length %d", data.ContentLength)
+			}
+			return nil
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request(t, "blob_created.json", "application/json"))
+	if w.Code != http.StatusOK || len(created) != 1 || created[0] != "images/blobs/cat.png" {
+		t.Errorf("got status %d and created blobs %v", w.Code, created)
+	}
+
+	// events without a function are ignored
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request(t, "blob_deleted.json", "application/json"))
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d for an ignored event", w.Code)
+	}
+
+	// errors answer 500, for the event to be delivered again
+	h.OnBlobDeleted = func(ctx context.Context, e Event, data *BlobDeletedData) error {
+		deleted = append(deleted, e.Blob())
+		return errors.New("unavailable")
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request(t, "blob_deleted.json", "application/json"))
+	if w.Code != http.StatusInternalServerError || len(deleted) != 1 {
+		t.Errorf("got status %d and deleted blobs %v", w.Code, deleted)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d for a GET", w.Code)
+	}
+}
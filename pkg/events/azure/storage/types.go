@@ -0,0 +1,91 @@
+// Package storage provides typed Azure Storage events, as delivered by Event Grid
+// to eventprovider handlers, either in the Event Grid schema or as CloudEvents.
+// See https://learn.microsoft.com/azure/event-grid/event-schema-blob-storage
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// BlobCreatedEventType is the type of the event sent when a blob is created or replaced
+	BlobCreatedEventType = "Microsoft.Storage.BlobCreated"
+	// BlobDeletedEventType is the type of the event sent when a blob is deleted
+	BlobDeletedEventType = "Microsoft.Storage.BlobDeleted"
+)
+
+// Event is an Azure Storage event, independently of the envelope it was delivered in
+type Event struct {
+	// ID of the event
+	ID string
+	// Source is the resource ID of the storage account
+	Source string
+	// Type of the event, such as Microsoft.Storage.BlobCreated
+	Type string
+	// Subject is the path of the blob, /blobServices/default/containers/<container>/blobs/<blob>
+	Subject string
+	// Time is when the event happened
+	Time time.Time
+	// Data is *BlobCreatedData or *BlobDeletedData for blob events,
+	// and the raw JSON data for any other event type
+	Data interface{}
+}
+
+// BlobCreatedData is the data of a Microsoft.Storage.BlobCreated event
+type BlobCreatedData struct {
+	// API is the operation that created the blob, such as PutBlob or CopyBlob
+	API             string `json:"api"`
+	ClientRequestID string `json:"clientRequestId"`
+	RequestID       string `json:"requestId"`
+	ETag            string `json:"eTag"`
+	ContentType     string `json:"contentType"`
+	ContentLength   int64  `json:"contentLength"`
+	// BlobType is BlockBlob, PageBlob or AppendBlob
+	BlobType   string `json:"blobType"`
+	AccessTier string `json:"accessTier,omitempty"`
+	// URL of the blob
+	URL string `json:"url"`
+	// Sequencer orders the events of a blob, comparing as strings
+	Sequencer          string             `json:"sequencer"`
+	StorageDiagnostics StorageDiagnostics `json:"storageDiagnostics"`
+}
+
+// BlobDeletedData is the data of a Microsoft.Storage.BlobDeleted event
+type BlobDeletedData struct {
+	// API is the operation that deleted the blob, DeleteBlob
+	API             string `json:"api"`
+	ClientRequestID string `json:"clientRequestId"`
+	RequestID       string `json:"requestId"`
+	ContentType     string `json:"contentType"`
+	// BlobType is BlockBlob, PageBlob or AppendBlob
+	BlobType string `json:"blobType"`
+	// URL of the blob
+	URL string `json:"url"`
+	// Sequencer orders the events of a blob, comparing as strings
+	Sequencer          string             `json:"sequencer"`
+	StorageDiagnostics StorageDiagnostics `json:"storageDiagnostics"`
+}
+
+// StorageDiagnostics is diagnostic data included by Azure Storage, for Microsoft support only
+type StorageDiagnostics struct {
+	BatchID string `json:"batchId,omitempty"`
+}
+
+// BlobCreated returns the data of a BlobCreated event
+func (e Event) BlobCreated() (*BlobCreatedData, bool) {
+	data, ok := e.Data.(*BlobCreatedData)
+	return data, ok
+}
+
+// BlobDeleted returns the data of a BlobDeleted event
+func (e Event) BlobDeleted() (*BlobDeletedData, bool) {
+	data, ok := e.Data.(*BlobDeletedData)
+	return data, ok
+}
+
+// RawData returns the data of events without a typed representation
+func (e Event) RawData() (json.RawMessage, bool) {
+	data, ok := e.Data.(json.RawMessage)
+	return data, ok
+}
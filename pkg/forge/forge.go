@@ -0,0 +1,131 @@
+// Package forge is the REST client shared by the packages managing the webhooks of git forges:
+// JSON requests authenticated with a token, errors answered by the API and paginated lists.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Client sends JSON requests to the REST API of a forge
+type Client struct {
+	baseURL string
+	// header is set on every request, such as the token
+	header http.Header
+	// apiError returns the error of a response outside of the 2xx range, into which its body is decoded
+	apiError func(statusCode int) error
+
+	client *http.Client
+}
+
+// NewClient returns a client of the API at baseURL, sending header with every request.
+// apiError returns the error of a response outside of the 2xx range, and must be a pointer
+// into which the JSON body of the response is decoded.
+func NewClient(baseURL string, header http.Header, apiError func(statusCode int) error) *Client {
+	return &Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		header:   header,
+		apiError: apiError,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Do sends a request to path, encoding in and decoding the response into out
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	_, err := c.request(ctx, method, c.baseURL+path, in, out)
+	return err
+}
+
+// List gets all the pages of the list at path, appending their items to out, a pointer to a slice.
+// Pages are followed with the Link header of the responses, as GitHub, GitLab and Gitea answer it.
+func (c *Client) List(ctx context.Context, path string, out interface{}) error {
+	items := reflect.ValueOf(out).Elem()
+	next := c.baseURL + path
+	for next != "" {
+		page := reflect.New(items.Type())
+		header, err := c.request(ctx, http.MethodGet, next, nil, page.Interface())
+		if err != nil {
+			return err
+		}
+		items.Set(reflect.AppendSlice(items, page.Elem()))
+
+		if next, err = c.nextPage(header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextPage returns the URL of the next page of a list, from the Link header of the response,
+// empty on the last page. The token is only ever sent to the API, so must be the next page.
+func (c *Client) nextPage(header http.Header) (string, error) {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		target := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) != `rel="next"` {
+				continue
+			}
+			if !strings.HasPrefix(target, c.baseURL+"/") {
+				return "", fmt.Errorf("the next page %s is outside of the API %s", target, c.baseURL)
+			}
+			return target, nil
+		}
+	}
+
+	return "", nil
+}
+
+// request sends a request to url, encoding in and decoding the response into out,
+// and returns the header of the response
+func (c *Client) request(ctx context.Context, method, url string, in, out interface{}) (http.Header, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode request: %v", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot %s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := c.apiError(resp.StatusCode)
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(apiErr)
+		return nil, apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return resp.Header, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %v", err)
+	}
+
+	return resp.Header, nil
+}
@@ -0,0 +1,138 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// testError is an error answered by the test API
+type testError struct {
+	StatusCode int
+	Message    string `json:"message"`
+}
+
+func (e *testError) Error() string {
+	return fmt.Sprintf("test API answered %d: %s", e.StatusCode, e.Message)
+}
+
+// item is an item of the lists of the test API
+type item struct {
+	ID int `json:"id"`
+}
+
+// newTestAPI returns an API listing items 1 to n at /items, pageSize items per page,
+// and echoing what is posted to /echo
+func newTestAPI(t *testing.T, n, pageSize int) *httptest.Server {
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"message": "unauthorized"})
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/items":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page < 1 {
+				page = 1
+			}
+			items := []item{}
+			for id := (page-1)*pageSize + 1; id <= n && id <= page*pageSize; id++ {
+				items = append(items, item{ID: id})
+			}
+			if page*pageSize < n {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/api/items?page=%d>; rel="next", <%s/api/items?page=1>; rel="first"`, s.URL, page+1, s.URL))
+			}
+			json.NewEncoder(w).Encode(items)
+		case "/api/echo":
+			if r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			var in item
+			json.NewDecoder(r.Body).Decode(&in)
+			json.NewEncoder(w).Encode(in)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "not found"})
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func newTestClient(baseURL, token string) *Client {
+	header := http.Header{}
+	header.Set("Authorization", "token "+token)
+
+	return NewClient(baseURL+"/api/", header, func(statusCode int) error { return &testError{StatusCode: statusCode} })
+}
+
+func TestList(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 4, 7} {
+		api := newTestAPI(t, n, 2)
+
+		var items []item
+		if err := newTestClient(api.URL, "secret").List(context.Background(), "/items", &items); err != nil {
+			t.Fatalf("cannot list %d items: %v", n, err)
+		}
+		if len(items) != n {
+			t.Errorf("got %d items, want %d", len(items), n)
+		}
+		for i, item := range items {
+			if item.ID != i+1 {
+				t.Errorf("got item %d at %d", item.ID, i)
+			}
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	api := newTestAPI(t, 0, 2)
+
+	var out item
+	if err := newTestClient(api.URL, "secret").Do(context.Background(), http.MethodPost, "/echo", item{ID: 3}, &out); err != nil || out.ID != 3 {
+		t.Errorf("got %+v and error %v", out, err)
+	}
+
+	err := newTestClient(api.URL, "secret").Do(context.Background(), http.MethodGet, "/missing", nil, nil)
+	if apiErr, ok := err.(*testError); !ok || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "not found" {
+		t.Errorf("got error %v", err)
+	}
+
+	var items []item
+	err = newTestClient(api.URL, "wrong").List(context.Background(), "/items", &items)
+	if apiErr, ok := err.(*testError); !ok || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	client := NewClient("https://api.github.com", nil, nil)
+
+	tests := []struct {
+		link  string
+		next  string
+		valid bool
+	}{
+		{link: "", valid: true},
+		{link: `<https://api.github.com/orgs/org/hooks?page=1>; rel="prev", <https://api.github.com/orgs/org/hooks?page=1>; rel="first"`, valid: true},
+		{link: `<https://api.github.com/orgs/org/hooks?page=3>; rel="next", <https://api.github.com/orgs/org/hooks?page=5>; rel="last"`, next: "https://api.github.com/orgs/org/hooks?page=3", valid: true},
+		{link: `<https://attacker.example.com/orgs/org/hooks?page=3>; rel="next"`},
+		{link: `<https://api.github.com.attacker.example.com/hooks?page=3>; rel="next"`},
+	}
+
+	for _, tt := range tests {
+		next, err := client.nextPage(http.Header{"Link": []string{tt.link}})
+		if next != tt.next || (err == nil) != tt.valid {
+			t.Errorf("%s: got next page %q and error %v", tt.link, next, err)
+		}
+	}
+}
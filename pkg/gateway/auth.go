@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// Authenticator checks the caller of a webhook is who it claims to be
+type Authenticator interface {
+	// Authenticate returns an error when the request, with the given body, is not authentic
+	Authenticate(r *http.Request, body []byte) error
+}
+
+// PathAuthenticator accepts any request: the secret path of the route is the only credential.
+// It is meant for local emulators, which cannot authenticate their deliveries.
+type PathAuthenticator struct{}
+
+// Authenticate implements Authenticator
+func (PathAuthenticator) Authenticate(r *http.Request, body []byte) error {
+	return nil
+}
+
+// BearerAuthenticator expects a token in an Authorization: Bearer header
+type BearerAuthenticator struct {
+	Token string
+}
+
+// Authenticate implements Authenticator
+func (a BearerAuthenticator) Authenticate(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len("Bearer ") || !strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return fmt.Errorf("missing bearer token")
+	}
+	if !equal(auth[len("Bearer "):], a.Token) {
+		return fmt.Errorf("invalid bearer token")
+	}
+
+	return nil
+}
+
+// TokenAuthenticator expects a token as is in a header
+type TokenAuthenticator struct {
+	Header string
+	Token  string
+}
+
+// Authenticate implements Authenticator
+func (a TokenAuthenticator) Authenticate(r *http.Request, body []byte) error {
+	token := r.Header.Get(a.Header)
+	if token == "" {
+		return fmt.Errorf("missing token header %s", a.Header)
+	}
+	if !equal(token, a.Token) {
+		return fmt.Errorf("invalid token")
+	}
+
+	return nil
+}
+
+// BasicAuthenticator expects basic auth credentials
+type BasicAuthenticator struct {
+	Username string
+	Password string
+}
+
+// Authenticate implements Authenticator
+func (a BasicAuthenticator) Authenticate(r *http.Request, body []byte) error {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return fmt.Errorf("missing basic auth credentials")
+	}
+
+	// check both, so the time taken does not tell which one is wrong
+	usernameOK := equal(username, a.Username)
+	passwordOK := equal(password, a.Password)
+	if !usernameOK || !passwordOK {
+		return fmt.Errorf("invalid basic auth credentials")
+	}
+
+	return nil
+}
+
+// HMACAuthenticator expects an HMAC signature of the body in a header
+type HMACAuthenticator struct {
+	// Header carrying the signature
+	Header string
+	// Prefix of the signature in the header, such as sha256=
+	Prefix string
+	// Hash of the HMAC
+	Hash func() hash.Hash
+	// Decode decodes the signature in the header
+	Decode func(string) ([]byte, error)
+	// Secret the HMAC is keyed with
+	Secret []byte
+}
+
+// NewHMACAuthenticator returns an HMACAuthenticator for the named algorithm and encoding
+func NewHMACAuthenticator(header, prefix, algorithm, encoding string, secret []byte) (*HMACAuthenticator, error) {
+	a := &HMACAuthenticator{
+		Header: header,
+		Prefix: prefix,
+		Secret: secret,
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "sha1":
+		a.Hash = sha1.New
+	case "", "sha256":
+		a.Hash = sha256.New
+	case "sha512":
+		a.Hash = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported HMAC algorithm %s", algorithm)
+	}
+
+	switch strings.ToLower(encoding) {
+	case "", "hex":
+		a.Decode = hex.DecodeString
+	case "base64":
+		a.Decode = base64.StdEncoding.DecodeString
+	default:
+		return nil, fmt.Errorf("unsupported signature encoding %s", encoding)
+	}
+
+	return a, nil
+}
+
+// Authenticate implements Authenticator
+func (a *HMACAuthenticator) Authenticate(r *http.Request, body []byte) error {
+	signature := r.Header.Get(a.Header)
+	if signature == "" {
+		return fmt.Errorf("missing signature header %s", a.Header)
+	}
+	if !strings.HasPrefix(signature, a.Prefix) {
+		return fmt.Errorf("signature without the %s prefix", a.Prefix)
+	}
+
+	actual, err := a.Decode(signature[len(a.Prefix):])
+	if err != nil {
+		return fmt.Errorf("cannot decode signature: %v", err)
+	}
+
+	mac := hmac.New(a.Hash, a.Secret)
+	mac.Write(body)
+	if !hmac.Equal(actual, mac.Sum(nil)) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// equal compares secrets in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// authRequest returns a request with the given headers
+func authRequest(header map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+
+	return r
+}
+
+func TestBearerAuthenticator(t *testing.T) {
+	a := BearerAuthenticator{Token: "token"}
+	tests := []struct {
+		authorization string
+		valid         bool
+	}{
+		{authorization: "Bearer token", valid: true},
+		{authorization: "bearer token", valid: true},
+		{authorization: ""},
+		{authorization: "Bearer"},
+		{authorization: "Bearer "},
+		{authorization: "Bearer other"},
+		{authorization: "Bearer token2"},
+		{authorization: "Basic token"},
+		{authorization: "token"},
+	}
+
+	for _, tt := range tests {
+		err := a.Authenticate(authRequest(map[string]string{"Authorization": tt.authorization}), nil)
+		if tt.valid && err != nil {
+			t.Errorf("%q: %v", tt.authorization, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%q: authenticated", tt.authorization)
+		}
+	}
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	a := BasicAuthenticator{Username: "user", Password: "password"}
+	basic := func(credentials string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		valid         bool
+	}{
+		{name: "valid", authorization: basic("user:password"), valid: true},
+		{name: "no credentials", authorization: ""},
+		{name: "wrong username", authorization: basic("other:password")},
+		{name: "wrong password", authorization: basic("user:other")},
+		{name: "no password", authorization: basic("user")},
+		{name: "not base64", authorization: "Basic user:password"},
+		{name: "bearer", authorization: "Bearer password"},
+	}
+
+	for _, tt := range tests {
+		err := a.Authenticate(authRequest(map[string]string{"Authorization": tt.authorization}), nil)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: authenticated", tt.name)
+		}
+	}
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	sign := func(h func() hash.Hash, secret string) []byte {
+		mac := hmac.New(h, []byte(secret))
+		mac.Write(body)
+		return mac.Sum(nil)
+	}
+
+	tests := []struct {
+		name      string
+		algorithm string
+		encoding  string
+		prefix    string
+		signature string
+		valid     bool
+	}{
+		{name: "default", signature: hex.EncodeToString(sign(sha256.New, "secret")), valid: true},
+		{name: "sha1", algorithm: "sha1", signature: hex.EncodeToString(sign(sha1.New, "secret")), valid: true},
+		{name: "sha512 base64", algorithm: "SHA512", encoding: "base64", signature: base64.StdEncoding.EncodeToString(sign(sha512.New, "secret")), valid: true},
+		{name: "prefix", prefix: "sha256=", signature: "sha256=" + hex.EncodeToString(sign(sha256.New, "secret")), valid: true},
+		{name: "missing prefix", prefix: "sha256=", signature: hex.EncodeToString(sign(sha256.New, "secret"))},
+		{name: "missing signature"},
+		{name: "wrong secret", signature: hex.EncodeToString(sign(sha256.New, "other"))},
+		{name: "wrong algorithm", algorithm: "sha512", signature: hex.EncodeToString(sign(sha256.New, "secret"))},
+		{name: "wrong encoding", encoding: "base64", signature: hex.EncodeToString(sign(sha256.New, "secret"))},
+		{name: "not hex", signature: strings.Repeat("z", 64)},
+	}
+
+	for _, tt := range tests {
+		a, err := NewHMACAuthenticator("X-Signature", tt.prefix, tt.algorithm, tt.encoding, []byte("secret"))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		err = a.Authenticate(authRequest(map[string]string{"X-Signature": tt.signature}), body)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: authenticated", tt.name)
+		}
+	}
+
+	// the signature covers the body
+	a, _ := NewHMACAuthenticator("X-Signature", "", "", "", []byte("secret"))
+	if err := a.Authenticate(authRequest(map[string]string{"X-Signature": hex.EncodeToString(sign(sha256.New, "secret"))}), []byte(`{"id":2}`)); err == nil {
+		t.Errorf("tampered body: authenticated")
+	}
+
+	for _, config := range [][2]string{{"md5", "hex"}, {"sha256", "base32"}} {
+		if _, err := NewHMACAuthenticator("X-Signature", "", config[0], config[1], []byte("secret")); err == nil {
+			t.Errorf("%s %s: no error", config[0], config[1])
+		}
+	}
+}
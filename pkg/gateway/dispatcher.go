@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+// Dispatcher delivers events to sinks over HTTP
+type Dispatcher struct {
+	client *http.Client
+}
+
+// NewDispatcher returns a new Dispatcher giving up on a delivery after timeout
+func NewDispatcher(timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Dispatch posts a CloudEvent to sink in the given content mode.
+// Any response outside of the 2xx range is an error.
+func (d *Dispatcher) Dispatch(ctx context.Context, sink string, event cloudevents.Event, mode cloudevents.Mode) error {
+	req, err := cloudevents.NewRequest(ctx, sink, event, mode)
+	if err != nil {
+		return fmt.Errorf("cannot create request: %v", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot deliver event to %s: %v", sink, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink %s answered %s", sink, resp.Status)
+	}
+
+	return nil
+}
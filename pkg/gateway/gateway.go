@@ -0,0 +1,218 @@
+// Package gateway implements the operator event gateway: it receives the webhooks
+// of all the eventproviders that have a sink, authenticates them, normalizes their
+// events and dispatches them to the sink of the eventprovider.
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/eventgrid/receiver"
+)
+
+const (
+	// ProviderEventGrid is the provider name of Azure Event Grid eventproviders
+	ProviderEventGrid = "eventgrid.azure.com"
+
+	// maxBodySize is the maximum size of a webhook delivery
+	maxBodySize = 2 << 20
+)
+
+// Route is how the gateway dispatches the events received for an eventprovider
+type Route struct {
+	// Namespace of the eventprovider
+	Namespace string
+	// Name of the eventprovider
+	Name string
+	// Provider is the provider name of the eventprovider
+	Provider string
+	// Path is the path the provider delivers events to. It contains a random
+	// token, so knowing the path authenticates the caller.
+	Path string
+	// Sink is the URL events are dispatched to
+	Sink string
+	// Mode is the CloudEvents content mode events are dispatched in
+	Mode cloudevents.Mode
+
+	// Auth authenticates the callers of generic webhooks, on top of the secret path
+	Auth Authenticator
+	// Source is the CloudEvents source of events built by the gateway
+	Source string
+	// EventType is the CloudEvents type of events built by the gateway
+	EventType string
+	// Events are the provider event names dispatched to the sink, all of them when empty or "*"
+	Events []string
+}
+
+// Gateway receives webhooks and dispatches their events to the sink of the matching route
+type Gateway struct {
+	mu     sync.RWMutex
+	routes map[string]Route
+
+	dispatcher *Dispatcher
+}
+
+// New returns a new Gateway without any route
+func New(dispatcher *Dispatcher) *Gateway {
+	return &Gateway{
+		routes:     map[string]Route{},
+		dispatcher: dispatcher,
+	}
+}
+
+// SetRoute adds or replaces the route of an eventprovider
+func (g *Gateway) SetRoute(route Route) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.deleteRoute(route.Namespace, route.Name)
+	g.routes[route.Path] = route
+}
+
+// DeleteRoute removes the route of an eventprovider
+func (g *Gateway) DeleteRoute(namespace, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.deleteRoute(namespace, name)
+}
+
+func (g *Gateway) deleteRoute(namespace, name string) {
+	for path, route := range g.routes {
+		if route.Namespace == namespace && route.Name == name {
+			delete(g.routes, path)
+		}
+	}
+}
+
+// acceptsEvent returns true when the events named event are dispatched to the sink
+func (r Route) acceptsEvent(event string) bool {
+	if len(r.Events) == 0 {
+		return true
+	}
+	for _, e := range r.Events {
+		if e == "*" || e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServeHTTP implements http.Handler
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.RLock()
+	route, ok := g.routes[r.URL.Path]
+	g.mu.RUnlock()
+
+	// unknown paths are indistinguishable from bad tokens
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch route.Provider {
+	case ProviderEventGrid:
+		receiver.NewHandler(&eventGridHandler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	case ProviderWebhook:
+		(&webhookHandler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	case ProviderGitHub:
+		(&githubHandler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	case ProviderGitLab:
+		(&gitlabHandler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	case ProviderGitea:
+		(&giteaHandler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	case ProviderSNS:
+		(&snsHandler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	case ProviderPubSub:
+		(&pubsubHandler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	case ProviderS3:
+		(&s3Handler{route: route, dispatcher: g.dispatcher}).ServeHTTP(w, r)
+	default:
+		glog.Errorf("no receiver for provider %s of eventprovider %s/%s", route.Provider, route.Namespace, route.Name)
+		http.Error(w, "unsupported provider", http.StatusNotImplemented)
+	}
+}
+
+// eventGridHandler dispatches Event Grid deliveries, once the handshakes were answered
+type eventGridHandler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP converts the Event Grid batch to CloudEvents, and dispatches them one by one.
+// Subscriptions using the CloudEvents schema already deliver CloudEvents, which are dispatched as is.
+// Any failure makes Event Grid retry the whole batch, so events are delivered at least once.
+func (h *eventGridHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+	var events []cloudevents.Event
+	if cloudevents.IsCloudEvent(r) {
+		var err error
+		if events, err = cloudevents.ReadRequest(r); err != nil {
+			http.Error(w, fmt.Sprintf("cannot decode events: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		var batch []receiver.Event
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, fmt.Sprintf("cannot decode events: %v", err), http.StatusBadRequest)
+			return
+		}
+		for _, e := range batch {
+			events = append(events, e.CloudEvent())
+		}
+	}
+
+	dispatch(w, r, h.route, h.dispatcher, events)
+}
+
+// dispatch delivers events to the sink of the route, and answers the provider:
+// 200 once all the events were delivered, 502 on the first failure
+func dispatch(w http.ResponseWriter, r *http.Request, route Route, dispatcher *Dispatcher, events []cloudevents.Event) {
+	for _, e := range events {
+		if err := dispatcher.Dispatch(r.Context(), route.Sink, e, route.Mode); err != nil {
+			glog.Errorf("cannot dispatch event %s for eventprovider %s/%s: %v", e.ID, route.Namespace, route.Name, err)
+			http.Error(w, "cannot dispatch event", http.StatusBadGateway)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// readAuthenticated reads the body of a POST, and authenticates it with the authenticator of the route.
+// Rejected requests are answered, counted and logged, and false is returned.
+func readAuthenticated(w http.ResponseWriter, r *http.Request, route Route) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		status := http.StatusBadRequest
+		if _, ok := err.(*http.MaxBytesError); ok {
+			status = http.StatusRequestEntityTooLarge
+		}
+		reject(w, r, route, "body", status, err)
+		return nil, false
+	}
+
+	if route.Auth == nil {
+		reject(w, r, route, "auth", http.StatusUnauthorized, fmt.Errorf("no authenticator configured"))
+		return nil, false
+	}
+	if err := route.Auth.Authenticate(r, body); err != nil {
+		reject(w, r, route, "auth", http.StatusUnauthorized, err)
+		return nil, false
+	}
+
+	return body, true
+}
@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+// sink is a test sink recording the events it receives
+type sink struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	events []cloudevents.Event
+	status int
+}
+
+func newSink(t *testing.T) *sink {
+	s := &sink{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events, err := cloudevents.ReadRequest(r)
+		if err != nil {
+			t.Errorf("sink received an invalid request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.events = append(s.events, events...)
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *sink) received() []cloudevents.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]cloudevents.Event(nil), s.events...)
+}
+
+func (s *sink) answer(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+// post sends body to the gateway at path, and returns the status it answered
+func post(g *Gateway, path, contentType, body string, header http.Header) int {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+
+	return w.Code
+}
+
+func TestDispatcher(t *testing.T) {
+	s := newSink(t)
+	d := NewDispatcher(time.Second)
+
+	e := cloudevents.New("1", "/s", "t")
+	e.SetData("application/json", []byte(`{"a":1}`))
+	for _, mode := range []cloudevents.Mode{cloudevents.ModeBinary, cloudevents.ModeStructured} {
+		if err := d.Dispatch(context.Background(), s.URL, e, mode); err != nil {
+			t.Errorf("%s: cannot dispatch event: %v", mode, err)
+		}
+	}
+	if events := s.received(); len(events) != 2 || string(events[0].Data) != `{"a":1}` || string(events[1].Data) != `{"a":1}` {
+		t.Errorf("got events %+v", events)
+	}
+
+	s.answer(http.StatusServiceUnavailable)
+	if err := d.Dispatch(context.Background(), s.URL, e, cloudevents.ModeBinary); err == nil {
+		t.Errorf("a sink answering 503 must fail the delivery")
+	}
+
+	if err := d.Dispatch(context.Background(), s.URL, cloudevents.Event{}, cloudevents.ModeBinary); err == nil {
+		t.Errorf("an invalid event must not be delivered")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	s := newSink(t)
+	g := New(NewDispatcher(time.Second))
+
+	route := Route{
+		Namespace: "default",
+		Name:      "hook",
+		Provider:  ProviderWebhook,
+		Path:      "/default/hook/token1",
+		Sink:      s.URL,
+		Auth:      PathAuthenticator{},
+		Source:    "/hook",
+		EventType: DefaultWebhookEventType,
+	}
+	g.SetRoute(route)
+
+	if status := post(g, "/default/hook/token1", "text/plain", "hello", nil); status != http.StatusOK {
+		t.Errorf("got status %d", status)
+	}
+	events := s.received()
+	if len(events) != 1 || string(events[0].Data) != "hello" || events[0].Type != DefaultWebhookEventType || events[0].Source != "/hook" {
+		t.Fatalf("got events %+v", events)
+	}
+
+	// a new path replaces the previous one
+	route.Path = "/default/hook/token2"
+	g.SetRoute(route)
+	if status := post(g, "/default/hook/token1", "text/plain", "hello", nil); status != http.StatusNotFound {
+		t.Errorf("got status %d for a replaced path", status)
+	}
+	if status := post(g, "/default/hook/token2", "text/plain", "hello", nil); status != http.StatusOK {
+		t.Errorf("got status %d for the new path", status)
+	}
+
+	// failed deliveries are retried by the provider
+	s.answer(http.StatusInternalServerError)
+	if status := post(g, "/default/hook/token2", "text/plain", "hello", nil); status != http.StatusBadGateway {
+		t.Errorf("got status %d for a failed delivery", status)
+	}
+
+	g.DeleteRoute("default", "hook")
+	if status := post(g, "/default/hook/token2", "text/plain", "hello", nil); status != http.StatusNotFound {
+		t.Errorf("got status %d for a deleted route", status)
+	}
+
+	route.Provider = "unknown"
+	g.SetRoute(route)
+	if status := post(g, "/default/hook/token2", "text/plain", "hello", nil); status != http.StatusNotImplemented {
+		t.Errorf("got status %d for an unknown provider", status)
+	}
+}
+
+func TestBody(t *testing.T) {
+	s := newSink(t)
+	g := New(NewDispatcher(time.Second))
+	g.SetRoute(Route{
+		Namespace: "default",
+		Name:      "hook",
+		Provider:  ProviderWebhook,
+		Path:      "/default/hook/token",
+		Sink:      s.URL,
+		Auth:      PathAuthenticator{},
+	})
+
+	if status := post(g, "/default/hook/token", "text/plain", strings.Repeat("a", maxBodySize+1), nil); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status %d for a body too large", status)
+	}
+
+	// bodies that cannot be read are not too large
+	r := httptest.NewRequest(http.MethodPost, "/default/hook/token", iotest.ErrReader(errors.New("connection reset")))
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for a body that cannot be read", w.Code)
+	}
+
+	if events := s.received(); len(events) != 0 {
+		t.Errorf("got events %+v", events)
+	}
+}
+
+func TestEventGridRoute(t *testing.T) {
+	s := newSink(t)
+	g := New(NewDispatcher(time.Second))
+	g.SetRoute(Route{
+		Namespace: "default",
+		Name:      "storage",
+		Provider:  ProviderEventGrid,
+		Path:      "/default/storage/token",
+		Sink:      s.URL,
+		Mode:      cloudevents.ModeStructured,
+	})
+
+	header := http.Header{"Aeg-Event-Type": []string{"Notification"}}
+	batch := `[
+		{"id":"1","topic":"/accounts/a","subject":"/containers/c/blobs/b","eventType":"Microsoft.Storage.BlobCreated","eventTime":"2020-01-02T03:04:05Z","data":{"url":"u"}},
+		{"id":"2","topic":"/accounts/a","subject":"/containers/c/blobs/b","eventType":"Microsoft.EventGrid.SubscriptionDeletedEvent","eventTime":"2020-01-02T03:04:05Z","data":{}},
+		{"id":"3","topic":"/accounts/a","subject":"/containers/c/blobs/b","eventType":"Microsoft.Storage.BlobDeleted","eventTime":"2020-01-02T03:04:05Z","data":{"url":"u"}}
+	]`
+	if status := post(g, "/default/storage/token", "application/json", batch, header); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+
+	events := s.received()
+	if len(events) != 2 || events[0].ID != "1" || events[1].ID != "3" {
+		t.Fatalf("got events %+v", events)
+	}
+	if events[0].Type != "Microsoft.Storage.BlobCreated" || events[0].Source != "/accounts/a" || string(events[0].Data) != `{"url":"u"}` {
+		t.Errorf("got event %+v", events[0])
+	}
+
+	// CloudEvents are dispatched as is
+	ce := `{"specversion":"1.0","id":"4","source":"/accounts/a","type":"Microsoft.Storage.BlobCreated","data":{"url":"u"}}`
+	if status := post(g, "/default/storage/token", cloudevents.StructuredContentType, ce, header); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if events := s.received(); len(events) != 3 || events[2].ID != "4" {
+		t.Errorf("got events %+v", events)
+	}
+}
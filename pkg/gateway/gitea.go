@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+const (
+	// ProviderGitea is the provider name of Gitea eventproviders
+	ProviderGitea = "gitea"
+
+	// GiteaSignatureHeader is the header Gitea signs deliveries in, with a hex HMAC-SHA256
+	GiteaSignatureHeader = "X-Gitea-Signature"
+)
+
+// giteaEvents maps the X-Gitea-Event header to the event names of the route
+var giteaEvents = map[string]string{
+	"push":         "push",
+	"pull_request": "merge_request",
+	"create":       "tag",
+}
+
+// giteaHandler verifies the signature of Gitea deliveries, and dispatches them as
+// CloudEvents of type io.gitea.push, io.gitea.merge_request or io.gitea.tag
+type giteaHandler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP implements http.Handler
+func (h *giteaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readAuthenticated(w, r, h.route)
+	if !ok {
+		return
+	}
+
+	event, ok := giteaEvents[r.Header.Get("X-Gitea-Event")]
+	if ok && event == "tag" {
+		// create events are sent for branches too
+		var payload struct {
+			RefType string `json:"ref_type"`
+		}
+		ok = json.Unmarshal(body, &payload) == nil && payload.RefType == "tag"
+	}
+	if !ok || !h.route.acceptsEvent(event) {
+		glog.V(4).Infof("ignoring Gitea %s event for eventprovider %s/%s", r.Header.Get("X-Gitea-Event"), h.route.Namespace, h.route.Name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	id, err := deliveryID(r, "X-Gitea-Delivery")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot generate event id: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	e := cloudevents.New(id, h.route.Source, "io.gitea."+event)
+	e.DataContentType = "application/json"
+	e.Data = body
+
+	dispatch(w, r, h.route, h.dispatcher, []cloudevents.Event{e})
+}
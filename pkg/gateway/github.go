@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+const (
+	// ProviderGitHub is the provider name of GitHub eventproviders
+	ProviderGitHub = "github"
+
+	// GitHubSignatureHeader is the header GitHub signs deliveries in
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+	// GitHubSignaturePrefix prefixes the hex HMAC-SHA256 of GitHub deliveries
+	GitHubSignaturePrefix = "sha256="
+)
+
+// githubHandler verifies the signature of GitHub deliveries, and dispatches the
+// ones for the events of the route as CloudEvents of type com.github.<event>
+type githubHandler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP implements http.Handler
+func (h *githubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readAuthenticated(w, r, h.route)
+	if !ok {
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "" {
+		reject(w, r, h.route, "event", http.StatusBadRequest, fmt.Errorf("missing X-GitHub-Event header"))
+		return
+	}
+
+	// GitHub pings a hook when it is created
+	if event == "ping" || !h.route.acceptsEvent(event) {
+		glog.V(4).Infof("ignoring GitHub %s event for eventprovider %s/%s", event, h.route.Namespace, h.route.Name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	id, err := deliveryID(r, "X-GitHub-Delivery")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot generate event id: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	e := cloudevents.New(id, h.route.Source, "com.github."+event)
+	e.DataContentType = "application/json"
+	e.Data = body
+
+	dispatch(w, r, h.route, h.dispatcher, []cloudevents.Event{e})
+}
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// githubSignature returns the X-Hub-Signature-256 of body
+func githubSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return GitHubSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubRoute(t *testing.T) {
+	s := newSink(t)
+	g := New(NewDispatcher(time.Second))
+
+	auth, err := NewHMACAuthenticator(GitHubSignatureHeader, GitHubSignaturePrefix, "sha256", "hex", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetRoute(Route{
+		Namespace: "default",
+		Name:      "github",
+		Provider:  ProviderGitHub,
+		Path:      "/default/github/token",
+		Sink:      s.URL,
+		Auth:      auth,
+		Source:    "https://api.github.com/repos/owner/repo",
+		Events:    []string{"push"},
+	})
+
+	body := `{"ref":"refs/heads/main"}`
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{
+			name:   "push",
+			header: http.Header{"X-Hub-Signature-256": {githubSignature("secret", body)}, "X-Github-Event": {"push"}, "X-Github-Delivery": {"d1"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "unsubscribed event",
+			header: http.Header{"X-Hub-Signature-256": {githubSignature("secret", body)}, "X-Github-Event": {"issues"}},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "ping",
+			header: http.Header{"X-Hub-Signature-256": {githubSignature("secret", body)}, "X-Github-Event": {"ping"}},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "missing event",
+			header: http.Header{"X-Hub-Signature-256": {githubSignature("secret", body)}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "wrong secret",
+			header: http.Header{"X-Hub-Signature-256": {githubSignature("other", body)}, "X-Github-Event": {"push"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "signature of another body",
+			header: http.Header{"X-Hub-Signature-256": {githubSignature("secret", body+" ")}, "X-Github-Event": {"push"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "sha1 signature",
+			header: http.Header{"X-Hub-Signature-256": {"sha1=" + githubSignature("secret", body)[len(GitHubSignaturePrefix):]}, "X-Github-Event": {"push"}},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "unsigned",
+			header: http.Header{"X-Github-Event": {"push"}},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		if status := post(g, "/default/github/token", "application/json", body, tt.header); status != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, status, tt.status)
+		}
+	}
+
+	events := s.received()
+	if len(events) != 1 {
+		t.Fatalf("got events %+v", events)
+	}
+	if e := events[0]; e.ID != "d1" || e.Type != "com.github.push" || e.Source != "https://api.github.com/repos/owner/repo" || string(e.Data) != body {
+		t.Errorf("got event %+v", e)
+	}
+}
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+const (
+	// ProviderGitLab is the provider name of GitLab eventproviders
+	ProviderGitLab = "gitlab"
+
+	// GitLabTokenHeader is the header GitLab sends the secret token of a hook in
+	GitLabTokenHeader = "X-Gitlab-Token"
+)
+
+// gitlabEvents maps the X-Gitlab-Event header to the event names of the route
+var gitlabEvents = map[string]string{
+	"Push Hook":          "push",
+	"Merge Request Hook": "merge_request",
+	"Tag Push Hook":      "tag",
+}
+
+// gitlabHandler checks the token of GitLab deliveries, and dispatches them as
+// CloudEvents of type com.gitlab.push, com.gitlab.merge_request or com.gitlab.tag
+type gitlabHandler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP implements http.Handler
+func (h *gitlabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readAuthenticated(w, r, h.route)
+	if !ok {
+		return
+	}
+
+	event, ok := gitlabEvents[r.Header.Get("X-Gitlab-Event")]
+	if !ok || !h.route.acceptsEvent(event) {
+		glog.V(4).Infof("ignoring GitLab %s event for eventprovider %s/%s", r.Header.Get("X-Gitlab-Event"), h.route.Namespace, h.route.Name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	id, err := deliveryID(r, "X-Gitlab-Event-UUID")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot generate event id: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	e := cloudevents.New(id, h.route.Source, "com.gitlab."+event)
+	e.DataContentType = "application/json"
+	e.Data = body
+
+	dispatch(w, r, h.route, h.dispatcher, []cloudevents.Event{e})
+}
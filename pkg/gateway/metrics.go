@@ -0,0 +1,29 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// rejectedRequests counts the webhook requests refused by the gateway
+	rejectedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "events_operator",
+		Subsystem: "gateway",
+		Name:      "rejected_requests_total",
+		Help:      "Number of webhook requests rejected by the event gateway.",
+	}, []string{"namespace", "name", "provider", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(rejectedRequests)
+}
+
+// reject counts and logs a rejected request, and answers it with status
+func reject(w http.ResponseWriter, r *http.Request, route Route, reason string, status int, err error) {
+	rejectedRequests.WithLabelValues(route.Namespace, route.Name, route.Provider, reason).Inc()
+	glog.Warningf("rejecting %s request from %s for eventprovider %s/%s: %v", reason, r.RemoteAddr, route.Namespace, route.Name, err)
+	http.Error(w, http.StatusText(status), status)
+}
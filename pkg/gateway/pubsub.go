@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/pubsub"
+)
+
+const (
+	// ProviderPubSub is the provider name of Google Cloud Pub/Sub eventproviders
+	ProviderPubSub = "pubsub"
+
+	// PubSubMessageEventType is the CloudEvents type of Pub/Sub messages
+	PubSubMessageEventType = "com.google.cloud.pubsub.message"
+)
+
+// pubsubHandler dispatches the messages pushed by a Pub/Sub subscription as CloudEvents.
+// The authenticator of the route verifies the OIDC token of pushes.
+type pubsubHandler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP implements http.Handler
+func (h *pubsubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readAuthenticated(w, r, h.route)
+	if !ok {
+		return
+	}
+
+	var push pubsub.PushRequest
+	if err := json.Unmarshal(body, &push); err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode push: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// the message data is the data of the event, its attributes are extensions
+	e := cloudevents.New(push.Message.MessageID, h.route.Source, PubSubMessageEventType)
+	e.Time = push.Message.PublishTime
+	e.SetData("", push.Message.Data)
+	e.SetExtensions(push.Message.Attributes)
+
+	// Pub/Sub retries pushes answered outside of 102, 200, 201, 202 and 204
+	dispatch(w, r, h.route, h.dispatcher, []cloudevents.Event{e})
+}
@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/s3"
+)
+
+const (
+	// ProviderS3 is the provider name of S3 bucket notification eventproviders
+	ProviderS3 = "s3"
+
+	// S3EventTypePrefix prefixes the CloudEvents type of S3 event records, followed by their
+	// event name, such as com.amazonaws.s3.ObjectCreated:Put
+	S3EventTypePrefix = "com.amazonaws.s3."
+)
+
+// s3Handler dispatches the records of bucket notifications as CloudEvents, one per record.
+// The authenticator of the route verifies the token of the webhook target.
+type s3Handler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP implements http.Handler
+func (h *s3Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readAuthenticated(w, r, h.route)
+	if !ok {
+		return
+	}
+
+	var n s3.Notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode notification: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	events := make([]cloudevents.Event, 0, len(n.Records))
+	for _, record := range n.Records {
+		e, err := s3CloudEvent(h.route.Source, record)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		events = append(events, e)
+	}
+
+	// the whole notification is delivered again when any of its records failed
+	dispatch(w, r, h.route, h.dispatcher, events)
+}
+
+// s3CloudEvent converts an event record as the CloudEvents S3 adapter does: the record is the
+// data of the event, and the object key its subject. The sequencer of the object is appended to
+// the ID, as the records of a multi-object request share the same request IDs.
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/adapters/aws-s3.md
+func s3CloudEvent(source string, raw json.RawMessage) (cloudevents.Event, error) {
+	var record s3.Record
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return cloudevents.Event{}, fmt.Errorf("cannot decode event record: %v", err)
+	}
+
+	id := record.ResponseElements["x-amz-request-id"]
+	if id2 := record.ResponseElements["x-amz-id-2"]; id2 != "" {
+		id += "." + id2
+	}
+	if record.S3.Object.Sequencer != "" {
+		id += "." + record.S3.Object.Sequencer
+	}
+	if id == "" {
+		var err error
+		if id, err = cloudevents.NewID(); err != nil {
+			return cloudevents.Event{}, fmt.Errorf("cannot generate event id: %v", err)
+		}
+	}
+
+	if record.S3.Bucket.ARN != "" {
+		source = record.S3.Bucket.ARN
+	}
+	e := cloudevents.New(id, source, S3EventTypePrefix+strings.TrimPrefix(record.EventName, "s3:"))
+	e.Time = record.EventTime
+	e.Subject = record.S3.Object.Key
+	if key, err := url.QueryUnescape(record.S3.Object.Key); err == nil {
+		e.Subject = key
+	}
+	e.SetData("application/json", raw)
+
+	return e, nil
+}
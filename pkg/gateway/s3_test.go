@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+// s3Notification is a MinIO notification of a multi-object delete, whose records share their request IDs
+const s3Notification = `{"EventName":"s3:ObjectRemoved:Delete","Key":"uploads/reports","Records":[
+	{"eventVersion":"2.0","eventSource":"minio:s3","awsRegion":"","eventTime":"2020-01-02T03:04:05.000Z","eventName":"s3:ObjectRemoved:Delete",
+	 "responseElements":{"x-amz-request-id":"16A1B2C3","x-amz-id-2":"dd9025bab4ad"},
+	 "s3":{"bucket":{"name":"uploads","arn":"arn:aws:s3:::uploads"},"object":{"key":"2020%2Freport+1.csv","sequencer":"16A1B2C3D4E5F600"}}},
+	{"eventVersion":"2.0","eventSource":"minio:s3","awsRegion":"","eventTime":"2020-01-02T03:04:05.000Z","eventName":"s3:ObjectRemoved:Delete",
+	 "responseElements":{"x-amz-request-id":"16A1B2C3","x-amz-id-2":"dd9025bab4ad"},
+	 "s3":{"bucket":{"name":"uploads","arn":"arn:aws:s3:::uploads"},"object":{"key":"2020%2Freport+2.csv","sequencer":"16A1B2C3D4E5F601"}}}
+]}`
+
+func TestS3CloudEvent(t *testing.T) {
+	record := json.RawMessage(`{"eventSource":"aws:s3","awsRegion":"eu-west-1","eventTime":"2020-01-02T03:04:05.000Z","eventName":"ObjectCreated:Put",
+		"responseElements":{"x-amz-request-id":"C3D13FE58DE4C810"},
+		"s3":{"bucket":{"name":"uploads"},"object":{"key":"a%20b.txt","size":1024}}}`)
+
+	e, err := s3CloudEvent("/default/uploads", record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "C3D13FE58DE4C810" || e.Type != "com.amazonaws.s3.ObjectCreated:Put" || e.Subject != "a b.txt" {
+		t.Errorf("got event %+v", e)
+	}
+	// without a bucket ARN, the source is the one of the route
+	if e.Source != "/default/uploads" || !e.Time.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("got source %s and time %s", e.Source, e.Time)
+	}
+	if e.DataContentType != "application/json" || string(e.Data) != string(record) {
+		t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+	}
+
+	// records without request IDs get a generated ID
+	if e, err := s3CloudEvent("/default/uploads", json.RawMessage(`{"eventName":"s3:ObjectCreated:Put"}`)); err != nil || e.ID == "" {
+		t.Errorf("got event %+v and error %v", e, err)
+	}
+	if _, err := s3CloudEvent("/default/uploads", json.RawMessage(`[]`)); err == nil {
+		t.Errorf("an invalid record must fail")
+	}
+}
+
+func TestS3Route(t *testing.T) {
+	s := newSink(t)
+	g := New(NewDispatcher(time.Second))
+	g.SetRoute(Route{
+		Namespace: "default",
+		Name:      "uploads",
+		Provider:  ProviderS3,
+		Path:      "/default/uploads/token",
+		Sink:      s.URL,
+		Mode:      cloudevents.ModeStructured,
+		Auth:      BearerAuthenticator{Token: "secret"},
+		Source:    "/default/uploads",
+	})
+
+	if status := post(g, "/default/uploads/token", "application/json", s3Notification, nil); status != http.StatusUnauthorized {
+		t.Errorf("got status %d without a token", status)
+	}
+
+	header := http.Header{"Authorization": []string{"Bearer secret"}}
+	if status := post(g, "/default/uploads/token", "application/json", s3Notification, header); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+
+	// one event per record, with distinct IDs
+	events := s.received()
+	if len(events) != 2 {
+		t.Fatalf("got events %+v", events)
+	}
+	for i, e := range events {
+		if e.ID != "16A1B2C3.dd9025bab4ad.16A1B2C3D4E5F60"+string(rune('0'+i)) {
+			t.Errorf("got ID %s", e.ID)
+		}
+		if e.Source != "arn:aws:s3:::uploads" || e.Type != "com.amazonaws.s3.ObjectRemoved:Delete" || e.Subject != "2020/report "+string(rune('1'+i))+".csv" {
+			t.Errorf("got event %+v", e)
+		}
+		if !strings.Contains(string(e.Data), `"sequencer":"16A1B2C3D4E5F60`+string(rune('0'+i))+`"`) {
+			t.Errorf("got data %s", e.Data)
+		}
+	}
+
+	if status := post(g, "/default/uploads/token", "application/json", `{"Records":[[]]}`, header); status != http.StatusBadRequest {
+		t.Errorf("got status %d for an invalid record", status)
+	}
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/sns"
+)
+
+const (
+	// ProviderSNS is the provider name of Amazon SNS eventproviders
+	ProviderSNS = "sns"
+
+	// SNSNotificationEventType is the CloudEvents type of SNS notifications
+	SNSNotificationEventType = "com.amazonaws.sns.notification"
+)
+
+// snsHandler confirms the subscription of the route to its topic, and dispatches the
+// notifications published to the topic as CloudEvents. The authenticator of the route
+// is the *sns.Verifier of the topic, so only signed messages for the topic get through.
+type snsHandler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP implements http.Handler
+func (h *snsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readAuthenticated(w, r, h.route)
+	if !ok {
+		return
+	}
+
+	var m sns.Message
+	if err := json.Unmarshal(body, &m); err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode message: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	switch m.Type {
+	case sns.SubscriptionConfirmationType:
+		verifier, ok := h.route.Auth.(*sns.Verifier)
+		if !ok {
+			http.Error(w, "cannot confirm subscription", http.StatusInternalServerError)
+			return
+		}
+		if err := verifier.Confirm(r.Context(), &m); err != nil {
+			glog.Errorf("cannot confirm subscription of eventprovider %s/%s to %s: %v", h.route.Namespace, h.route.Name, m.TopicArn, err)
+			http.Error(w, "cannot confirm subscription", http.StatusBadGateway)
+			return
+		}
+		glog.Infof("confirmed subscription of eventprovider %s/%s to %s", h.route.Namespace, h.route.Name, m.TopicArn)
+		w.WriteHeader(http.StatusOK)
+
+	case sns.UnsubscribeConfirmationType:
+		glog.Infof("eventprovider %s/%s was unsubscribed from %s", h.route.Namespace, h.route.Name, m.TopicArn)
+		w.WriteHeader(http.StatusOK)
+
+	case sns.NotificationType:
+		dispatch(w, r, h.route, h.dispatcher, []cloudevents.Event{snsCloudEvent(m)})
+
+	default:
+		http.Error(w, fmt.Sprintf("unknown message type %q", m.Type), http.StatusBadRequest)
+	}
+}
+
+// snsCloudEvent unwraps a notification: the published message is the data of the event,
+// and the message attributes are extensions, when their name is a valid extension name
+func snsCloudEvent(m sns.Message) cloudevents.Event {
+	e := cloudevents.New(m.MessageID, m.TopicArn, SNSNotificationEventType)
+	e.Subject = m.Subject
+	e.Time = m.Timestamp
+	e.Data = []byte(m.Message)
+	if json.Valid(e.Data) {
+		e.DataContentType = "application/json"
+	} else {
+		e.DataContentType = "text/plain"
+	}
+
+	attributes := make(map[string]string, len(m.MessageAttributes))
+	for name, attribute := range m.MessageAttributes {
+		attributes[name] = attribute.Value
+	}
+	e.SetExtensions(attributes)
+
+	return e
+}
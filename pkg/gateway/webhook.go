@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+const (
+	// ProviderWebhook is the provider name of generic webhook eventproviders
+	ProviderWebhook = "webhook"
+
+	// DefaultWebhookEventType is the type of webhook events when the eventprovider sets none
+	DefaultWebhookEventType = "dev.events-operator.webhook"
+)
+
+// webhookHandler authenticates the callers of a generic webhook, and dispatches their payloads as CloudEvents
+type webhookHandler struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+// ServeHTTP implements http.Handler
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := readAuthenticated(w, r, h.route)
+	if !ok {
+		return
+	}
+
+	id, err := cloudevents.NewID()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot generate event id: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	e := cloudevents.New(id, h.route.Source, h.route.EventType)
+	e.Time = time.Now()
+	if len(body) > 0 {
+		e.Data = body
+		e.DataContentType = r.Header.Get("Content-Type")
+	}
+
+	dispatch(w, r, h.route, h.dispatcher, []cloudevents.Event{e})
+}
+
+// deliveryID returns the ID of the delivery in header, or a random ID when the provider sends none
+func deliveryID(r *http.Request, header string) (string, error) {
+	if id := r.Header.Get(header); id != "" {
+		return id, nil
+	}
+
+	return cloudevents.NewID()
+}
@@ -0,0 +1,123 @@
+// Package gitea manages the webhooks of Gitea repositories through the Gitea REST API.
+// See https://gitea.com/api/swagger#/repository/repoListHooks
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/radu-matei/events-operator/pkg/forge"
+)
+
+// Hook is a Gitea repository webhook
+type Hook struct {
+	ID     int64      `json:"id,omitempty"`
+	Type   string     `json:"type,omitempty"`
+	Active bool       `json:"active"`
+	Events []string   `json:"events"`
+	Config HookConfig `json:"config"`
+}
+
+// HookConfig is where and how Gitea delivers the events of a hook
+type HookConfig struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	// Secret signs deliveries in X-Gitea-Signature. It is never returned by the API.
+	Secret string `json:"secret,omitempty"`
+}
+
+// NewHook returns an active JSON hook delivering events to url, signed with secret
+func NewHook(url, secret string, events []string) Hook {
+	return Hook{
+		Type:   "gitea",
+		Active: true,
+		Events: events,
+		Config: HookConfig{
+			URL:         url,
+			ContentType: "json",
+			Secret:      secret,
+		},
+	}
+}
+
+// Error is an error answered by the Gitea API
+type Error struct {
+	StatusCode int
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gitea API answered %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true when err is a 404 answered by the Gitea API
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client manages the hooks of a repository
+type Client struct {
+	api *forge.Client
+	// hooksPath is the path of the hooks collection, /repos/<owner>/<name>/hooks
+	hooksPath string
+}
+
+// NewClient returns a client for the hooks of a repository, in the owner/name form.
+// baseURL is the base URL of the API, https://<host>/api/v1.
+func NewClient(baseURL, token, repository string) (*Client, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("the Gitea API URL is required")
+	}
+
+	parts := strings.Split(repository, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid repository %q, expected owner/name", repository)
+	}
+
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	header.Set("Authorization", "token "+token)
+
+	return &Client{
+		api:       forge.NewClient(baseURL, header, func(statusCode int) error { return &Error{StatusCode: statusCode} }),
+		hooksPath: fmt.Sprintf("/repos/%s/%s/hooks", parts[0], parts[1]),
+	}, nil
+}
+
+// ListHooks returns the hooks of the repository, following the pages of the list
+func (c *Client) ListHooks(ctx context.Context) ([]Hook, error) {
+	var hooks []Hook
+	err := c.api.List(ctx, c.hooksPath+"?limit=50", &hooks)
+	return hooks, err
+}
+
+// GetHook returns the hook with the given ID
+func (c *Client) GetHook(ctx context.Context, id int64) (*Hook, error) {
+	hook := &Hook{}
+	err := c.api.Do(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.hooksPath, id), nil, hook)
+	return hook, err
+}
+
+// CreateHook creates a hook, and returns it with its ID
+func (c *Client) CreateHook(ctx context.Context, hook Hook) (*Hook, error) {
+	created := &Hook{}
+	err := c.api.Do(ctx, http.MethodPost, c.hooksPath, hook, created)
+	return created, err
+}
+
+// UpdateHook replaces the configuration of the hook with the given ID
+func (c *Client) UpdateHook(ctx context.Context, id int64, hook Hook) (*Hook, error) {
+	// the type of a hook cannot be changed
+	hook.Type = ""
+	updated := &Hook{}
+	err := c.api.Do(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", c.hooksPath, id), hook, updated)
+	return updated, err
+}
+
+// DeleteHook deletes the hook with the given ID
+func (c *Client) DeleteHook(ctx context.Context, id int64) error {
+	return c.api.Do(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", c.hooksPath, id), nil, nil)
+}
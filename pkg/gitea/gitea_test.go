@@ -0,0 +1,195 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pageSize is the page size of the fake API, small enough to paginate
+const pageSize = 2
+
+// fakeAPI is an in-memory implementation of the hooks API of the repository owner/repo
+type fakeAPI struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	hooks  map[int64]Hook
+	nextID int64
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	f := &fakeAPI{hooks: map[int64]Hook{}, nextID: 1}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "token token" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "token is required"})
+		return
+	}
+
+	const collection = "/api/v1/repos/owner/repo/hooks"
+	switch {
+	case r.URL.Path == collection && r.Method == http.MethodGet:
+		ids := make([]int64, 0, len(f.hooks))
+		for id := range f.hooks {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		hooks := []Hook{}
+		for i := (page - 1) * pageSize; i < len(ids) && i < page*pageSize; i++ {
+			hook := f.hooks[ids[i]]
+			hook.Config.Secret = ""
+			hooks = append(hooks, hook)
+		}
+		if page*pageSize < len(ids) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?limit=50&page=%d>; rel="next",<%s%s?limit=50&page=%d>; rel="last"`, f.URL, collection, page+1, f.URL, collection, (len(ids)+pageSize-1)/pageSize))
+		}
+		writeJSON(w, http.StatusOK, hooks)
+
+	case r.URL.Path == collection && r.Method == http.MethodPost:
+		var hook Hook
+		json.NewDecoder(r.Body).Decode(&hook)
+		hook.ID = f.nextID
+		f.nextID++
+		f.hooks[hook.ID] = hook
+		hook.Config.Secret = ""
+		writeJSON(w, http.StatusCreated, hook)
+
+	case strings.HasPrefix(r.URL.Path, collection+"/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, collection+"/"), 10, 64)
+		hook, ok := f.hooks[id]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			hook.Config.Secret = ""
+			writeJSON(w, http.StatusOK, hook)
+		case http.MethodPatch:
+			var changes Hook
+			json.NewDecoder(r.Body).Decode(&changes)
+			if changes.Type != "" {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "the type of a hook cannot be changed"})
+				return
+			}
+			changes.ID, changes.Type = id, hook.Type
+			f.hooks[id] = changes
+			changes.Config.Secret = ""
+			writeJSON(w, http.StatusOK, changes)
+		case http.MethodDelete:
+			delete(f.hooks, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestClient(t *testing.T, api *fakeAPI, token string) *Client {
+	client, err := NewClient(api.URL+"/api/v1", token, "owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestHooks(t *testing.T) {
+	api := newFakeAPI(t)
+	client := newTestClient(t, api, "token")
+	ctx := context.Background()
+
+	created, err := client.CreateHook(ctx, NewHook("https://events.example.com/a", "secret", []string{"push"}))
+	if err != nil {
+		t.Fatalf("cannot create hook: %v", err)
+	}
+	if created.ID == 0 || created.Config.Secret != "" || created.Type != "gitea" {
+		t.Errorf("got created hook %+v", created)
+	}
+	if stored := api.hooks[created.ID]; stored.Config.Secret != "secret" || !stored.Active {
+		t.Errorf("got stored hook %+v", stored)
+	}
+
+	updated, err := client.UpdateHook(ctx, created.ID, NewHook("https://events.example.com/a", "secret", []string{"push", "pull_request"}))
+	if err != nil {
+		t.Fatalf("cannot update hook: %v", err)
+	}
+	if len(updated.Events) != 2 || updated.Type != "gitea" {
+		t.Errorf("got updated hook %+v", updated)
+	}
+
+	if err := client.DeleteHook(ctx, created.ID); err != nil {
+		t.Fatalf("cannot delete hook: %v", err)
+	}
+	if _, err := client.GetHook(ctx, created.ID); !IsNotFound(err) {
+		t.Errorf("got error %v for a deleted hook", err)
+	}
+}
+
+func TestListHooksPages(t *testing.T) {
+	api := newFakeAPI(t)
+	client := newTestClient(t, api, "token")
+	ctx := context.Background()
+
+	for i := 0; i < 2*pageSize+1; i++ {
+		if _, err := client.CreateHook(ctx, NewHook(fmt.Sprintf("https://events.example.com/%d", i), "secret", nil)); err != nil {
+			t.Fatalf("cannot create hook: %v", err)
+		}
+	}
+
+	hooks, err := client.ListHooks(ctx)
+	if err != nil {
+		t.Fatalf("cannot list hooks: %v", err)
+	}
+	if len(hooks) != 2*pageSize+1 || hooks[2*pageSize].Config.URL != fmt.Sprintf("https://events.example.com/%d", 2*pageSize) {
+		t.Errorf("got hooks %+v", hooks)
+	}
+}
+
+func TestError(t *testing.T) {
+	api := newFakeAPI(t)
+
+	_, err := newTestClient(t, api, "wrong").ListHooks(context.Background())
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "token is required" {
+		t.Errorf("got error %v", err)
+	}
+
+	if _, err := NewClient("", "token", "owner/repo"); err == nil {
+		t.Errorf("the API URL is required")
+	}
+	if _, err := NewClient(api.URL, "token", "repo"); err == nil {
+		t.Errorf("a repository must be in the owner/name form")
+	}
+}
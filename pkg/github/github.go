@@ -0,0 +1,136 @@
+// Package github manages the webhooks of GitHub repositories and organizations
+// through the GitHub REST API.
+// See https://docs.github.com/rest/repos/webhooks and https://docs.github.com/rest/orgs/webhooks
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/radu-matei/events-operator/pkg/forge"
+)
+
+// DefaultAPIURL is the base URL of the github.com REST API
+const DefaultAPIURL = "https://api.github.com"
+
+// Hook is a GitHub webhook
+type Hook struct {
+	ID     int64      `json:"id,omitempty"`
+	Name   string     `json:"name"`
+	Active bool       `json:"active"`
+	Events []string   `json:"events"`
+	Config HookConfig `json:"config"`
+}
+
+// HookConfig is where and how GitHub delivers the events of a hook
+type HookConfig struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	// Secret signs deliveries in X-Hub-Signature-256. It is never returned by the API.
+	Secret      string `json:"secret,omitempty"`
+	InsecureSSL string `json:"insecure_ssl"`
+}
+
+// NewHook returns an active JSON hook delivering events to url, signed with secret
+func NewHook(url, secret string, events []string) Hook {
+	return Hook{
+		Name:   "web",
+		Active: true,
+		Events: events,
+		Config: HookConfig{
+			URL:         url,
+			ContentType: "json",
+			Secret:      secret,
+			InsecureSSL: "0",
+		},
+	}
+}
+
+// Error is an error answered by the GitHub API
+type Error struct {
+	StatusCode int
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("github API answered %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true when err is a 404 answered by the GitHub API
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client manages the hooks of a repository, owner/name, or of an organization
+type Client struct {
+	api *forge.Client
+	// hooksPath is the path of the hooks collection, /repos/<owner>/<name>/hooks or /orgs/<org>/hooks
+	hooksPath string
+}
+
+// NewRepositoryClient returns a client for the hooks of a repository, in the owner/name form
+func NewRepositoryClient(baseURL, token, repository string) (*Client, error) {
+	parts := strings.Split(repository, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid repository %q, expected owner/name", repository)
+	}
+
+	return newClient(baseURL, token, fmt.Sprintf("/repos/%s/%s/hooks", parts[0], parts[1])), nil
+}
+
+// NewOrganizationClient returns a client for the hooks of an organization
+func NewOrganizationClient(baseURL, token, organization string) *Client {
+	return newClient(baseURL, token, fmt.Sprintf("/orgs/%s/hooks", organization))
+}
+
+func newClient(baseURL, token, hooksPath string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	header.Set("Authorization", "Bearer "+token)
+
+	return &Client{
+		api:       forge.NewClient(baseURL, header, func(statusCode int) error { return &Error{StatusCode: statusCode} }),
+		hooksPath: hooksPath,
+	}
+}
+
+// ListHooks returns the hooks of the repository or organization, following the pages of the list
+func (c *Client) ListHooks(ctx context.Context) ([]Hook, error) {
+	var hooks []Hook
+	err := c.api.List(ctx, c.hooksPath+"?per_page=100", &hooks)
+	return hooks, err
+}
+
+// GetHook returns the hook with the given ID
+func (c *Client) GetHook(ctx context.Context, id int64) (*Hook, error) {
+	hook := &Hook{}
+	err := c.api.Do(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.hooksPath, id), nil, hook)
+	return hook, err
+}
+
+// CreateHook creates a hook, and returns it with its ID
+func (c *Client) CreateHook(ctx context.Context, hook Hook) (*Hook, error) {
+	created := &Hook{}
+	err := c.api.Do(ctx, http.MethodPost, c.hooksPath, hook, created)
+	return created, err
+}
+
+// UpdateHook replaces the configuration of the hook with the given ID
+func (c *Client) UpdateHook(ctx context.Context, id int64, hook Hook) (*Hook, error) {
+	updated := &Hook{}
+	err := c.api.Do(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", c.hooksPath, id), hook, updated)
+	return updated, err
+}
+
+// DeleteHook deletes the hook with the given ID
+func (c *Client) DeleteHook(ctx context.Context, id int64) error {
+	return c.api.Do(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", c.hooksPath, id), nil, nil)
+}
@@ -0,0 +1,205 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pageSize is the page size of the fake API, small enough to paginate
+const pageSize = 2
+
+// fakeAPI is an in-memory implementation of the hooks API of a repository
+type fakeAPI struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	hooks  map[int64]Hook
+	nextID int64
+	token  string
+}
+
+// newFakeAPI returns a fake API serving the hooks of the repository owner/repo
+func newFakeAPI(t *testing.T, token string) *fakeAPI {
+	f := &fakeAPI{hooks: map[int64]Hook{}, nextID: 1, token: token}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+		return
+	}
+
+	const collection = "/repos/owner/repo/hooks"
+	switch {
+	case r.URL.Path == collection && r.Method == http.MethodGet:
+		ids := make([]int64, 0, len(f.hooks))
+		for id := range f.hooks {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		hooks := []Hook{}
+		for i := (page - 1) * pageSize; i < len(ids) && i < page*pageSize; i++ {
+			hooks = append(hooks, f.public(f.hooks[ids[i]]))
+		}
+		if page*pageSize < len(ids) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=100&page=%d>; rel="next", <%s%s?page=1>; rel="first"`, f.URL, collection, page+1, f.URL, collection))
+		}
+		writeJSON(w, http.StatusOK, hooks)
+
+	case r.URL.Path == collection && r.Method == http.MethodPost:
+		var hook Hook
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+		hook.ID = f.nextID
+		f.nextID++
+		f.hooks[hook.ID] = hook
+		writeJSON(w, http.StatusCreated, f.public(hook))
+
+	case strings.HasPrefix(r.URL.Path, collection+"/"):
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, collection+"/"), 10, 64)
+		hook, ok := f.hooks[id]
+		if err != nil || !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, f.public(hook))
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+				return
+			}
+			hook.ID = id
+			f.hooks[id] = hook
+			writeJSON(w, http.StatusOK, f.public(hook))
+		case http.MethodDelete:
+			delete(f.hooks, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+// public returns a hook as the API does, without its secret
+func (f *fakeAPI) public(hook Hook) Hook {
+	hook.Config.Secret = ""
+	return hook
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestHooks(t *testing.T) {
+	api := newFakeAPI(t, "token")
+	client, err := NewRepositoryClient(api.URL, "token", "owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	created, err := client.CreateHook(ctx, NewHook("https://events.example.com/a", "secret", []string{"push"}))
+	if err != nil {
+		t.Fatalf("cannot create hook: %v", err)
+	}
+	if created.ID == 0 || created.Config.Secret != "" || created.Config.URL != "https://events.example.com/a" {
+		t.Errorf("got created hook %+v", created)
+	}
+	if stored := api.hooks[created.ID]; stored.Config.Secret != "secret" || stored.Name != "web" || !stored.Active {
+		t.Errorf("got stored hook %+v", stored)
+	}
+
+	updated, err := client.UpdateHook(ctx, created.ID, NewHook("https://events.example.com/a", "secret", []string{"push", "issues"}))
+	if err != nil {
+		t.Fatalf("cannot update hook: %v", err)
+	}
+	if len(updated.Events) != 2 {
+		t.Errorf("got updated hook %+v", updated)
+	}
+
+	hook, err := client.GetHook(ctx, created.ID)
+	if err != nil || len(hook.Events) != 2 {
+		t.Errorf("got hook %+v and error %v", hook, err)
+	}
+
+	if err := client.DeleteHook(ctx, created.ID); err != nil {
+		t.Fatalf("cannot delete hook: %v", err)
+	}
+	if _, err := client.GetHook(ctx, created.ID); !IsNotFound(err) {
+		t.Errorf("got error %v for a deleted hook", err)
+	}
+	if err := client.DeleteHook(ctx, created.ID); !IsNotFound(err) {
+		t.Errorf("got error %v deleting a deleted hook", err)
+	}
+}
+
+func TestListHooksPages(t *testing.T) {
+	api := newFakeAPI(t, "token")
+	client, err := NewRepositoryClient(api.URL, "token", "owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2*pageSize+1; i++ {
+		if _, err := client.CreateHook(ctx, NewHook(fmt.Sprintf("https://events.example.com/%d", i), "secret", nil)); err != nil {
+			t.Fatalf("cannot create hook: %v", err)
+		}
+	}
+
+	hooks, err := client.ListHooks(ctx)
+	if err != nil {
+		t.Fatalf("cannot list hooks: %v", err)
+	}
+	if len(hooks) != 2*pageSize+1 || hooks[2*pageSize].Config.URL != fmt.Sprintf("https://events.example.com/%d", 2*pageSize) {
+		t.Errorf("got hooks %+v", hooks)
+	}
+}
+
+func TestError(t *testing.T) {
+	api := newFakeAPI(t, "token")
+	client, err := NewRepositoryClient(api.URL, "wrong", "owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.ListHooks(context.Background())
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "Bad credentials" {
+		t.Errorf("got error %v", err)
+	}
+
+	if _, err := NewRepositoryClient(api.URL, "token", "repo"); err == nil {
+		t.Errorf("a repository must be in the owner/name form")
+	}
+}
@@ -0,0 +1,98 @@
+// Package gitlab manages the webhooks of GitLab projects through the GitLab REST API.
+// See https://docs.gitlab.com/ee/api/projects.html#hooks
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/radu-matei/events-operator/pkg/forge"
+)
+
+// DefaultAPIURL is the base URL of the gitlab.com REST API
+const DefaultAPIURL = "https://gitlab.com/api/v4"
+
+// Hook is a GitLab project webhook
+type Hook struct {
+	ID  int64  `json:"id,omitempty"`
+	URL string `json:"url"`
+	// Token is sent in the X-Gitlab-Token header of deliveries. It is never returned by the API.
+	Token                 string `json:"token,omitempty"`
+	PushEvents            bool   `json:"push_events"`
+	MergeRequestsEvents   bool   `json:"merge_requests_events"`
+	TagPushEvents         bool   `json:"tag_push_events"`
+	EnableSSLVerification bool   `json:"enable_ssl_verification"`
+}
+
+// Error is an error answered by the GitLab API
+type Error struct {
+	StatusCode int
+	Message    interface{} `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gitlab API answered %d: %v", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true when err is a 404 answered by the GitLab API
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client manages the hooks of a project
+type Client struct {
+	api *forge.Client
+	// hooksPath is the path of the hooks collection, /projects/<id>/hooks
+	hooksPath string
+}
+
+// NewClient returns a client for the hooks of a project, given by its ID or its group/name path
+func NewClient(baseURL, token, project string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", token)
+
+	return &Client{
+		api:       forge.NewClient(baseURL, header, func(statusCode int) error { return &Error{StatusCode: statusCode} }),
+		hooksPath: fmt.Sprintf("/projects/%s/hooks", url.PathEscape(project)),
+	}
+}
+
+// ListHooks returns the hooks of the project, following the pages of the list
+func (c *Client) ListHooks(ctx context.Context) ([]Hook, error) {
+	var hooks []Hook
+	err := c.api.List(ctx, c.hooksPath+"?per_page=100", &hooks)
+	return hooks, err
+}
+
+// GetHook returns the hook with the given ID
+func (c *Client) GetHook(ctx context.Context, id int64) (*Hook, error) {
+	hook := &Hook{}
+	err := c.api.Do(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.hooksPath, id), nil, hook)
+	return hook, err
+}
+
+// CreateHook creates a hook, and returns it with its ID
+func (c *Client) CreateHook(ctx context.Context, hook Hook) (*Hook, error) {
+	created := &Hook{}
+	err := c.api.Do(ctx, http.MethodPost, c.hooksPath, hook, created)
+	return created, err
+}
+
+// UpdateHook replaces the configuration of the hook with the given ID
+func (c *Client) UpdateHook(ctx context.Context, id int64, hook Hook) (*Hook, error) {
+	updated := &Hook{}
+	err := c.api.Do(ctx, http.MethodPut, fmt.Sprintf("%s/%d", c.hooksPath, id), hook, updated)
+	return updated, err
+}
+
+// DeleteHook deletes the hook with the given ID
+func (c *Client) DeleteHook(ctx context.Context, id int64) error {
+	return c.api.Do(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", c.hooksPath, id), nil, nil)
+}
@@ -0,0 +1,176 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pageSize is the page size of the fake API, small enough to paginate
+const pageSize = 2
+
+// fakeAPI is an in-memory implementation of the hooks API of the project group/project
+type fakeAPI struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	hooks  map[int64]Hook
+	nextID int64
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	f := &fakeAPI{hooks: map[int64]Hook{}, nextID: 1}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "token" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "401 Unauthorized"})
+		return
+	}
+
+	// the project path is escaped
+	const collection = "/api/v4/projects/group%2Fproject/hooks"
+	path := r.URL.EscapedPath()
+	switch {
+	case path == collection && r.Method == http.MethodGet:
+		ids := make([]int64, 0, len(f.hooks))
+		for id := range f.hooks {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		hooks := []Hook{}
+		for i := (page - 1) * pageSize; i < len(ids) && i < page*pageSize; i++ {
+			hook := f.hooks[ids[i]]
+			hook.Token = ""
+			hooks = append(hooks, hook)
+		}
+		if page*pageSize < len(ids) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d&per_page=100>; rel="next"`, f.URL, collection, page+1))
+		}
+		writeJSON(w, http.StatusOK, hooks)
+
+	case path == collection && r.Method == http.MethodPost:
+		var hook Hook
+		json.NewDecoder(r.Body).Decode(&hook)
+		hook.ID = f.nextID
+		f.nextID++
+		f.hooks[hook.ID] = hook
+		hook.Token = ""
+		writeJSON(w, http.StatusCreated, hook)
+
+	case strings.HasPrefix(path, collection+"/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, collection+"/"), 10, 64)
+		hook, ok := f.hooks[id]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "404 Not found"})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			hook.Token = ""
+			writeJSON(w, http.StatusOK, hook)
+		case http.MethodPut:
+			json.NewDecoder(r.Body).Decode(&hook)
+			hook.ID = id
+			f.hooks[id] = hook
+			hook.Token = ""
+			writeJSON(w, http.StatusOK, hook)
+		case http.MethodDelete:
+			delete(f.hooks, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "404 Not found"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestHooks(t *testing.T) {
+	api := newFakeAPI(t)
+	client := NewClient(api.URL+"/api/v4", "token", "group/project")
+	ctx := context.Background()
+
+	created, err := client.CreateHook(ctx, Hook{URL: "https://events.example.com/a", Token: "secret", PushEvents: true})
+	if err != nil {
+		t.Fatalf("cannot create hook: %v", err)
+	}
+	if created.ID == 0 || created.Token != "" || !created.PushEvents {
+		t.Errorf("got created hook %+v", created)
+	}
+	if stored := api.hooks[created.ID]; stored.Token != "secret" {
+		t.Errorf("got stored hook %+v", stored)
+	}
+
+	updated, err := client.UpdateHook(ctx, created.ID, Hook{URL: "https://events.example.com/a", Token: "secret", TagPushEvents: true})
+	if err != nil {
+		t.Fatalf("cannot update hook: %v", err)
+	}
+	if updated.PushEvents || !updated.TagPushEvents {
+		t.Errorf("got updated hook %+v", updated)
+	}
+
+	if err := client.DeleteHook(ctx, created.ID); err != nil {
+		t.Fatalf("cannot delete hook: %v", err)
+	}
+	if _, err := client.GetHook(ctx, created.ID); !IsNotFound(err) {
+		t.Errorf("got error %v for a deleted hook", err)
+	}
+}
+
+func TestListHooksPages(t *testing.T) {
+	api := newFakeAPI(t)
+	client := NewClient(api.URL+"/api/v4/", "token", "group/project")
+	ctx := context.Background()
+
+	for i := 0; i < 2*pageSize+1; i++ {
+		if _, err := client.CreateHook(ctx, Hook{URL: fmt.Sprintf("https://events.example.com/%d", i)}); err != nil {
+			t.Fatalf("cannot create hook: %v", err)
+		}
+	}
+
+	hooks, err := client.ListHooks(ctx)
+	if err != nil {
+		t.Fatalf("cannot list hooks: %v", err)
+	}
+	if len(hooks) != 2*pageSize+1 || hooks[2*pageSize].URL != fmt.Sprintf("https://events.example.com/%d", 2*pageSize) {
+		t.Errorf("got hooks %+v", hooks)
+	}
+}
+
+func TestError(t *testing.T) {
+	api := newFakeAPI(t)
+
+	_, err := NewClient(api.URL+"/api/v4", "wrong", "group/project").ListHooks(context.Background())
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "401 Unauthorized" {
+		t.Errorf("got error %v", err)
+	}
+}
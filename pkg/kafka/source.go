@@ -0,0 +1,132 @@
+// Package kafka implements the source of the kafka provider: a consumer group emitting the
+// records of topics as CloudEvents, committing their offsets once they were delivered.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+)
+
+// RecordEventType is the CloudEvents type of Kafka records
+const RecordEventType = "dev.events-operator.kafka.record"
+
+// Source consumes topics in a consumer group
+type Source struct {
+	// Brokers are the bootstrap brokers
+	Brokers []string
+	// Topics are the consumed topics
+	Topics []string
+	// Group is the ID of the consumer group
+	Group string
+	// Earliest starts a group without committed offsets at the start of the partitions, instead of their end
+	Earliest bool
+	// TLS enables TLS connections to the brokers when not nil
+	TLS *tls.Config
+	// SASL authenticates with the brokers when not nil
+	SASL sasl.Mechanism
+	// Retry is how the delivery of records is retried, before they are skipped
+	Retry source.Retry
+	// EventSource is the CloudEvents source of events, the topic is appended to it as a fragment
+	EventSource string
+}
+
+// Run implements source.Source. Records are delivered in order, one partition after the other,
+// and the offsets of a poll are only committed once all its records were delivered or skipped,
+// so that records are delivered at least once.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	start := kgo.NewOffset().AtEnd()
+	if s.Earliest {
+		start = kgo.NewOffset().AtStart()
+	}
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(s.Brokers...),
+		kgo.ConsumerGroup(s.Group),
+		kgo.ConsumeTopics(s.Topics...),
+		kgo.ConsumeResetOffset(start),
+		kgo.DisableAutoCommit(),
+		// partitions are not revoked while a poll is delivered, so its offsets can be committed
+		kgo.BlockRebalanceOnPoll(),
+	}
+	if s.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(s.TLS))
+	}
+	if s.SASL != nil {
+		opts = append(opts, kgo.SASL(s.SASL))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("cannot create Kafka client: %v", err)
+	}
+	defer client.CloseAllowingRebalance()
+
+	if err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("cannot connect to Kafka brokers: %v", err)
+	}
+	sink.Ready()
+
+	for {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return nil
+		}
+		for _, fe := range fetches.Errors() {
+			var dataLoss *kgo.ErrDataLoss
+			if errors.As(fe.Err, &dataLoss) {
+				glog.Warningf("consumer group %s lost records of %s/%d: %v", s.Group, fe.Topic, fe.Partition, fe.Err)
+				continue
+			}
+			return fmt.Errorf("cannot fetch records of %s/%d: %v", fe.Topic, fe.Partition, fe.Err)
+		}
+
+		for it := fetches.RecordIter(); !it.Done(); {
+			r := it.Next()
+			e := s.event(r)
+			if err := s.Retry.Deliver(ctx, sink, e); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				glog.Errorf("skipping Kafka record %s of %s: %v", e.ID, s.Group, err)
+			}
+		}
+
+		if err := client.CommitUncommittedOffsets(ctx); err != nil {
+			return fmt.Errorf("cannot commit offsets: %v", err)
+		}
+		client.AllowRebalance()
+	}
+}
+
+// event returns the CloudEvent of a record. The record value is the data, of the type of its
+// content-type header, and the other headers are extensions. The record key is the partitionkey extension.
+func (s *Source) event(r *kgo.Record) cloudevents.Event {
+	e := cloudevents.New(fmt.Sprintf("%s-%d-%d", r.Topic, r.Partition, r.Offset), s.EventSource+"#"+r.Topic, RecordEventType)
+	e.Subject = fmt.Sprintf("partition:%d#%d", r.Partition, r.Offset)
+	e.Time = r.Timestamp
+	if len(r.Key) > 0 {
+		e.SetExtension("partitionkey", string(r.Key))
+	}
+
+	var contentType string
+	headers := map[string]string{}
+	for _, h := range r.Headers {
+		if strings.EqualFold(h.Key, "content-type") {
+			contentType = string(h.Value)
+			continue
+		}
+		headers[h.Key] = string(h.Value)
+	}
+	e.SetData(contentType, r.Value)
+	e.SetExtensions(headers)
+
+	return e
+}
@@ -0,0 +1,160 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestEvent(t *testing.T) {
+	s := &Source{EventSource: "/apis/eventprovider.k8s.io/v1alpha1/namespaces/default/eventproviders/orders"}
+	timestamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		record      *kgo.Record
+		contentType string
+		extensions  map[string]string
+	}{
+		{
+			name: "headers",
+			record: &kgo.Record{
+				Topic:     "orders",
+				Partition: 3,
+				Offset:    42,
+				Timestamp: timestamp,
+				Key:       []byte("customer-1"),
+				Value:     []byte(`{"id":1}`),
+				Headers: []kgo.RecordHeader{
+					{Key: "Content-Type", Value: []byte("application/vnd.orders+json")},
+					{Key: "Tenant", Value: []byte("acme")},
+					{Key: "trace-id", Value: []byte("dropped, not a valid extension name")},
+					{Key: "id", Value: []byte("dropped, a context attribute")},
+				},
+			},
+			contentType: "application/vnd.orders+json",
+			extensions:  map[string]string{"partitionkey": "customer-1", "tenant": "acme"},
+		},
+		{
+			name:        "JSON value without headers nor key",
+			record:      &kgo.Record{Topic: "orders", Partition: 3, Offset: 42, Timestamp: timestamp, Value: []byte(`{"id":1}`)},
+			contentType: "application/json",
+			extensions:  map[string]string{},
+		},
+		{
+			name:        "binary value",
+			record:      &kgo.Record{Topic: "orders", Partition: 3, Offset: 42, Timestamp: timestamp, Value: []byte{0xff, 0x00}},
+			contentType: "application/octet-stream",
+			extensions:  map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		e := s.event(tt.record)
+		if e.ID != "orders-3-42" || e.Source != s.EventSource+"#orders" || e.Subject != "partition:3#42" || e.Type != RecordEventType || !e.Time.Equal(timestamp) {
+			t.Errorf("%s: got event %+v", tt.name, e)
+		}
+		if string(e.Data) != string(tt.record.Value) || e.DataContentType != tt.contentType {
+			t.Errorf("%s: got data %q of type %s", tt.name, e.Data, e.DataContentType)
+		}
+		if len(e.Extensions) != len(tt.extensions) {
+			t.Errorf("%s: got extensions %v", tt.name, e.Extensions)
+		}
+		for name, value := range tt.extensions {
+			if e.Extensions[name] != value {
+				t.Errorf("%s: got extension %s %q, want %q", tt.name, name, e.Extensions[name], value)
+			}
+		}
+	}
+}
+
+// commit is an offset commit of the partition 0 of the orders topic
+type commit struct {
+	offset int64
+	// attempts is how many deliveries were attempted before the commit
+	attempts int
+}
+
+func TestRunSkipsUndeliveredRecords(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sink := sourcetest.NewSink()
+	var (
+		mu      sync.Mutex
+		commits []commit
+	)
+	cluster.ControlKey(int16(kmsg.OffsetCommit), func(req kmsg.Request) (kmsg.Response, error, bool) {
+		cluster.KeepControl()
+		for _, topic := range req.(*kmsg.OffsetCommitRequest).Topics {
+			for _, p := range topic.Partitions {
+				if topic.Topic == "orders" && p.Partition == 0 {
+					mu.Lock()
+					commits = append(commits, commit{offset: p.Offset, attempts: sink.Attempts()})
+					mu.Unlock()
+				}
+			}
+		}
+		return nil, nil, false
+	})
+	committed := func() []commit {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]commit(nil), commits...)
+	}
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.DefaultProduceTopic("orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	produce := func(value string) {
+		if err := producer.ProduceSync(context.Background(), &kgo.Record{Value: []byte(value)}).FirstErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first record fails every attempt
+	sink.Fail(3)
+	produce(`{"id":1}`)
+	sink.Run(t, &Source{
+		Brokers:     cluster.ListenAddrs(),
+		Topics:      []string{"orders"},
+		Group:       "default-orders",
+		Earliest:    true,
+		Retry:       source.Retry{Attempts: 3, Backoff: time.Millisecond},
+		EventSource: "/orders",
+	})
+
+	// it is skipped, and its offset committed once its retries were exhausted
+	deadline := time.Now().Add(sourcetest.Timeout)
+	for len(committed()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c := committed(); len(c) != 1 || c[0].offset != 1 || c[0].attempts != 3 {
+		t.Fatalf("got commits %+v, want offset 1 committed after 3 attempts", c)
+	}
+
+	// the next record is delivered
+	produce(`{"id":2}`)
+	if e := sink.Next(t); e.Subject != "partition:0#1" || string(e.Data) != `{"id":2}` {
+		t.Errorf("got event %+v", e)
+	}
+	deadline = time.Now().Add(sourcetest.Timeout)
+	for len(committed()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c := committed(); len(c) != 2 || c[1].offset != 2 || c[1].attempts != 4 {
+		t.Errorf("got commits %+v, want offset 2 committed after 4 attempts", c)
+	}
+}
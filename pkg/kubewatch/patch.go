@@ -0,0 +1,79 @@
+package kubewatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Operation is an operation of a JSON patch
+// See https://www.rfc-editor.org/rfc/rfc6902
+type Operation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON encodes the operation, with a value unless it removes one - values can be null
+func (o Operation) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"op":   o.Op,
+		"path": o.Path,
+	}
+	if o.Op != "remove" {
+		m["value"] = o.Value
+	}
+
+	return json.Marshal(m)
+}
+
+// Diff returns the JSON patch turning the JSON value from into to. Objects are compared
+// member by member, anything else is replaced as a whole when it changed.
+func Diff(from, to interface{}) []Operation {
+	return diff("", from, to, []Operation{})
+}
+
+func diff(path string, from, to interface{}, ops []Operation) []Operation {
+	fromObject, fromOK := from.(map[string]interface{})
+	toObject, toOK := to.(map[string]interface{})
+	if !fromOK || !toOK {
+		if !reflect.DeepEqual(from, to) {
+			ops = append(ops, Operation{Op: "replace", Path: path, Value: to})
+		}
+		return ops
+	}
+
+	// members are visited in order, so that the patch of a change is always the same
+	for _, key := range sortedKeys(fromObject) {
+		if _, ok := toObject[key]; !ok {
+			ops = append(ops, Operation{Op: "remove", Path: path + "/" + escapePointer(key)})
+		}
+	}
+	for _, key := range sortedKeys(toObject) {
+		value, ok := fromObject[key]
+		if !ok {
+			ops = append(ops, Operation{Op: "add", Path: path + "/" + escapePointer(key), Value: toObject[key]})
+			continue
+		}
+		ops = diff(path+"/"+escapePointer(key), value, toObject[key], ops)
+	}
+
+	return ops
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escapePointer escapes a member name in a JSON pointer
+// See https://www.rfc-editor.org/rfc/rfc6901#section-3
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,107 @@
+package kubewatch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "equal",
+			from: `{"a":1,"b":{"c":[1,2]}}`,
+			to:   `{"a":1,"b":{"c":[1,2]}}`,
+			want: `[]`,
+		},
+		{
+			name: "add",
+			from: `{"a":1}`,
+			to:   `{"a":1,"b":{"c":true}}`,
+			want: `[{"op":"add","path":"/b","value":{"c":true}}]`,
+		},
+		{
+			name: "remove",
+			from: `{"a":1,"b":2}`,
+			to:   `{"b":2}`,
+			want: `[{"op":"remove","path":"/a"}]`,
+		},
+		{
+			name: "replace nested member",
+			from: `{"metadata":{"labels":{"app":"api","tier":"backend"}}}`,
+			to:   `{"metadata":{"labels":{"app":"web","tier":"backend"}}}`,
+			want: `[{"op":"replace","path":"/metadata/labels/app","value":"web"}]`,
+		},
+		{
+			name: "replace with null",
+			from: `{"a":1}`,
+			to:   `{"a":null}`,
+			want: `[{"op":"replace","path":"/a","value":null}]`,
+		},
+		{
+			name: "arrays are replaced as a whole",
+			from: `{"a":[1,2,3]}`,
+			to:   `{"a":[1,3]}`,
+			want: `[{"op":"replace","path":"/a","value":[1,3]}]`,
+		},
+		{
+			name: "members are removed, then added or replaced in order",
+			from: `{"d":1,"b":1,"a":1}`,
+			to:   `{"c":1,"b":2,"a":1}`,
+			want: `[{"op":"remove","path":"/d"},{"op":"replace","path":"/b","value":2},{"op":"add","path":"/c","value":1}]`,
+		},
+		{
+			name: "escaped member names",
+			from: `{"metadata":{"annotations":{}}}`,
+			to:   `{"metadata":{"annotations":{"example.com/owner~team":"events"}}}`,
+			want: `[{"op":"add","path":"/metadata/annotations/example.com~1owner~0team","value":"events"}]`,
+		},
+		{
+			name: "the root is replaced when it is not an object",
+			from: `[1]`,
+			to:   `{"a":1}`,
+			want: `[{"op":"replace","path":"","value":{"a":1}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to interface{}
+			if err := json.Unmarshal([]byte(tt.from), &from); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.to), &to); err != nil {
+				t.Fatal(err)
+			}
+
+			patch, err := json.Marshal(Diff(from, to))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(patch) != tt.want {
+				t.Errorf("got patch %s, want %s", patch, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapePointer(t *testing.T) {
+	tests := map[string]string{
+		"app":                    "app",
+		"example.com/owner":      "example.com~1owner",
+		"a~b":                    "a~0b",
+		"~1":                     "~01",
+		"/~":                     "~1~0",
+		"kubernetes.io/tls~acme": "kubernetes.io~1tls~0acme",
+	}
+
+	for key, want := range tests {
+		if got := escapePointer(key); got != want {
+			t.Errorf("escapePointer(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
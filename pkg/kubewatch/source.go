@@ -0,0 +1,211 @@
+// Package kubewatch implements the source of the kubernetes provider: it watches the objects
+// of any resource with a dynamic informer, and emits their changes as CloudEvents.
+package kubewatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// EventAdd is the name of the changes creating objects
+	EventAdd = "add"
+	// EventUpdate is the name of the changes updating objects
+	EventUpdate = "update"
+	// EventDelete is the name of the changes deleting objects
+	EventDelete = "delete"
+
+	// EventTypePrefix prefixes the name of the change in the CloudEvents type of events
+	EventTypePrefix = "dev.events-operator.kubernetes."
+	// PatchContentType is the media type of JSON patches
+	PatchContentType = "application/json-patch+json"
+
+	// pendingChanges is how many changes are queued before the informer waits for the sink
+	pendingChanges = 100
+)
+
+// Source emits the changes of the objects of a resource
+type Source struct {
+	// Client is the dynamic client objects are watched with
+	Client dynamic.Interface
+	// Resource is the watched resource
+	Resource schema.GroupVersionResource
+	// Namespace is the namespace watched, all of them when empty
+	Namespace string
+	// LabelSelector selects the watched objects by label
+	LabelSelector string
+	// FieldSelector selects the watched objects by field
+	FieldSelector string
+	// Events are the changes emitted, all of them when empty
+	Events []string
+	// Patch emits updates as the JSON patch from the previous object, instead of the whole object
+	Patch bool
+	// EventSource is the CloudEvents source of events
+	EventSource string
+}
+
+// change is a change of an object
+type change struct {
+	event string
+	old   *unstructured.Unstructured
+	obj   *unstructured.Unstructured
+}
+
+// Run implements source.Source. Objects that exist when the source starts are not emitted,
+// only the changes made afterwards.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	informer := dynamicinformer.NewFilteredDynamicInformer(s.Client, s.Resource, s.Namespace, 0, cache.Indexers{}, func(options *metav1.ListOptions) {
+		options.LabelSelector = s.LabelSelector
+		options.FieldSelector = s.FieldSelector
+	}).Informer()
+
+	// the informer retries forever, stop when the resource cannot be watched instead
+	failed := make(chan error, 1)
+	informer.SetWatchErrorHandlerWithContext(func(ctx context.Context, r *cache.Reflector, err error) {
+		if errors.IsForbidden(err) || errors.IsNotFound(err) || errors.IsBadRequest(err) {
+			select {
+			case failed <- fmt.Errorf("cannot watch %s: %v", s.Resource, err):
+			default:
+			}
+			return
+		}
+		cache.DefaultWatchErrorHandler(ctx, r, err)
+	})
+
+	changes := make(chan change, pendingChanges)
+	send := func(c change) {
+		if !s.accepts(c.event) {
+			return
+		}
+		select {
+		case changes <- c:
+		case <-ctx.Done():
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if u, ok := obj.(*unstructured.Unstructured); ok && !isInInitialList {
+				send(change{event: EventAdd, obj: u})
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, ok := oldObj.(*unstructured.Unstructured)
+			u, newOK := newObj.(*unstructured.Unstructured)
+			// relists notify updates of objects that did not change
+			if ok && newOK && old.GetResourceVersion() != u.GetResourceVersion() {
+				send(change{event: EventUpdate, old: old, obj: u})
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				send(change{event: EventDelete, obj: u})
+			}
+		},
+	})
+
+	go informer.Run(ctx.Done())
+	go func() {
+		if cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			sink.Ready()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-failed:
+			return err
+		case c := <-changes:
+			e, err := s.event(c)
+			if err != nil {
+				glog.Errorf("cannot emit %s of %s: %v", c.event, s.objectPath(c.obj), err)
+				continue
+			}
+			if err := source.DefaultRetry.Deliver(ctx, sink, e); err != nil && ctx.Err() == nil {
+				glog.Errorf("dropping %s of %s: %v", c.event, e.Subject, err)
+			}
+		}
+	}
+}
+
+// accepts returns true when the changes named event are emitted
+func (s *Source) accepts(event string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// event returns the CloudEvent of a change. Its data is the object, without its managed fields,
+// or the JSON patch from the previous object for updates when patches are emitted.
+func (s *Source) event(c change) (cloudevents.Event, error) {
+	id := fmt.Sprintf("%s:%s:%s", c.event, c.obj.GetUID(), c.obj.GetResourceVersion())
+	e := cloudevents.New(id, s.EventSource, EventTypePrefix+c.event)
+	e.Subject = s.objectPath(c.obj)
+	e.Time = time.Now()
+
+	obj := withoutManagedFields(c.obj)
+	var data interface{} = obj.Object
+	e.DataContentType = "application/json"
+	if s.Patch && c.event == EventUpdate {
+		data = Diff(withoutManagedFields(c.old).Object, obj.Object)
+		e.DataContentType = PatchContentType
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return e, fmt.Errorf("cannot encode data: %v", err)
+	}
+	e.Data = b
+
+	return e, nil
+}
+
+// objectPath returns the path of an object in the API
+func (s *Source) objectPath(obj *unstructured.Unstructured) string {
+	p := path.Join("/apis", s.Resource.Group, s.Resource.Version)
+	if s.Resource.Group == "" {
+		p = path.Join("/api", s.Resource.Version)
+	}
+	if obj.GetNamespace() != "" {
+		p = path.Join(p, "namespaces", obj.GetNamespace())
+	}
+
+	return path.Join(p, s.Resource.Resource, obj.GetName())
+}
+
+// withoutManagedFields returns a copy of an object without its managed fields, which only add noise to events
+func withoutManagedFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+
+	return obj
+}
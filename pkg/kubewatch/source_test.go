@@ -0,0 +1,153 @@
+package kubewatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// configMaps is the resource watched by the tests
+var configMaps = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// newTestClient returns a fake dynamic client serving configmaps
+func newTestClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{configMaps: "ConfigMapList"}, objects...)
+}
+
+// configMap returns a configmap at resourceVersion, with data
+func configMap(namespace, name, resourceVersion string, data map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"data": data}}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetUID(types.UID("uid-" + name))
+	obj.SetResourceVersion(resourceVersion)
+
+	return obj
+}
+
+// runTestSource runs s until the test ends, and waits for it to watch, as changes
+// made before are not sent by the fake client
+func runTestSource(t *testing.T, client *dynamicfake.FakeDynamicClient, s *Source) *sourcetest.Sink {
+	sink := sourcetest.NewSink()
+	sink.Run(t, s)
+
+	deadline := time.Now().Add(sourcetest.Timeout)
+	for time.Now().Before(deadline) {
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "watch" {
+				return sink
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the source did not watch after %s", sourcetest.Timeout)
+
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	client := newTestClient(configMap("default", "existing", "1", nil))
+	s := &Source{Client: client, Resource: configMaps, Namespace: "default", EventSource: "/kubernetes"}
+	sink := runTestSource(t, client, s)
+
+	// the objects listed when the source starts are not emitted
+	sink.None(t, 100*time.Millisecond)
+
+	settings := client.Resource(configMaps).Namespace("default")
+	if _, err := settings.Create(context.TODO(), configMap("default", "settings", "2", map[string]interface{}{"color": "red"}), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	e := sink.Next(t)
+	if e.Type != EventTypePrefix+EventAdd || e.Subject != "/api/v1/namespaces/default/configmaps/settings" || e.ID != "add:uid-settings:2" || e.DataContentType != "application/json" {
+		t.Errorf("got event %+v", e)
+	}
+
+	// other namespaces are not watched
+	if _, err := client.Resource(configMaps).Namespace("other").Create(context.TODO(), configMap("other", "settings", "3", nil), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// resyncs notify updates of objects that did not change
+	if _, err := settings.Update(context.TODO(), configMap("default", "settings", "2", map[string]interface{}{"color": "red"}), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sink.None(t, 100*time.Millisecond)
+
+	if _, err := settings.Update(context.TODO(), configMap("default", "settings", "4", map[string]interface{}{"color": "blue"}), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if e := sink.Next(t); e.Type != EventTypePrefix+EventUpdate || e.ID != "update:uid-settings:4" {
+		t.Errorf("got event %+v", e)
+	}
+
+	if err := settings.Delete(context.TODO(), "settings", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if e := sink.Next(t); e.Type != EventTypePrefix+EventDelete || e.Subject != "/api/v1/namespaces/default/configmaps/settings" {
+		t.Errorf("got event %+v", e)
+	}
+}
+
+func TestRunFilters(t *testing.T) {
+	client := newTestClient()
+	s := &Source{
+		Client:        client,
+		Resource:      configMaps,
+		Namespace:     "default",
+		LabelSelector: "app=web",
+		FieldSelector: "metadata.name=settings",
+		Events:        []string{EventUpdate},
+		Patch:         true,
+		EventSource:   "/kubernetes",
+	}
+	sink := runTestSource(t, client, s)
+
+	// the selectors are passed to the API server
+	for _, action := range client.Actions() {
+		var labels, fields string
+		switch action := action.(type) {
+		case k8stesting.ListAction:
+			labels, fields = action.GetListRestrictions().Labels.String(), action.GetListRestrictions().Fields.String()
+		case k8stesting.WatchAction:
+			labels, fields = action.GetWatchRestrictions().Labels.String(), action.GetWatchRestrictions().Fields.String()
+		default:
+			continue
+		}
+		if labels != "app=web" || fields != "metadata.name=settings" {
+			t.Errorf("%s: got selectors %q and %q", action.GetVerb(), labels, fields)
+		}
+	}
+
+	// only updates are emitted
+	settings := client.Resource(configMaps).Namespace("default")
+	if _, err := settings.Create(context.TODO(), configMap("default", "settings", "1", map[string]interface{}{"color": "red"}), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sink.None(t, 100*time.Millisecond)
+
+	// as the JSON patch from the previous object
+	if _, err := settings.Update(context.TODO(), configMap("default", "settings", "2", map[string]interface{}{"color": "blue"}), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	e := sink.Next(t)
+	want := `[{"op":"replace","path":"/data/color","value":"blue"},{"op":"replace","path":"/metadata/resourceVersion","value":"2"}]`
+	if e.Type != EventTypePrefix+EventUpdate || e.DataContentType != PatchContentType || string(e.Data) != want {
+		t.Errorf("got event %+v with data %s", e, e.Data)
+	}
+
+	if err := settings.Delete(context.TODO(), "settings", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sink.None(t, 100*time.Millisecond)
+}
@@ -0,0 +1,152 @@
+// Package mqtt implements the source of the mqtt provider: it subscribes to topic filters
+// of an MQTT broker and emits the messages as CloudEvents whose subject is their topic.
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+const (
+	// MessageEventType is the CloudEvents type of MQTT messages
+	MessageEventType = "dev.events-operator.mqtt.message"
+
+	// connectTimeout is how long connecting to the broker and subscribing may take
+	connectTimeout = 30 * time.Second
+)
+
+// Source subscribes to MQTT topic filters. The session is persistent: the broker keeps the
+// subscriptions and the unacknowledged messages of the client ID while it is disconnected,
+// and redelivers them when the source connects again.
+type Source struct {
+	// Brokers are the URLs of the brokers, with a tcp, ssl, ws or wss scheme
+	Brokers []string
+	// ClientID identifies the session on the broker
+	ClientID string
+	// Topics are the topic filters subscribed to, with + and # wildcards
+	Topics []string
+	// QoS is the quality of service of the subscriptions
+	QoS byte
+	// Username authenticates with the broker when not empty
+	Username string
+	// Password authenticates with the broker along with Username
+	Password string
+	// TLS is the TLS configuration of ssl and wss brokers, and their client certificate
+	TLS *tls.Config
+	// Retry is how the delivery of messages is retried, before the source reconnects
+	// and the broker redelivers them
+	Retry source.Retry
+	// EventSource is the CloudEvents source of events
+	EventSource string
+}
+
+// Run implements source.Source. Messages are acknowledged once they were delivered. When a message
+// cannot be delivered, or the connection is lost, Run returns an error: the source is started again
+// after a backoff and resumes its session, so that unacknowledged messages are delivered again.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	failed := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	}
+
+	handler := func(_ paho.Client, m paho.Message) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		e, err := s.event(m)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if err := s.Retry.Deliver(ctx, sink, e); err != nil {
+			if ctx.Err() == nil {
+				fail(fmt.Errorf("cannot deliver MQTT message on %s: %v", m.Topic(), err))
+			}
+			return
+		}
+		m.Ack()
+	}
+
+	options := paho.NewClientOptions().
+		SetClientID(s.ClientID).
+		SetCleanSession(false).
+		// reconnections go through the backoff of the source
+		SetAutoReconnect(false).
+		SetConnectTimeout(connectTimeout).
+		// messages are handled one after the other, and acknowledged once delivered
+		SetOrderMatters(true).
+		SetAutoAckDisabled(true).
+		// messages of the resumed session may arrive before the subscriptions are made
+		SetDefaultPublishHandler(handler).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			fail(fmt.Errorf("lost the connection to the MQTT broker: %v", err))
+		})
+	for _, broker := range s.Brokers {
+		options.AddBroker(broker)
+	}
+	if s.Username != "" {
+		options.SetUsername(s.Username).SetPassword(s.Password)
+	}
+	if s.TLS != nil {
+		options.SetTLSConfig(s.TLS)
+	}
+
+	client := paho.NewClient(options)
+	if err := wait(ctx, client.Connect()); err != nil {
+		return fmt.Errorf("cannot connect to the MQTT broker: %v", err)
+	}
+	defer client.Disconnect(250)
+
+	filters := map[string]byte{}
+	for _, topic := range s.Topics {
+		filters[topic] = s.QoS
+	}
+	if err := wait(ctx, client.SubscribeMultiple(filters, handler)); err != nil {
+		return fmt.Errorf("cannot subscribe to %v: %v", s.Topics, err)
+	}
+	sink.Ready()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-failed:
+		return err
+	}
+}
+
+// wait waits for an MQTT operation to complete
+func wait(ctx context.Context, t paho.Token) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.Done():
+		return t.Error()
+	case <-time.After(connectTimeout):
+		return fmt.Errorf("timed out")
+	}
+}
+
+// event returns the CloudEvent of a message, whose subject is the topic and data the payload
+func (s *Source) event(m paho.Message) (cloudevents.Event, error) {
+	id, err := cloudevents.NewID()
+	if err != nil {
+		return cloudevents.Event{}, fmt.Errorf("cannot generate event id: %v", err)
+	}
+
+	e := cloudevents.New(id, s.EventSource, MessageEventType)
+	e.Subject = m.Topic()
+	e.Time = time.Now()
+	e.SetData("", m.Payload())
+
+	return e, nil
+}
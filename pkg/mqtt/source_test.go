@@ -0,0 +1,169 @@
+package mqtt
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+)
+
+// sessions is a hook of the broker reporting the client IDs of the sessions established
+type sessions struct {
+	mochi.HookBase
+	established chan string
+}
+
+// ID implements mochi.Hook
+func (h *sessions) ID() string {
+	return "sessions"
+}
+
+// Provides implements mochi.Hook
+func (h *sessions) Provides(b byte) bool {
+	return b == mochi.OnSessionEstablished
+}
+
+// OnSessionEstablished implements mochi.Hook
+func (h *sessions) OnSessionEstablished(cl *mochi.Client, _ packets.Packet) {
+	select {
+	case h.established <- cl.ID:
+	default:
+	}
+}
+
+// newBroker runs an embedded MQTT broker until the test ends, and returns it with its URL
+// and the client IDs of the sessions established
+func newBroker(t *testing.T) (*mochi.Server, string, <-chan string) {
+	broker := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	hook := &sessions{established: make(chan string, 10)}
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.AddHook(hook, nil); err != nil {
+		t.Fatal(err)
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	return broker, "tcp://" + tcp.Address(), hook.established
+}
+
+// waitConnected waits for the source to establish its session, and returns its client ID
+func waitConnected(t *testing.T, established <-chan string) string {
+	t.Helper()
+
+	select {
+	case clientID := <-established:
+		return clientID
+	case <-time.After(sourcetest.Timeout):
+		t.Fatalf("the source did not connect")
+		return ""
+	}
+}
+
+// waitInflight waits until the broker has n messages in flight, not acknowledged by the client
+func waitInflight(t *testing.T, broker *mochi.Server, clientID string, n int) {
+	t.Helper()
+
+	inflight := func() int {
+		cl, ok := broker.Clients.Get(clientID)
+		if !ok {
+			return -1
+		}
+		return cl.State.Inflight.Len()
+	}
+
+	deadline := time.Now().Add(sourcetest.Timeout)
+	for inflight() != n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := inflight(); got != n {
+		t.Fatalf("got %d messages in flight, want %d", got, n)
+	}
+}
+
+// publish publishes a QoS 1 message through the inline client of the broker
+func publish(t *testing.T, broker *mochi.Server, topic, payload string) {
+	if err := broker.Publish(topic, []byte(payload), false, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSource(t *testing.T) {
+	broker, url, established := newBroker(t)
+	src := &Source{
+		Brokers:     []string{url},
+		ClientID:    "events-operator-default-sensors",
+		Topics:      []string{"sensors/+/temperature"},
+		QoS:         1,
+		Retry:       source.Retry{Attempts: 1},
+		EventSource: "/mqtt",
+	}
+
+	sink := sourcetest.NewSink()
+	done := sink.Run(t, src)
+	if clientID := waitConnected(t, established); clientID != src.ClientID {
+		t.Errorf("got client ID %s", clientID)
+	}
+
+	publish(t, broker, "sensors/kitchen/temperature", `{"celsius":21}`)
+	e := sink.Next(t)
+	if e.ID == "" || e.Subject != "sensors/kitchen/temperature" || e.Source != "/mqtt" || e.Type != MessageEventType {
+		t.Errorf("got event %+v", e)
+	}
+	if string(e.Data) != `{"celsius":21}` || e.DataContentType != "application/json" {
+		t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+	}
+
+	// messages are acknowledged once delivered, and topics not subscribed to are ignored
+	waitInflight(t, broker, src.ClientID, 0)
+	publish(t, broker, "sensors/kitchen/humidity", "40")
+	sink.None(t, 100*time.Millisecond)
+
+	// a message that cannot be delivered is not acknowledged, and the source fails
+	sink.Fail(1)
+	publish(t, broker, "sensors/garage/temperature", "12")
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("the source did not fail")
+		}
+	case <-time.After(sourcetest.Timeout):
+		t.Fatalf("the source did not fail")
+	}
+	waitInflight(t, broker, src.ClientID, 1)
+
+	// the broker keeps the session while the source is disconnected
+	publish(t, broker, "sensors/attic/temperature", "9")
+	waitInflight(t, broker, src.ClientID, 2)
+
+	// the session is resumed when the source is started again, and the messages delivered again
+	sink.Run(t, src)
+	waitConnected(t, established)
+	// the broker resends the messages in flight in no particular order
+	redelivered := map[string]string{}
+	for i := 0; i < 2; i++ {
+		e := sink.Next(t)
+		redelivered[e.Subject] = string(e.Data)
+	}
+	if len(redelivered) != 2 || redelivered["sensors/garage/temperature"] != "12" || redelivered["sensors/attic/temperature"] != "9" {
+		t.Errorf("got redelivered messages %v", redelivered)
+	}
+	waitInflight(t, broker, src.ClientID, 0)
+}
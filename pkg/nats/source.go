@@ -0,0 +1,238 @@
+// Package nats implements the source of the nats provider: it subscribes to core NATS subjects,
+// or consumes a durable JetStream consumer, and emits the messages as CloudEvents.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+const (
+	// MessageEventType is the CloudEvents type of NATS messages
+	MessageEventType = "dev.events-operator.nats.message"
+
+	// pendingMessages is how many core NATS messages are buffered before the
+	// server considers the subscription a slow consumer and drops messages
+	pendingMessages = 1024
+)
+
+// Source subscribes to NATS subjects
+type Source struct {
+	// URL is the comma separated list of the URLs of the servers
+	URL string
+	// Options are the connection options, such as credentials and TLS
+	Options []nats.Option
+	// Subjects are the subjects subscribed to, with wildcards
+	Subjects []string
+	// QueueGroup shares the messages of core NATS subjects between the members of the group
+	QueueGroup string
+	// JetStream consumes a durable JetStream consumer, instead of core NATS subjects, when not nil
+	JetStream *JetStream
+	// Retry is how the delivery of messages is retried, before they are given up on
+	Retry source.Retry
+	// EventSource is the CloudEvents source of events
+	EventSource string
+}
+
+// JetStream is the durable consumer of a stream
+type JetStream struct {
+	// Stream is the name of the stream
+	Stream string
+	// Consumer is the name of the durable consumer, created or updated when the source starts
+	Consumer string
+	// DeliverPolicy is where a new consumer starts in the stream
+	DeliverPolicy jetstream.DeliverPolicy
+	// AckWait is how long the server waits for an ack before it redelivers a message
+	AckWait time.Duration
+	// MaxDeliver is how many times a message is delivered before the server gives up on it, unlimited when zero
+	MaxDeliver int
+}
+
+// Run implements source.Source
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	closed := make(chan struct{})
+	options := append([]nats.Option{
+		nats.Name("events-operator"),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2 * time.Second),
+		nats.ClosedHandler(func(*nats.Conn) { close(closed) }),
+	}, s.Options...)
+
+	conn, err := nats.Connect(s.URL, options...)
+	if err != nil {
+		return fmt.Errorf("cannot connect to NATS: %v", err)
+	}
+	defer conn.Close()
+
+	if s.JetStream != nil {
+		return s.consume(ctx, conn, sink)
+	}
+
+	return s.subscribe(ctx, conn, closed, sink)
+}
+
+// subscribe delivers the messages of core NATS subjects. Core NATS does not acknowledge messages,
+// so a message is dropped when it cannot be delivered after the retries.
+func (s *Source) subscribe(ctx context.Context, conn *nats.Conn, closed <-chan struct{}, sink source.Sink) error {
+	messages := make(chan *nats.Msg, pendingMessages)
+	for _, subject := range s.Subjects {
+		subscription, err := conn.ChanQueueSubscribe(subject, s.QueueGroup, messages)
+		if err != nil {
+			return fmt.Errorf("cannot subscribe to %s: %v", subject, err)
+		}
+		defer subscription.Unsubscribe()
+	}
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("cannot subscribe: %v", err)
+	}
+	sink.Ready()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-closed:
+			return fmt.Errorf("NATS connection closed: %v", conn.LastError())
+		case m := <-messages:
+			id := m.Header.Get(nats.MsgIdHdr)
+			if id == "" {
+				id = nuid.Next()
+			}
+
+			e := s.event(id, m.Subject, time.Now(), m.Data, m.Header)
+			if err := s.Retry.Deliver(ctx, sink, e); err != nil && ctx.Err() == nil {
+				glog.Errorf("dropping NATS message %s on %s: %v", e.ID, m.Subject, err)
+			}
+		}
+	}
+}
+
+// consume delivers the messages of a durable JetStream consumer. Messages are acked once they
+// were delivered, and nacked otherwise so that the server redelivers them, up to MaxDeliver times.
+func (s *Source) consume(ctx context.Context, conn *nats.Conn, sink source.Sink) error {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return fmt.Errorf("cannot use JetStream: %v", err)
+	}
+
+	config := jetstream.ConsumerConfig{
+		Durable:       s.JetStream.Consumer,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: s.JetStream.DeliverPolicy,
+		AckWait:       s.JetStream.AckWait,
+		MaxDeliver:    s.JetStream.MaxDeliver,
+	}
+	if len(s.Subjects) == 1 {
+		config.FilterSubject = s.Subjects[0]
+	} else {
+		config.FilterSubjects = s.Subjects
+	}
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, s.JetStream.Stream, config)
+	if err != nil {
+		return fmt.Errorf("cannot create JetStream consumer %s of stream %s: %v", s.JetStream.Consumer, s.JetStream.Stream, err)
+	}
+	ackWait := consumer.CachedInfo().Config.AckWait
+
+	messages, err := consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("cannot consume JetStream consumer %s: %v", s.JetStream.Consumer, err)
+	}
+	defer messages.Stop()
+	go func() {
+		<-ctx.Done()
+		messages.Stop()
+	}()
+	sink.Ready()
+
+	for {
+		m, err := messages.Next()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return nil
+			}
+			return fmt.Errorf("cannot get JetStream message: %v", err)
+		}
+
+		metadata, err := m.Metadata()
+		if err != nil {
+			glog.Errorf("terminating JetStream message on %s without metadata: %v", m.Subject(), err)
+			m.Term()
+			continue
+		}
+
+		id := fmt.Sprintf("%s-%d", s.JetStream.Stream, metadata.Sequence.Stream)
+		e := s.event(id, m.Subject(), metadata.Timestamp, m.Data(), m.Headers())
+		if err := s.deliver(ctx, sink, m, ackWait, e); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			glog.Errorf("cannot deliver JetStream message %s (delivery %d): %v", e.ID, metadata.NumDelivered, err)
+			m.Nak()
+			continue
+		}
+		if err := m.Ack(); err != nil {
+			glog.Errorf("cannot ack JetStream message %s: %v", e.ID, err)
+		}
+	}
+}
+
+// deliver delivers the event of a JetStream message, telling the server the message is still in
+// progress while the delivery is retried, so that it is not redelivered in the meantime
+func (s *Source) deliver(ctx context.Context, sink source.Sink, m jetstream.Msg, ackWait time.Duration, e cloudevents.Event) error {
+	if ackWait <= 0 {
+		return s.Retry.Deliver(ctx, sink, e)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(ackWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.InProgress()
+			}
+		}
+	}()
+
+	return s.Retry.Deliver(ctx, sink, e)
+}
+
+// event returns the CloudEvent of a message. Its subject is the NATS subject, its data the message
+// data, of the type of the Content-Type header, and the other headers are extensions.
+func (s *Source) event(id, subject string, t time.Time, data []byte, header nats.Header) cloudevents.Event {
+	e := cloudevents.New(id, s.EventSource, MessageEventType)
+	e.Subject = subject
+	e.Time = t
+
+	var contentType string
+	headers := map[string]string{}
+	for name, values := range header {
+		if len(values) == 0 {
+			continue
+		}
+		if strings.EqualFold(name, "Content-Type") {
+			contentType = values[0]
+			continue
+		}
+		headers[name] = values[0]
+	}
+	e.SetData(contentType, data)
+	e.SetExtensions(headers)
+
+	return e
+}
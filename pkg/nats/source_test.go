@@ -0,0 +1,177 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+)
+
+// newServer runs an embedded NATS server with JetStream until the test ends
+func newServer(t *testing.T) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := natstest.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+
+	return srv
+}
+
+// connect returns a client of the server, closed when the test ends
+func connect(t *testing.T, srv *server.Server) *nats.Conn {
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+// publish publishes a message through a client of the server
+func publish(t *testing.T, srv *server.Server, m *nats.Msg) {
+	conn := connect(t, srv)
+	if err := conn.PublishMsg(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor fails the test when condition is not true in time
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(sourcetest.Timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: timed out after %s", what, sourcetest.Timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	srv := newServer(t)
+	sink := sourcetest.NewSink()
+	sink.Run(t, &Source{
+		URL:         srv.ClientURL(),
+		Subjects:    []string{"orders.>", "payments.*"},
+		QueueGroup:  "events-operator",
+		Retry:       source.Retry{Attempts: 2, Backoff: time.Millisecond},
+		EventSource: "/nats",
+	})
+
+	header := nats.Header{}
+	header.Set(nats.MsgIdHdr, "order-1")
+	header.Set("Content-Type", "application/json")
+	header.Set("Tenant", "acme")
+	publish(t, srv, &nats.Msg{Subject: "orders.eu.created", Header: header, Data: []byte(`{"id":1}`)})
+
+	e := sink.Next(t)
+	if e.ID != "order-1" || e.Subject != "orders.eu.created" || e.Source != "/nats" || e.Type != MessageEventType {
+		t.Errorf("got event %+v", e)
+	}
+	if string(e.Data) != `{"id":1}` || e.DataContentType != "application/json" {
+		t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+	}
+	if len(e.Extensions) != 1 || e.Extensions["tenant"] != "acme" {
+		t.Errorf("got extensions %v", e.Extensions)
+	}
+
+	// messages without headers get a generated ID, and subjects not subscribed to are ignored
+	publish(t, srv, &nats.Msg{Subject: "payments.eu.created", Data: []byte("ignored")})
+	publish(t, srv, &nats.Msg{Subject: "payments.created", Data: []byte("paid")})
+	if e := sink.Next(t); e.ID == "" || e.Subject != "payments.created" || string(e.Data) != "paid" {
+		t.Errorf("got event %+v", e)
+	}
+
+	// core NATS messages are dropped once the retries are exhausted
+	sink.Fail(2)
+	publish(t, srv, &nats.Msg{Subject: "orders.dropped", Data: []byte("dropped")})
+	publish(t, srv, &nats.Msg{Subject: "orders.delivered", Data: []byte("delivered")})
+	if e := sink.Next(t); e.Subject != "orders.delivered" || sink.Attempts() != 5 {
+		t.Errorf("got event %+v after %d attempts", e, sink.Attempts())
+	}
+}
+
+func TestConsume(t *testing.T) {
+	srv := newServer(t)
+	js, err := jetstream.New(connect(t, srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishJS := func(subject, data string) {
+		if _, err := js.Publish(ctx, subject, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink := sourcetest.NewSink()
+	sink.Run(t, &Source{
+		URL:      srv.ClientURL(),
+		Subjects: []string{"orders.>"},
+		JetStream: &JetStream{
+			Stream:        "ORDERS",
+			Consumer:      "events-operator",
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			AckWait:       time.Minute,
+			MaxDeliver:    3,
+		},
+		Retry:       source.Retry{Attempts: 1},
+		EventSource: "/nats",
+	})
+	consumer, err := stream.Consumer(ctx, "events-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// acked returns true once the server got the ack of the stream sequence seq
+	acked := func(seq uint64) func() bool {
+		return func() bool {
+			info, err := consumer.Info(ctx)
+			return err == nil && info.AckFloor.Stream >= seq && info.NumAckPending == 0
+		}
+	}
+
+	// delivered messages are acked
+	publishJS("orders.created", `{"id":1}`)
+	if e := sink.Next(t); e.ID != "ORDERS-1" || e.Subject != "orders.created" || string(e.Data) != `{"id":1}` {
+		t.Errorf("got event %+v", e)
+	}
+	waitFor(t, "ack", acked(1))
+
+	// failed deliveries are nacked, and redelivered by the server
+	sink.Fail(1)
+	publishJS("orders.created", `{"id":2}`)
+	if e := sink.Next(t); e.ID != "ORDERS-2" || sink.Attempts() != 3 {
+		t.Errorf("got event %+v after %d attempts", e, sink.Attempts())
+	}
+	waitFor(t, "ack after a nak", acked(2))
+
+	// the server gives up on messages delivered MaxDeliver times
+	sink.Fail(3)
+	publishJS("orders.created", `{"id":3}`)
+	waitFor(t, "MaxDeliver deliveries", func() bool { return sink.Attempts() == 6 })
+	publishJS("orders.created", `{"id":4}`)
+	if e := sink.Next(t); e.ID != "ORDERS-4" {
+		t.Errorf("got event %+v", e)
+	}
+	sink.None(t, 100*time.Millisecond)
+	if attempts := sink.Attempts(); attempts != 7 {
+		t.Errorf("got %d attempts, want the message given up on after 3", attempts)
+	}
+}
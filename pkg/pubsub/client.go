@@ -0,0 +1,164 @@
+// Package pubsub manages Google Cloud Pub/Sub push subscriptions through the
+// Pub/Sub REST API, and verifies the messages they push.
+// See https://cloud.google.com/pubsub/docs/push
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the endpoint of the Pub/Sub REST API
+const DefaultEndpoint = "https://pubsub.googleapis.com"
+
+// Subscription is a Pub/Sub subscription
+type Subscription struct {
+	// Name is projects/<project>/subscriptions/<name>
+	Name string `json:"name,omitempty"`
+	// Topic is projects/<project>/topics/<name>
+	Topic              string     `json:"topic"`
+	PushConfig         PushConfig `json:"pushConfig"`
+	AckDeadlineSeconds int32      `json:"ackDeadlineSeconds,omitempty"`
+}
+
+// PushConfig is where and how a subscription pushes its messages
+type PushConfig struct {
+	PushEndpoint string     `json:"pushEndpoint"`
+	OIDCToken    *OIDCToken `json:"oidcToken,omitempty"`
+}
+
+// OIDCToken has pushes authenticated with a JWT of a service account
+type OIDCToken struct {
+	ServiceAccountEmail string `json:"serviceAccountEmail"`
+	Audience            string `json:"audience,omitempty"`
+}
+
+// Error is an error answered by the Pub/Sub API
+type Error struct {
+	StatusCode int
+	Message    string `json:"message"`
+	Status     string `json:"status"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Pub/Sub API answered %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true when err is a 404 answered by the Pub/Sub API
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client calls the Pub/Sub REST API
+type Client struct {
+	endpoint string
+	// tokens is nil for the emulator, which does not authenticate requests
+	tokens *tokenSource
+
+	client *http.Client
+}
+
+// NewClient returns a client authenticated with a service account key. endpoint overrides
+// the Pub/Sub endpoint, for the emulator, in which case key can be nil.
+func NewClient(endpoint string, key *ServiceAccountKey) (*Client, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	c := &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if key != nil {
+		tokens, err := newTokenSource(key)
+		if err != nil {
+			return nil, err
+		}
+		c.tokens = tokens
+	}
+
+	return c, nil
+}
+
+// GetSubscription returns a subscription, by its full name
+func (c *Client) GetSubscription(ctx context.Context, name string) (*Subscription, error) {
+	subscription := &Subscription{}
+	err := c.do(ctx, http.MethodGet, "/v1/"+name, nil, subscription)
+	return subscription, err
+}
+
+// CreateSubscription creates a subscription, named by its full name
+func (c *Client) CreateSubscription(ctx context.Context, subscription Subscription) (*Subscription, error) {
+	created := &Subscription{}
+	err := c.do(ctx, http.MethodPut, "/v1/"+subscription.Name, subscription, created)
+	return created, err
+}
+
+// ModifyPushConfig replaces the push configuration of a subscription
+func (c *Client) ModifyPushConfig(ctx context.Context, name string, pushConfig PushConfig) error {
+	return c.do(ctx, http.MethodPost, "/v1/"+name+":modifyPushConfig", map[string]interface{}{"pushConfig": pushConfig}, nil)
+}
+
+// DeleteSubscription deletes a subscription, by its full name
+func (c *Client) DeleteSubscription(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/"+name, nil, nil)
+}
+
+// do sends a request to the API, encoding in and decoding the response into out
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("cannot encode request: %v", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.endpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("cannot create request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.Token()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error Error `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&apiErr)
+		apiErr.Error.StatusCode = resp.StatusCode
+		return &apiErr.Error
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("cannot decode response: %v", err)
+	}
+
+	return nil
+}
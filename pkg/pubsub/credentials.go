@@ -0,0 +1,106 @@
+package pubsub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// apiAudience is the audience of the self-signed JWTs the Pub/Sub API accepts as access tokens
+const apiAudience = "https://pubsub.googleapis.com/"
+
+// ServiceAccountKey is a JSON key of a Google service account
+type ServiceAccountKey struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+}
+
+// ParseServiceAccountKey decodes a JSON service account key
+func ParseServiceAccountKey(b []byte) (*ServiceAccountKey, error) {
+	key := &ServiceAccountKey{}
+	if err := json.Unmarshal(b, key); err != nil {
+		return nil, fmt.Errorf("cannot decode service account key: %v", err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("not a service account key")
+	}
+
+	return key, nil
+}
+
+// tokenSource signs JWTs with a service account key, which Google APIs accept
+// as access tokens without an OAuth exchange
+// See https://developers.google.com/identity/protocols/oauth2/service-account#jwt-auth
+type tokenSource struct {
+	key     *ServiceAccountKey
+	private *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newTokenSource(key *ServiceAccountKey) (*tokenSource, error) {
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key in the service account key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse service account private key: %v", err)
+	}
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the service account private key is not an RSA key")
+	}
+
+	return &tokenSource{key: key, private: private}, nil
+}
+
+// Token returns a JWT valid for at least 5 more minutes
+func (s *tokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.token != "" && now.Add(5*time.Minute).Before(s.expires) {
+		return s.token, nil
+	}
+
+	header, _ := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": s.key.PrivateKeyID,
+	})
+	expires := now.Add(time.Hour)
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss": s.key.ClientEmail,
+		"sub": s.key.ClientEmail,
+		"aud": apiAudience,
+		"iat": now.Unix(),
+		"exp": expires.Unix(),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.private, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("cannot sign token: %v", err)
+	}
+
+	s.token = unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	s.expires = expires
+
+	return s.token, nil
+}
@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// googleCertsURL serves the keys Google signs the OIDC tokens of pushes with
+const googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// googleKeys is shared by all the verifiers, so the keys are fetched once and refreshed on rotation
+var googleKeys = oidc.NewRemoteKeySet(context.Background(), googleCertsURL)
+
+// PushRequest is the body of a push
+type PushRequest struct {
+	Message      Message `json:"message"`
+	Subscription string  `json:"subscription"`
+}
+
+// Message is a Pub/Sub message
+type Message struct {
+	// Data is the payload of the message, base64 encoded in pushes
+	Data        []byte            `json:"data,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	MessageID   string            `json:"messageId"`
+	PublishTime time.Time         `json:"publishTime"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+// PushVerifier checks pushes carry an OIDC token Google issued for a service account and an audience
+type PushVerifier struct {
+	serviceAccount string
+	verifier       *oidc.IDTokenVerifier
+}
+
+// NewPushVerifier returns a verifier for the tokens of serviceAccount with the given audience
+func NewPushVerifier(serviceAccount, audience string) *PushVerifier {
+	return &PushVerifier{
+		serviceAccount: serviceAccount,
+		// Google issues tokens as both accounts.google.com and https://accounts.google.com
+		verifier: oidc.NewVerifier("https://accounts.google.com", googleKeys, &oidc.Config{
+			ClientID:        audience,
+			SkipIssuerCheck: true,
+		}),
+	}
+}
+
+// Authenticate verifies the OIDC token in the Authorization header of a push
+func (v *PushVerifier) Authenticate(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return fmt.Errorf("missing bearer token")
+	}
+
+	token, err := v.verifier.Verify(r.Context(), strings.TrimPrefix(auth, "Bearer "))
+	if err != nil {
+		return fmt.Errorf("invalid token: %v", err)
+	}
+	if token.Issuer != "accounts.google.com" && token.Issuer != "https://accounts.google.com" {
+		return fmt.Errorf("token issued by %s", token.Issuer)
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := token.Claims(&claims); err != nil {
+		return fmt.Errorf("cannot decode token claims: %v", err)
+	}
+	if !claims.EmailVerified || claims.Email != v.serviceAccount {
+		return fmt.Errorf("token of %s, expected %s", claims.Email, v.serviceAccount)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+// Package redisstreams implements the source of the redis-streams provider: a consumer of a
+// consumer group emitting the entries of a Redis stream as CloudEvents, acknowledging them once
+// they were delivered and reclaiming the entries other consumers left pending.
+package redisstreams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// EntryEventType is the CloudEvents type of stream entries
+	EntryEventType = "dev.events-operator.redis-streams.entry"
+
+	// block is how long a read waits for new entries, before pending entries are reclaimed again
+	block = 5 * time.Second
+)
+
+// Source reads a stream as a consumer of a consumer group
+type Source struct {
+	// Options are the connection options of the Redis server
+	Options *redis.Options
+	// Stream is the key of the stream
+	Stream string
+	// Group is the consumer group, created when it does not exist
+	Group string
+	// Consumer is the name of the consumer in the group
+	Consumer string
+	// Earliest creates the group at the start of the stream, instead of its end
+	Earliest bool
+	// ClaimMinIdle is how long an entry is pending before it is reclaimed from its consumer and delivered again
+	ClaimMinIdle time.Duration
+	// Count is the maximum number of entries read at once
+	Count int64
+	// Retry is how the delivery of entries is retried, before they are left pending
+	Retry source.Retry
+	// MaxDeliveryCount is how many times an entry is delivered before it is given up on, unlimited when zero
+	MaxDeliveryCount int64
+	// DeadLetterStream is the key of the stream the entries given up on are added to. They are dropped when it is empty.
+	DeadLetterStream string
+	// EventSource is the CloudEvents source of events, the stream is appended to it as a fragment
+	EventSource string
+}
+
+// Run implements source.Source. Entries are acknowledged once they were delivered. Entries that could
+// not be delivered are left pending, and reclaimed with XAUTOCLAIM once they were idle for ClaimMinIdle,
+// along with the entries of consumers that went away, so that entries are delivered at least once.
+// Reclaimed entries that were already delivered MaxDeliveryCount times are given up on instead.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	client := redis.NewClient(s.Options)
+	defer client.Close()
+
+	start := "$"
+	if s.Earliest {
+		start = "0"
+	}
+	if err := client.XGroupCreateMkStream(ctx, s.Stream, s.Group, start).Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("cannot create consumer group %s of stream %s: %v", s.Group, s.Stream, err)
+	}
+	sink.Ready()
+
+	var claimed time.Time
+	for ctx.Err() == nil {
+		if time.Since(claimed) >= s.ClaimMinIdle/2 {
+			if err := s.claim(ctx, client, sink); err != nil {
+				return err
+			}
+			claimed = time.Now()
+		}
+
+		streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.Group,
+			Consumer: s.Consumer,
+			Streams:  []string{s.Stream, ">"},
+			Count:    s.Count,
+			Block:    block,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return fmt.Errorf("cannot read stream %s: %v", s.Stream, err)
+		}
+
+		for _, stream := range streams {
+			s.deliver(ctx, client, sink, stream.Messages)
+		}
+	}
+
+	return nil
+}
+
+// claim reclaims and delivers the entries of the group pending for more than ClaimMinIdle
+func (s *Source) claim(ctx context.Context, client *redis.Client, sink source.Sink) error {
+	start := "0-0"
+	for {
+		entries, next, err := client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   s.Stream,
+			Group:    s.Group,
+			Consumer: s.Consumer,
+			MinIdle:  s.ClaimMinIdle,
+			Start:    start,
+			Count:    s.Count,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("cannot claim pending entries of stream %s: %v", s.Stream, err)
+		}
+
+		entries, err = s.giveUp(ctx, client, entries)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		s.deliver(ctx, client, sink, entries)
+		if next == "0-0" || ctx.Err() != nil {
+			return nil
+		}
+		start = next
+	}
+}
+
+// giveUp moves the reclaimed entries that were delivered more than MaxDeliveryCount times, counting
+// their reclaim, to the dead-letter stream, or drops them, and returns the other entries
+func (s *Source) giveUp(ctx context.Context, client *redis.Client, entries []redis.XMessage) ([]redis.XMessage, error) {
+	if s.MaxDeliveryCount <= 0 {
+		return entries, nil
+	}
+
+	var remaining []redis.XMessage
+	for _, entry := range entries {
+		pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: s.Stream,
+			Group:  s.Group,
+			Start:  entry.ID,
+			End:    entry.ID,
+			Count:  1,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the delivery count of entry %s of stream %s: %v", entry.ID, s.Stream, err)
+		}
+		if len(pending) == 0 || pending[0].RetryCount <= s.MaxDeliveryCount {
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		// the entry is added to the dead-letter stream and acknowledged at once
+		_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if s.DeadLetterStream != "" {
+				pipe.XAdd(ctx, &redis.XAddArgs{Stream: s.DeadLetterStream, Values: entry.Values})
+			}
+			pipe.XAck(ctx, s.Stream, s.Group, entry.ID)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot give up on entry %s of stream %s: %v", entry.ID, s.Stream, err)
+		}
+		if s.DeadLetterStream != "" {
+			glog.Errorf("moved entry %s of stream %s to %s after %d deliveries", entry.ID, s.Stream, s.DeadLetterStream, pending[0].RetryCount-1)
+		} else {
+			glog.Errorf("dropped entry %s of stream %s after %d deliveries", entry.ID, s.Stream, pending[0].RetryCount-1)
+		}
+	}
+
+	return remaining, nil
+}
+
+// deliver delivers entries, acknowledging those that were delivered
+func (s *Source) deliver(ctx context.Context, client *redis.Client, sink source.Sink, entries []redis.XMessage) {
+	for _, entry := range entries {
+		e, err := s.event(entry)
+		if err != nil {
+			glog.Errorf("leaving entry %s of stream %s pending: %v", entry.ID, s.Stream, err)
+			continue
+		}
+
+		if err := s.Retry.Deliver(ctx, sink, e); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			glog.Errorf("leaving entry %s of stream %s pending: %v", entry.ID, s.Stream, err)
+			continue
+		}
+
+		if err := client.XAck(ctx, s.Stream, s.Group, entry.ID).Err(); err != nil && ctx.Err() == nil {
+			glog.Errorf("cannot ack entry %s of stream %s: %v", entry.ID, s.Stream, err)
+		}
+	}
+}
+
+// event returns the CloudEvent of an entry, whose data is the JSON object of the entry fields.
+// Its ID is the entry ID, and its time the time the entry was added, from its ID.
+func (s *Source) event(entry redis.XMessage) (cloudevents.Event, error) {
+	data, err := json.Marshal(entry.Values)
+	if err != nil {
+		return cloudevents.Event{}, fmt.Errorf("cannot marshal the fields of the entry: %v", err)
+	}
+
+	e := cloudevents.New(entry.ID, s.EventSource+"#"+s.Stream, EntryEventType)
+	if ms, err := strconv.ParseInt(strings.SplitN(entry.ID, "-", 2)[0], 10, 64); err == nil {
+		e.Time = time.UnixMilli(ms)
+	}
+	e.SetData("application/json", data)
+
+	return e, nil
+}
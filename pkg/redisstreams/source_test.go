@@ -0,0 +1,197 @@
+package redisstreams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestSource returns a source reading the stream orders of an embedded Redis server,
+// along with the server and a client of it
+func newTestSource(t *testing.T) (*Source, *miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return &Source{
+		Options:      &redis.Options{Addr: server.Addr()},
+		Stream:       "orders",
+		Group:        "default-orders",
+		Consumer:     "operator-0",
+		Earliest:     true,
+		ClaimMinIdle: time.Minute,
+		Count:        10,
+		Retry:        source.Retry{Attempts: 1},
+		EventSource:  "/orders",
+	}, server, client
+}
+
+// add adds an entry to the stream, and returns its ID
+func add(t *testing.T, client *redis.Client, values map[string]interface{}) string {
+	id, err := client.XAdd(context.Background(), &redis.XAddArgs{Stream: "orders", Values: values}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// pending returns the IDs of the pending entries of the group
+func pending(t *testing.T, client *redis.Client) []string {
+	entries, err := client.XPendingExt(context.Background(), &redis.XPendingExtArgs{Stream: "orders", Group: "default-orders", Start: "-", End: "+", Count: 100}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+func TestRun(t *testing.T) {
+	s, _, client := newTestSource(t)
+	first := add(t, client, map[string]interface{}{"id": "1"})
+
+	sink := sourcetest.NewSink()
+	sink.Run(t, s)
+	if e := sink.Next(t); e.ID != first || string(e.Data) != `{"id":"1"}` {
+		t.Errorf("got event %+v", e)
+	}
+
+	second := add(t, client, map[string]interface{}{"id": "2"})
+	if e := sink.Next(t); e.ID != second {
+		t.Errorf("got event %+v", e)
+	}
+
+	// entries are acknowledged once delivered
+	deadline := time.Now().Add(sourcetest.Timeout)
+	for len(pending(t, client)) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ids := pending(t, client); len(ids) != 0 {
+		t.Errorf("got pending entries %v", ids)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	s, server, client := newTestSource(t)
+	ctx := context.Background()
+	now := time.Now()
+	server.SetTime(now)
+
+	if err := client.XGroupCreateMkStream(ctx, "orders", "default-orders", "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	id := add(t, client, map[string]interface{}{"id": "1"})
+
+	// a consumer that went away read the entry, and never acknowledged it
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "default-orders", Consumer: "operator-1", Streams: []string{"orders", ">"}}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the entry is not idle for long enough yet
+	sink := sourcetest.NewSink()
+	if err := s.claim(ctx, client, sink); err != nil {
+		t.Fatal(err)
+	}
+	if attempts := sink.Attempts(); attempts != 0 {
+		t.Fatalf("got %d deliveries of an entry that is not idle yet", attempts)
+	}
+
+	// the delivery of the reclaimed entry fails, and it stays pending
+	server.SetTime(now.Add(2 * time.Minute))
+	sink.Fail(1)
+	if err := s.claim(ctx, client, sink); err != nil {
+		t.Fatal(err)
+	}
+	if ids := pending(t, client); sink.Attempts() != 1 || len(ids) != 1 || ids[0] != id {
+		t.Fatalf("got %d deliveries and pending entries %v", sink.Attempts(), ids)
+	}
+
+	// it is reclaimed again once idle, delivered and acknowledged
+	server.SetTime(now.Add(4 * time.Minute))
+	if err := s.claim(ctx, client, sink); err != nil {
+		t.Fatal(err)
+	}
+	if e := sink.Next(t); e.ID != id {
+		t.Errorf("got event %+v", e)
+	}
+	if ids := pending(t, client); len(ids) != 0 {
+		t.Errorf("got pending entries %v", ids)
+	}
+}
+
+func TestEvent(t *testing.T) {
+	s := &Source{Stream: "orders", EventSource: "/apis/eventprovider.k8s.io/v1alpha1/namespaces/default/eventproviders/orders"}
+
+	e, err := s.event(redis.XMessage{ID: "1577934245000-0", Values: map[string]interface{}{"id": "1", "status": "created"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "1577934245000-0" || e.Source != s.EventSource+"#orders" || e.Type != EntryEventType {
+		t.Errorf("got event %+v", e)
+	}
+	if !e.Time.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("got time %s", e.Time)
+	}
+	if string(e.Data) != `{"id":"1","status":"created"}` || e.DataContentType != "application/json" {
+		t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+	for _, deadLetterStream := range []string{"", "orders-dead-letter"} {
+		s, server, client := newTestSource(t)
+		s.MaxDeliveryCount = 2
+		s.DeadLetterStream = deadLetterStream
+		ctx := context.Background()
+		now := time.Now()
+		server.SetTime(now)
+
+		if err := client.XGroupCreateMkStream(ctx, "orders", "default-orders", "0").Err(); err != nil {
+			t.Fatal(err)
+		}
+		id := add(t, client, map[string]interface{}{"id": "1"})
+		if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "default-orders", Consumer: "operator-1", Streams: []string{"orders", ">"}}).Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		// the entry was delivered once, and is delivered a second time
+		sink := sourcetest.NewSink()
+		sink.Fail(1)
+		server.SetTime(now.Add(2 * time.Minute))
+		if err := s.claim(ctx, client, sink); err != nil {
+			t.Fatal(err)
+		}
+		if ids := pending(t, client); sink.Attempts() != 1 || len(ids) != 1 || ids[0] != id {
+			t.Fatalf("%q: got %d deliveries and pending entries %v", deadLetterStream, sink.Attempts(), ids)
+		}
+
+		// it was delivered twice: it is given up on without being delivered again
+		server.SetTime(now.Add(4 * time.Minute))
+		if err := s.claim(ctx, client, sink); err != nil {
+			t.Fatal(err)
+		}
+		if ids := pending(t, client); sink.Attempts() != 1 || len(ids) != 0 {
+			t.Errorf("%q: got %d deliveries and pending entries %v", deadLetterStream, sink.Attempts(), ids)
+		}
+
+		if deadLetterStream == "" {
+			continue
+		}
+		dead, err := client.XRange(ctx, deadLetterStream, "-", "+").Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dead) != 1 || dead[0].Values["id"] != "1" {
+			t.Errorf("got dead-lettered entries %+v", dead)
+		}
+	}
+}
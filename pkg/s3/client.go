@@ -0,0 +1,211 @@
+// Package s3 configures S3 compatible object stores, such as MinIO, to notify a webhook of the
+// events of a bucket, and decodes the event records they deliver.
+// See https://min.io/docs/minio/linux/administration/monitoring/bucket-notifications.html
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/notification"
+)
+
+// webhookSubsystem is the MinIO configuration subsystem of webhook notification targets
+const webhookSubsystem = "notify_webhook"
+
+// Client configures the webhook targets of a MinIO deployment through its admin API,
+// and the notifications of its buckets through the S3 API
+type Client struct {
+	s3     *minio.Client
+	admin  *madmin.AdminClient
+	region string
+}
+
+// NewClient returns a client of the deployment at endpoint, such as http://minio.minio:9000,
+// authenticated with the given access key. region is the region of the deployment, empty when
+// it has none.
+func NewClient(endpoint, region, accessKeyID, secretAccessKey string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: http or https URL expected", endpoint)
+	}
+	secure := u.Scheme == "https"
+
+	s3, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Secure: secure,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create S3 client: %v", err)
+	}
+	admin, err := madmin.New(u.Host, accessKeyID, secretAccessKey, secure)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create MinIO admin client: %v", err)
+	}
+
+	return &Client{s3: s3, admin: admin, region: region}, nil
+}
+
+// WebhookARN returns the ARN bucket notifications deliver to the webhook target id with
+func (c *Client) WebhookARN(id string) string {
+	return notification.NewArn("minio", "sqs", c.region, id, "webhook").String()
+}
+
+// ParseWebhookARN returns the ID of the webhook target of an ARN, arn:minio:sqs:<region>:<id>:webhook
+func ParseWebhookARN(arn string) (string, error) {
+	parsed, err := notification.NewArnFromString(arn)
+	if err != nil || parsed.Service != "sqs" || parsed.Resource != "webhook" || parsed.AccountID == "" {
+		return "", fmt.Errorf("invalid webhook target ARN %q", arn)
+	}
+
+	return parsed.AccountID, nil
+}
+
+// WebhookTarget returns the endpoint of the webhook target id, and whether it exists
+func (c *Client) WebhookTarget(ctx context.Context, id string) (string, bool, error) {
+	config, err := c.admin.GetConfigKV(ctx, webhookSubsystem)
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, line := range strings.Split(string(config), "\n") {
+		fields := splitConfig(strings.TrimSpace(line))
+		if len(fields) == 0 || fields[0] != webhookSubsystem+":"+id {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "endpoint=") {
+				return strings.TrimPrefix(field, "endpoint="), true, nil
+			}
+		}
+		return "", true, nil
+	}
+
+	return "", false, nil
+}
+
+// SetWebhookTarget creates or replaces the webhook target id, delivering to endpoint with the
+// bearer token authToken. It returns whether the deployment must be restarted to apply it.
+func (c *Client) SetWebhookTarget(ctx context.Context, id, endpoint, authToken string) (bool, error) {
+	if strings.ContainsAny(authToken, "\" \t\n") {
+		return false, fmt.Errorf("the webhook token must not contain quotes or spaces")
+	}
+
+	return c.admin.SetConfigKV(ctx, fmt.Sprintf(`%s:%s enable="on" endpoint="%s" auth_token="%s"`, webhookSubsystem, id, endpoint, authToken))
+}
+
+// DeleteWebhookTarget deletes the webhook target id. It returns whether the deployment must be
+// restarted to apply it.
+func (c *Client) DeleteWebhookTarget(ctx context.Context, id string) (bool, error) {
+	return c.admin.DelConfigKV(ctx, webhookSubsystem+":"+id)
+}
+
+// BucketNotifies returns whether a bucket notifies arn of exactly the given events, for the objects
+// whose key has the given prefix and suffix
+func (c *Client) BucketNotifies(ctx context.Context, bucket, arn string, events []string, prefix, suffix string) (bool, error) {
+	config, err := c.s3.GetBucketNotification(ctx, bucket)
+	if err != nil {
+		return false, err
+	}
+
+	eventTypes := make([]notification.EventType, 0, len(events))
+	for _, event := range events {
+		eventTypes = append(eventTypes, notification.EventType(event))
+	}
+	for _, queue := range config.QueueConfigs {
+		if queue.Queue == arn && queue.Equal(eventTypes, prefix, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SetBucketQueue makes a bucket notify arn of the given events, for the objects whose key has the
+// given prefix and suffix, when they are not empty. The other notifications of the bucket are kept.
+func (c *Client) SetBucketQueue(ctx context.Context, bucket, arn string, events []string, prefix, suffix string) error {
+	parsed, err := notification.NewArnFromString(arn)
+	if err != nil {
+		return fmt.Errorf("invalid ARN %q: %v", arn, err)
+	}
+
+	config, err := c.s3.GetBucketNotification(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	config.RemoveQueueByArn(parsed)
+
+	queue := notification.NewConfig(parsed)
+	for _, event := range events {
+		queue.AddEvents(notification.EventType(event))
+	}
+	if prefix != "" {
+		queue.AddFilterPrefix(prefix)
+	}
+	if suffix != "" {
+		queue.AddFilterSuffix(suffix)
+	}
+	config.AddQueue(queue)
+
+	return c.s3.SetBucketNotification(ctx, bucket, config)
+}
+
+// RemoveBucketQueue removes the notifications of a bucket delivering to arn, and keeps the others
+func (c *Client) RemoveBucketQueue(ctx context.Context, bucket, arn string) error {
+	parsed, err := notification.NewArnFromString(arn)
+	if err != nil {
+		return fmt.Errorf("invalid ARN %q: %v", arn, err)
+	}
+
+	config, err := c.s3.GetBucketNotification(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	before := len(config.QueueConfigs)
+	config.RemoveQueueByArn(parsed)
+	if len(config.QueueConfigs) == before {
+		return nil
+	}
+
+	return c.s3.SetBucketNotification(ctx, bucket, config)
+}
+
+// IsNoSuchBucket returns true when err is the error answered by the S3 API for a missing bucket
+func IsNoSuchBucket(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchBucket"
+}
+
+// splitConfig splits a line of MinIO configuration, <subsystem>[:<target>] key=value...,
+// into its fields, unquoting quoted values
+func splitConfig(line string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted, inField := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
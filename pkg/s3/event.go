@@ -0,0 +1,35 @@
+package s3
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification is what S3 and MinIO deliver: one or more event records
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-content-structure.html
+type Notification struct {
+	Records []json.RawMessage `json:"Records"`
+}
+
+// Record is an event record of a notification
+type Record struct {
+	EventSource      string            `json:"eventSource"`
+	AWSRegion        string            `json:"awsRegion"`
+	EventTime        time.Time         `json:"eventTime"`
+	EventName        string            `json:"eventName"`
+	ResponseElements map[string]string `json:"responseElements"`
+	S3               struct {
+		Bucket struct {
+			Name string `json:"name"`
+			ARN  string `json:"arn"`
+		} `json:"bucket"`
+		Object struct {
+			// Key is URL encoded
+			Key       string `json:"key"`
+			Size      int64  `json:"size"`
+			ETag      string `json:"eTag"`
+			VersionID string `json:"versionId"`
+			Sequencer string `json:"sequencer"`
+		} `json:"object"`
+	} `json:"s3"`
+}
@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// lastTickAnnotation is the annotation of the lease holding the last tick emitted
+	lastTickAnnotation = "eventprovider.k8s.io/last-tick"
+	// claimAttempts is how many times a claim is attempted when replicas race for it
+	claimAttempts = 5
+)
+
+// LeaseClaimer records the ticks of a schedule on a Lease. Updates of the lease are
+// conditional on its resource version, so exactly one replica claims every tick.
+type LeaseClaimer struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	owner     metav1.OwnerReference
+	identity  string
+}
+
+// NewLeaseClaimer returns a claimer recording ticks on the lease namespace/name, created
+// owned by owner. identity is recorded as the holder of the lease when a tick is claimed.
+func NewLeaseClaimer(client kubernetes.Interface, namespace, name string, owner metav1.OwnerReference, identity string) *LeaseClaimer {
+	return &LeaseClaimer{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		owner:     owner,
+		identity:  identity,
+	}
+}
+
+// Last implements Claimer
+func (c *LeaseClaimer) Last(ctx context.Context) (time.Time, error) {
+	lease, err := c.client.CoordinationV1().Leases(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return lastTick(lease)
+}
+
+// Claim implements Claimer
+func (c *LeaseClaimer) Claim(ctx context.Context, t time.Time) (bool, error) {
+	leases := c.client.CoordinationV1().Leases(c.namespace)
+	for attempt := 0; attempt < claimAttempts; attempt++ {
+		lease, err := leases.Get(ctx, c.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			lease = &coordinationv1.Lease{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            c.name,
+					OwnerReferences: []metav1.OwnerReference{c.owner},
+				},
+			}
+			c.record(lease, t)
+			_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				continue
+			}
+			return err == nil, err
+		}
+		if err != nil {
+			return false, err
+		}
+
+		last, err := lastTick(lease)
+		if err != nil {
+			return false, err
+		}
+		if !last.Before(t) {
+			return false, nil
+		}
+
+		lease = lease.DeepCopy()
+		c.record(lease, t)
+		_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+		if errors.IsConflict(err) {
+			continue
+		}
+		return err == nil, err
+	}
+
+	return false, fmt.Errorf("lease %s/%s kept changing", c.namespace, c.name)
+}
+
+// record sets t as the last tick of the lease, claimed by this replica
+func (c *LeaseClaimer) record(lease *coordinationv1.Lease, t time.Time) {
+	if lease.Annotations == nil {
+		lease.Annotations = map[string]string{}
+	}
+	lease.Annotations[lastTickAnnotation] = t.UTC().Format(time.RFC3339)
+
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.HolderIdentity = &c.identity
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+}
+
+// lastTick returns the last tick recorded on a lease
+func lastTick(lease *coordinationv1.Lease) (time.Time, error) {
+	value, ok := lease.Annotations[lastTickAnnotation]
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last tick %q on lease %s/%s: %v", value, lease.Namespace, lease.Name, err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,155 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var leasesResource = coordinationv1.SchemeGroupVersion.WithResource("leases")
+
+// newTestClient returns a fake clientset whose leases are versioned like the API server versions them:
+// updates of a lease that changed since it was read conflict
+func newTestClient() *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	tracker := client.Tracker()
+
+	client.PrependReactor("create", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lease := action.(k8stesting.CreateAction).GetObject().(*coordinationv1.Lease).DeepCopy()
+		lease.ResourceVersion = "1"
+		if err := tracker.Create(leasesResource, lease, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, lease, nil
+	})
+	client.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lease := action.(k8stesting.UpdateAction).GetObject().(*coordinationv1.Lease).DeepCopy()
+		stored, err := tracker.Get(leasesResource, action.GetNamespace(), lease.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		if stored.(*coordinationv1.Lease).ResourceVersion != lease.ResourceVersion {
+			return true, nil, errors.NewConflict(leasesResource.GroupResource(), lease.Name, fmt.Errorf("the object has been modified"))
+		}
+
+		version, _ := strconv.Atoi(lease.ResourceVersion)
+		lease.ResourceVersion = strconv.Itoa(version + 1)
+		if err := tracker.Update(leasesResource, lease, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, lease, nil
+	})
+
+	return client
+}
+
+// newTestClaimer returns a claimer of the lease default/schedule
+func newTestClaimer(client *fake.Clientset, identity string) *LeaseClaimer {
+	owner := metav1.OwnerReference{APIVersion: "eventprovider.k8s.io/v1alpha1", Kind: "EventProvider", Name: "schedule", UID: "schedule-uid"}
+	return NewLeaseClaimer(client, "default", "schedule", owner, identity)
+}
+
+func TestLeaseClaimer(t *testing.T) {
+	ctx := context.TODO()
+	client := newTestClient()
+	c := newTestClaimer(client, "replica-1")
+	tick := time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)
+
+	if last, err := c.Last(ctx); err != nil || !last.IsZero() {
+		t.Fatalf("got last tick %s without a lease: %v", last, err)
+	}
+
+	// the lease is created with the first claim
+	if claimed, err := c.Claim(ctx, tick); err != nil || !claimed {
+		t.Fatalf("got claimed %t: %v", claimed, err)
+	}
+	lease, err := client.CoordinationV1().Leases("default").Get(ctx, "schedule", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lease.OwnerReferences) != 1 || lease.OwnerReferences[0].UID != "schedule-uid" || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != "replica-1" {
+		t.Errorf("got lease %+v", lease)
+	}
+	if last, err := c.Last(ctx); err != nil || !last.Equal(tick) {
+		t.Errorf("got last tick %s: %v", last, err)
+	}
+
+	// ticks are only claimed once, and never before the last one
+	if claimed, err := c.Claim(ctx, tick); err != nil || claimed {
+		t.Errorf("claimed the same tick again: %v", err)
+	}
+	if claimed, err := newTestClaimer(client, "replica-2").Claim(ctx, tick.Add(-time.Minute)); err != nil || claimed {
+		t.Errorf("claimed an earlier tick: %v", err)
+	}
+	if claimed, err := newTestClaimer(client, "replica-2").Claim(ctx, tick.Add(time.Minute)); err != nil || !claimed {
+		t.Errorf("got claimed %t for the next tick: %v", claimed, err)
+	}
+	if last, err := c.Last(ctx); err != nil || !last.Equal(tick.Add(time.Minute)) {
+		t.Errorf("got last tick %s: %v", last, err)
+	}
+
+	if lease, err = client.CoordinationV1().Leases("default").Get(ctx, "schedule", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	lease.Annotations[lastTickAnnotation] = "yesterday"
+	if _, err := client.CoordinationV1().Leases("default").Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Last(ctx); err == nil {
+		t.Errorf("no error for an invalid last tick")
+	}
+}
+
+func TestLeaseClaimerRace(t *testing.T) {
+	const replicas = 10
+	client := newTestClient()
+	tick := time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)
+
+	// the first tick races to create the lease, the next ones to update it
+	for i := 0; i < 5; i++ {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var winners []string
+		for r := 0; r < replicas; r++ {
+			identity := fmt.Sprintf("replica-%d", r)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				claimed, err := newTestClaimer(client, identity).Claim(context.TODO(), tick)
+				if err != nil {
+					t.Errorf("%s: %v", identity, err)
+				}
+				if claimed {
+					mu.Lock()
+					winners = append(winners, identity)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(winners) != 1 {
+			t.Fatalf("tick %s claimed by %v", tick, winners)
+		}
+		lease, err := client.CoordinationV1().Leases("default").Get(context.TODO(), "schedule", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *lease.Spec.HolderIdentity != winners[0] {
+			t.Errorf("tick %s claimed by %s, but held by %s", tick, winners[0], *lease.Spec.HolderIdentity)
+		}
+
+		tick = tick.Add(time.Minute)
+	}
+}
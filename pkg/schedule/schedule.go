@@ -0,0 +1,69 @@
+// Package schedule implements the source of the schedule provider: it emits tick
+// events on cron schedules, and claims every tick so that it is only emitted once
+// across the replicas of the operator.
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// parser parses standard five field cron expressions, and descriptors such as @hourly
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule is the union of cron schedules, in a time zone
+type Schedule struct {
+	schedules []cron.Schedule
+	location  *time.Location
+}
+
+// Parse parses cron expressions evaluated in the IANA time zone timeZone, UTC when empty
+func Parse(expressions []string, timeZone string) (*Schedule, error) {
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("at least one cron expression is required")
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %v", timeZone, err)
+	}
+
+	s := &Schedule{location: location}
+	for _, expression := range expressions {
+		schedule, err := parser.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", expression, err)
+		}
+		s.schedules = append(s.schedules, schedule)
+	}
+
+	return s, nil
+}
+
+// Next returns the first tick after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range s.schedules {
+		n := schedule.Next(t.In(s.location))
+		if next.IsZero() || (!n.IsZero() && n.Before(next)) {
+			next = n
+		}
+	}
+
+	return next
+}
+
+// Between returns the ticks after after and up to until, the last max of them when there are more
+func (s *Schedule) Between(after, until time.Time, max int) []time.Time {
+	var ticks []time.Time
+	for t := s.Next(after); !t.IsZero() && !t.After(until); t = s.Next(t) {
+		ticks = append(ticks, t)
+		if len(ticks) > max {
+			ticks = ticks[1:]
+		}
+	}
+
+	return ticks
+}
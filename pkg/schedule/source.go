@@ -0,0 +1,163 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+const (
+	// TickEventType is the CloudEvents type of tick events
+	TickEventType = "dev.events-operator.schedule.tick"
+
+	// lateTolerance is how late a tick can be emitted, on top of the jitter, before it counts as missed
+	lateTolerance = 30 * time.Second
+	// maxMissedTicks caps the number of missed ticks emitted at once by the FireAll policy
+	maxMissedTicks = 100
+)
+
+// MissedTickPolicy is what a schedule does with the ticks it missed, while no replica
+// of the operator was running or while the sink was too slow
+type MissedTickPolicy string
+
+const (
+	// MissedTicksSkip drops missed ticks
+	MissedTicksSkip MissedTickPolicy = "Skip"
+	// MissedTicksFireOnce emits the last missed tick only
+	MissedTicksFireOnce MissedTickPolicy = "FireOnce"
+	// MissedTicksFireAll emits every missed tick, up to 100 of them
+	MissedTicksFireAll MissedTickPolicy = "FireAll"
+)
+
+// ParseMissedTickPolicy returns the policy named s, defaulting to Skip
+func ParseMissedTickPolicy(s string) (MissedTickPolicy, error) {
+	switch MissedTickPolicy(s) {
+	case "", MissedTicksSkip:
+		return MissedTicksSkip, nil
+	case MissedTicksFireOnce, MissedTicksFireAll:
+		return MissedTickPolicy(s), nil
+	default:
+		return "", fmt.Errorf("unknown missed tick policy %q", s)
+	}
+}
+
+// Claimer records the ticks emitted by a schedule, shared by all the replicas of the operator
+type Claimer interface {
+	// Last returns the last tick emitted, zero if none was
+	Last(ctx context.Context) (time.Time, error)
+	// Claim records t as the last tick emitted. It returns false when t, or a later
+	// tick, was already claimed by another replica.
+	Claim(ctx context.Context, t time.Time) (bool, error)
+}
+
+// Source emits a tick event on every tick of a schedule
+type Source struct {
+	// Schedule is when ticks are emitted
+	Schedule *Schedule
+	// MissedTicks is the policy applied to missed ticks
+	MissedTicks MissedTickPolicy
+	// Jitter is the maximum random delay added to every tick
+	Jitter time.Duration
+	// EventSource is the CloudEvents source of tick events
+	EventSource string
+	// Data is the payload of tick events
+	Data []byte
+	// ContentType is the media type of Data
+	ContentType string
+	// Claimer makes sure every tick is only emitted once
+	Claimer Claimer
+}
+
+// Run implements source.Source
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	cursor, err := s.Claimer.Last(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get the last tick: %v", err)
+	}
+	if cursor.IsZero() {
+		cursor = time.Now()
+	}
+	sink.Ready()
+
+	for {
+		next := s.Schedule.Next(cursor)
+		if next.IsZero() {
+			// the schedule never fires again
+			<-ctx.Done()
+			return nil
+		}
+
+		var jitter time.Duration
+		if s.Jitter > 0 {
+			jitter = time.Duration(rand.Int63n(int64(s.Jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next.Add(jitter))):
+		}
+
+		now := time.Now()
+		due := s.Schedule.Between(cursor, now, maxMissedTicks)
+		if len(due) == 0 {
+			continue
+		}
+		cursor = due[len(due)-1]
+
+		for _, tick := range s.apply(due, now) {
+			if err := s.emit(ctx, sink, tick); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// apply returns the ticks to emit out of the due ones, according to the missed tick policy.
+// Ticks are missed when there is more than one due, or the only one is late.
+func (s *Source) apply(due []time.Time, now time.Time) []time.Time {
+	last := due[len(due)-1]
+	if len(due) == 1 && now.Sub(last) <= s.Jitter+lateTolerance {
+		return due
+	}
+
+	switch s.MissedTicks {
+	case MissedTicksFireAll:
+		return due
+	case MissedTicksFireOnce:
+		return []time.Time{last}
+	default:
+		glog.Warningf("skipping %d missed ticks of %s, the last one at %s", len(due), s.EventSource, last)
+		return nil
+	}
+}
+
+// emit claims a tick and delivers its event. Ticks claimed by another replica are skipped, and a tick
+// that cannot be delivered after a few attempts is dropped, so that it is never emitted twice.
+func (s *Source) emit(ctx context.Context, sink source.Sink, tick time.Time) error {
+	claimed, err := s.Claimer.Claim(ctx, tick)
+	if err != nil {
+		return fmt.Errorf("cannot claim tick %s: %v", tick, err)
+	}
+	if !claimed {
+		glog.V(4).Infof("tick %s of %s was emitted by another replica", tick, s.EventSource)
+		return nil
+	}
+
+	e := cloudevents.New(tick.UTC().Format(time.RFC3339), s.EventSource, TickEventType)
+	e.Time = tick
+	if s.Data != nil {
+		e.Data = s.Data
+		e.DataContentType = s.ContentType
+	}
+
+	if err := source.DefaultRetry.Deliver(ctx, sink, e); err != nil && ctx.Err() == nil {
+		glog.Errorf("dropping tick %s of %s: %v", tick, s.EventSource, err)
+	}
+
+	return nil
+}
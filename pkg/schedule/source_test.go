@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ticks := func(ago ...time.Duration) []time.Time {
+		var due []time.Time
+		for _, d := range ago {
+			due = append(due, now.Add(-d))
+		}
+		return due
+	}
+
+	tests := []struct {
+		name   string
+		due    []time.Time
+		jitter time.Duration
+		// want are the ticks emitted with the Skip, FireOnce and FireAll policies
+		want [3][]time.Time
+	}{
+		{
+			name: "on time",
+			due:  ticks(5 * time.Second),
+			want: [3][]time.Time{ticks(5 * time.Second), ticks(5 * time.Second), ticks(5 * time.Second)},
+		},
+		{
+			name:   "late within the jitter",
+			due:    ticks(time.Minute),
+			jitter: time.Minute,
+			want:   [3][]time.Time{ticks(time.Minute), ticks(time.Minute), ticks(time.Minute)},
+		},
+		{
+			name: "late",
+			due:  ticks(time.Minute),
+			want: [3][]time.Time{nil, ticks(time.Minute), ticks(time.Minute)},
+		},
+		{
+			name: "missed",
+			due:  ticks(3*time.Minute, 2*time.Minute, time.Minute),
+			want: [3][]time.Time{nil, ticks(time.Minute), ticks(3*time.Minute, 2*time.Minute, time.Minute)},
+		},
+		{
+			name: "missed, the last one on time",
+			due:  ticks(time.Minute, 0),
+			want: [3][]time.Time{nil, ticks(0), ticks(time.Minute, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		for i, policy := range []MissedTickPolicy{MissedTicksSkip, MissedTicksFireOnce, MissedTicksFireAll} {
+			s := &Source{MissedTicks: policy, Jitter: tt.jitter, EventSource: "/schedule"}
+			got := s.apply(tt.due, now)
+			if len(got) != len(tt.want[i]) {
+				t.Errorf("%s, %s: got ticks %v, want %v", tt.name, policy, got, tt.want[i])
+				continue
+			}
+			for j := range got {
+				if !got[j].Equal(tt.want[i][j]) {
+					t.Errorf("%s, %s: got ticks %v, want %v", tt.name, policy, got, tt.want[i])
+					break
+				}
+			}
+		}
+	}
+}
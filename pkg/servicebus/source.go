@@ -0,0 +1,321 @@
+// Package servicebus implements the source of the servicebus provider: it receives the messages of
+// an Azure Service Bus queue or topic subscription in peek-lock mode, and emits them as CloudEvents.
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+const (
+	// MessageEventType is the CloudEvents type of Service Bus messages
+	MessageEventType = "dev.events-operator.servicebus.message"
+
+	// sessionIdleTimeout is how long a session receives no message before it is released
+	sessionIdleTimeout = 30 * time.Second
+)
+
+// Source receives the messages of a queue or topic subscription
+type Source struct {
+	// Namespace is the fully qualified namespace, such as <namespace>.servicebus.windows.net,
+	// authenticated with Credential
+	Namespace string
+	// Credential authenticates with Namespace
+	Credential azcore.TokenCredential
+	// ConnectionString is the connection string of the namespace, used instead of Namespace when not empty
+	ConnectionString string
+	// Queue is the name of the queue, when messages are not received from a topic subscription
+	Queue string
+	// Topic is the name of the topic of Subscription
+	Topic string
+	// Subscription is the name of the topic subscription
+	Subscription string
+	// Sessions receives the messages of a session-enabled entity, one session at a time
+	Sessions bool
+	// MaxDeliveryCount is how many times a message is delivered before it is dead-lettered
+	MaxDeliveryCount uint32
+	// BatchSize is the maximum number of messages received at once
+	BatchSize int
+	// Retry is how the delivery of messages is retried, before they are abandoned
+	Retry source.Retry
+	// EventSource is the CloudEvents source of events, the entity is appended to it as a fragment
+	EventSource string
+}
+
+// receiver is what a Receiver and a SessionReceiver have in common
+type receiver interface {
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error
+	Close(ctx context.Context) error
+}
+
+// messageLockRenewer renews the locks of messages. Messages received from a session are locked with
+// the session, so only a Receiver renews them.
+type messageLockRenewer interface {
+	RenewMessageLock(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.RenewMessageLockOptions) error
+}
+
+// Run implements source.Source. Messages are completed once they were delivered, and abandoned
+// otherwise, so that Service Bus delivers them again, until they were delivered MaxDeliveryCount
+// times and are dead-lettered.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	var client *azservicebus.Client
+	var err error
+	if s.ConnectionString != "" {
+		client, err = azservicebus.NewClientFromConnectionString(s.ConnectionString, nil)
+	} else {
+		client, err = azservicebus.NewClient(s.Namespace, s.Credential, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot create Service Bus client: %v", err)
+	}
+	defer client.Close(context.Background())
+
+	if s.Sessions {
+		return s.receiveSessions(ctx, client, sink)
+	}
+
+	var r *azservicebus.Receiver
+	options := &azservicebus.ReceiverOptions{ReceiveMode: azservicebus.ReceiveModePeekLock}
+	if s.Queue != "" {
+		r, err = client.NewReceiverForQueue(s.Queue, options)
+	} else {
+		r, err = client.NewReceiverForSubscription(s.Topic, s.Subscription, options)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot receive messages of %s: %v", s.entity(), err)
+	}
+	defer r.Close(context.Background())
+
+	// receiving waits for messages, peeking checks the entity can be received from right away
+	if _, err := r.PeekMessages(ctx, 1, nil); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("cannot receive messages of %s: %v", s.entity(), err)
+	}
+	sink.Ready()
+
+	return s.receive(ctx, r, sink, 0)
+}
+
+// receiveSessions receives the messages of the sessions of the entity, one session after the other
+func (s *Source) receiveSessions(ctx context.Context, client *azservicebus.Client, sink source.Sink) error {
+	options := &azservicebus.SessionReceiverOptions{ReceiveMode: azservicebus.ReceiveModePeekLock}
+	for ctx.Err() == nil {
+		var r *azservicebus.SessionReceiver
+		var err error
+		if s.Queue != "" {
+			r, err = client.AcceptNextSessionForQueue(ctx, s.Queue, options)
+		} else {
+			r, err = client.AcceptNextSessionForSubscription(ctx, s.Topic, s.Subscription, options)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var sbErr *azservicebus.Error
+			if errors.As(err, &sbErr) && sbErr.Code == azservicebus.CodeTimeout {
+				// no session has messages
+				sink.Ready()
+				continue
+			}
+			return fmt.Errorf("cannot accept a session of %s: %v", s.entity(), err)
+		}
+		sink.Ready()
+
+		err = s.receiveSession(ctx, r, sink)
+		r.Close(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// receiveSession receives the messages of a session until it is idle, renewing the session lock meanwhile
+func (s *Source) receiveSession(ctx context.Context, r *azservicebus.SessionReceiver, sink source.Sink) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(sessionIdleTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.RenewSessionLock(ctx, nil); err != nil && ctx.Err() == nil {
+					glog.Warningf("cannot renew the lock of session %s of %s: %v", r.SessionID(), s.entity(), err)
+				}
+			}
+		}
+	}()
+
+	return s.receive(ctx, r, sink, sessionIdleTimeout)
+}
+
+// receive receives and delivers messages until ctx is done or, when idle is not zero,
+// no message was received for idle
+func (s *Source) receive(ctx context.Context, r receiver, sink source.Sink, idle time.Duration) error {
+	for {
+		messages, idled, err := s.receiveBatch(ctx, r, idle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if idled && len(messages) == 0 {
+			return nil
+		}
+		if err != nil && !idled {
+			return fmt.Errorf("cannot receive messages of %s: %v", s.entity(), err)
+		}
+
+		for _, m := range messages {
+			s.handle(ctx, r, sink, m)
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// receiveBatch receives the next messages, waiting for them for idle at most when it is not zero
+func (s *Source) receiveBatch(ctx context.Context, r receiver, idle time.Duration) ([]*azservicebus.ReceivedMessage, bool, error) {
+	if idle == 0 {
+		messages, err := r.ReceiveMessages(ctx, s.BatchSize, nil)
+		return messages, false, err
+	}
+
+	receiveCtx, cancel := context.WithTimeout(ctx, idle)
+	defer cancel()
+	messages, err := r.ReceiveMessages(receiveCtx, s.BatchSize, nil)
+
+	return messages, receiveCtx.Err() != nil, err
+}
+
+// handle delivers a message, and completes, abandons or dead-letters it
+func (s *Source) handle(ctx context.Context, r receiver, sink source.Sink, m *azservicebus.ReceivedMessage) {
+	e, err := s.event(m)
+	if err == nil {
+		err = s.deliver(ctx, r, sink, m, e)
+	}
+	if ctx.Err() != nil {
+		// the lock expires and the message is delivered again
+		return
+	}
+
+	// settlements are not cancelled with ctx, so that they are not lost when the source stops
+	settleCtx := context.Background()
+	switch {
+	case err == nil:
+		if err := r.CompleteMessage(settleCtx, m, nil); err != nil {
+			glog.Errorf("cannot complete message %s of %s: %v", m.MessageID, s.entity(), err)
+		}
+	case m.DeliveryCount >= s.MaxDeliveryCount:
+		glog.Errorf("dead-lettering message %s of %s after %d deliveries: %v", m.MessageID, s.entity(), m.DeliveryCount, err)
+		reason, description := "DeliveryFailed", err.Error()
+		if err := r.DeadLetterMessage(settleCtx, m, &azservicebus.DeadLetterOptions{Reason: &reason, ErrorDescription: &description}); err != nil {
+			glog.Errorf("cannot dead-letter message %s of %s: %v", m.MessageID, s.entity(), err)
+		}
+	default:
+		glog.Errorf("abandoning message %s of %s (delivery %d): %v", m.MessageID, s.entity(), m.DeliveryCount, err)
+		if err := r.AbandonMessage(settleCtx, m, nil); err != nil {
+			glog.Errorf("cannot abandon message %s of %s: %v", m.MessageID, s.entity(), err)
+		}
+	}
+}
+
+// deliver delivers the event of a message, renewing the lock of the message while the delivery is retried
+func (s *Source) deliver(ctx context.Context, r receiver, sink source.Sink, m *azservicebus.ReceivedMessage, e cloudevents.Event) error {
+	renewer, ok := r.(messageLockRenewer)
+	if !ok || m.LockedUntil == nil {
+		return s.Retry.Deliver(ctx, sink, e)
+	}
+	lockDuration := time.Until(*m.LockedUntil)
+	if lockDuration <= 0 {
+		return s.Retry.Deliver(ctx, sink, e)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(lockDuration / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := renewer.RenewMessageLock(ctx, m, nil); err != nil && ctx.Err() == nil {
+					glog.Warningf("cannot renew the lock of message %s of %s: %v", m.MessageID, s.entity(), err)
+				}
+			}
+		}
+	}()
+
+	return s.Retry.Deliver(ctx, sink, e)
+}
+
+// event returns the CloudEvent of a message. Its subject is the subject of the message, its data the
+// body, of the content type of the message, and its application properties are extensions, along with
+// the session ID, correlation ID and partition key of the message.
+func (s *Source) event(m *azservicebus.ReceivedMessage) (cloudevents.Event, error) {
+	id := m.MessageID
+	if id == "" {
+		var err error
+		if id, err = cloudevents.NewID(); err != nil {
+			return cloudevents.Event{}, fmt.Errorf("cannot generate event id: %v", err)
+		}
+	}
+
+	e := cloudevents.New(id, s.EventSource+"#"+s.entity(), MessageEventType)
+	if m.Subject != nil {
+		e.Subject = *m.Subject
+	}
+	if m.EnqueuedTime != nil {
+		e.Time = *m.EnqueuedTime
+	}
+	var contentType string
+	if m.ContentType != nil {
+		contentType = *m.ContentType
+	}
+	e.SetData(contentType, m.Body)
+
+	properties := map[string]string{}
+	for name, value := range m.ApplicationProperties {
+		properties[name] = fmt.Sprint(value)
+	}
+	e.SetExtensions(properties)
+	for name, value := range map[string]*string{
+		"sessionid":     m.SessionID,
+		"correlationid": m.CorrelationID,
+		"partitionkey":  m.PartitionKey,
+	} {
+		if value != nil && *value != "" {
+			e.SetExtension(name, *value)
+		}
+	}
+
+	return e, nil
+}
+
+// entity returns the path of the entity messages are received from
+func (s *Source) entity() string {
+	if s.Queue != "" {
+		return s.Queue
+	}
+
+	return fmt.Sprintf("%s/subscriptions/%s", s.Topic, s.Subscription)
+}
@@ -0,0 +1,254 @@
+package servicebus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+)
+
+// fakeReceiver is a receiver answering batches of messages, and recording their settlements
+type fakeReceiver struct {
+	mu          sync.Mutex
+	batches     [][]*azservicebus.ReceivedMessage
+	settlements []string
+}
+
+// ReceiveMessages implements receiver. It answers the next batch, and waits for ctx once there is none.
+func (r *fakeReceiver) ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error) {
+	r.mu.Lock()
+	if len(r.batches) > 0 {
+		batch := r.batches[0]
+		r.batches = r.batches[1:]
+		r.mu.Unlock()
+		return batch, nil
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// CompleteMessage implements receiver
+func (r *fakeReceiver) CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error {
+	r.settle("complete " + message.MessageID)
+	return nil
+}
+
+// AbandonMessage implements receiver
+func (r *fakeReceiver) AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error {
+	r.settle("abandon " + message.MessageID)
+	return nil
+}
+
+// DeadLetterMessage implements receiver
+func (r *fakeReceiver) DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error {
+	r.settle(fmt.Sprintf("dead-letter %s %s", message.MessageID, *options.Reason))
+	return nil
+}
+
+// Close implements receiver
+func (r *fakeReceiver) Close(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeReceiver) settle(settlement string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settlements = append(r.settlements, settlement)
+}
+
+// settled returns the settlements of the messages, in order
+func (r *fakeReceiver) settled() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.settlements...)
+}
+
+// fakeLockRenewingReceiver is a fakeReceiver renewing message locks, as a Receiver does
+type fakeLockRenewingReceiver struct {
+	fakeReceiver
+	renewals int
+}
+
+// RenewMessageLock implements messageLockRenewer
+func (r *fakeLockRenewingReceiver) RenewMessageLock(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.RenewMessageLockOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.renewals++
+	return nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestEvent(t *testing.T) {
+	enqueued := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		source     *Source
+		message    *azservicebus.ReceivedMessage
+		eventSrc   string
+		subject    string
+		extensions map[string]string
+	}{
+		{
+			name:   "queue message with properties",
+			source: &Source{Queue: "orders", EventSource: "/servicebus"},
+			message: &azservicebus.ReceivedMessage{
+				MessageID:             "42",
+				Subject:               stringPtr("order.created"),
+				ContentType:           stringPtr("application/json"),
+				Body:                  []byte(`{"id":1}`),
+				EnqueuedTime:          &enqueued,
+				ApplicationProperties: map[string]any{"Tenant": "acme", "priority": int64(3), "not-valid": "dropped"},
+			},
+			eventSrc:   "/servicebus#orders",
+			subject:    "order.created",
+			extensions: map[string]string{"tenant": "acme", "priority": "3"},
+		},
+		{
+			name:   "session message of a subscription",
+			source: &Source{Topic: "orders", Subscription: "billing", EventSource: "/servicebus"},
+			message: &azservicebus.ReceivedMessage{
+				MessageID:     "42",
+				ContentType:   stringPtr("application/json"),
+				Body:          []byte(`{"id":1}`),
+				EnqueuedTime:  &enqueued,
+				SessionID:     stringPtr("customer-1"),
+				CorrelationID: stringPtr("request-7"),
+				PartitionKey:  stringPtr("customer-1"),
+			},
+			eventSrc:   "/servicebus#orders/subscriptions/billing",
+			extensions: map[string]string{"sessionid": "customer-1", "correlationid": "request-7", "partitionkey": "customer-1"},
+		},
+		{
+			name:   "empty session, correlation and partition key",
+			source: &Source{Queue: "orders", EventSource: "/servicebus"},
+			message: &azservicebus.ReceivedMessage{
+				MessageID:     "42",
+				ContentType:   stringPtr("application/json"),
+				Body:          []byte(`{"id":1}`),
+				EnqueuedTime:  &enqueued,
+				SessionID:     stringPtr(""),
+				CorrelationID: stringPtr(""),
+			},
+			eventSrc:   "/servicebus#orders",
+			extensions: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := tt.source.event(tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.ID != "42" || e.Source != tt.eventSrc || e.Type != MessageEventType || e.Subject != tt.subject || !e.Time.Equal(enqueued) {
+				t.Errorf("got event %+v", e)
+			}
+			if string(e.Data) != `{"id":1}` || e.DataContentType != "application/json" {
+				t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+			}
+			extensions := map[string]string{}
+			for name, value := range e.Extensions {
+				extensions[name] = fmt.Sprint(value)
+			}
+			if !reflect.DeepEqual(extensions, tt.extensions) {
+				t.Errorf("got extensions %v, want %v", extensions, tt.extensions)
+			}
+		})
+	}
+
+	// messages without an ID get a generated one
+	s := &Source{Queue: "orders", EventSource: "/servicebus"}
+	if e, err := s.event(&azservicebus.ReceivedMessage{Body: []byte("text")}); err != nil || e.ID == "" {
+		t.Errorf("got event %+v and error %v", e, err)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name          string
+		deliveryCount uint32
+		failures      int
+		settlement    string
+	}{
+		{name: "delivered", deliveryCount: 1, settlement: "complete 42"},
+		{name: "delivered after a retry", deliveryCount: 1, failures: 1, settlement: "complete 42"},
+		{name: "first delivery failed", deliveryCount: 1, failures: 2, settlement: "abandon 42"},
+		{name: "delivery below MaxDeliveryCount failed", deliveryCount: 4, failures: 2, settlement: "abandon 42"},
+		{name: "delivery at MaxDeliveryCount failed", deliveryCount: 5, failures: 2, settlement: "dead-letter 42 DeliveryFailed"},
+		{name: "delivery above MaxDeliveryCount failed", deliveryCount: 6, failures: 2, settlement: "dead-letter 42 DeliveryFailed"},
+		{name: "delivered at MaxDeliveryCount", deliveryCount: 5, settlement: "complete 42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Source{Queue: "orders", MaxDeliveryCount: 5, Retry: source.Retry{Attempts: 2, Backoff: time.Millisecond}, EventSource: "/servicebus"}
+			r := &fakeReceiver{}
+			sink := sourcetest.NewSink()
+			sink.Fail(tt.failures)
+
+			s.handle(context.Background(), r, sink, &azservicebus.ReceivedMessage{MessageID: "42", Body: []byte("text"), DeliveryCount: tt.deliveryCount})
+			if settled := r.settled(); len(settled) != 1 || settled[0] != tt.settlement {
+				t.Errorf("got settlements %v, want %s", settled, tt.settlement)
+			}
+		})
+	}
+
+	// a message is not settled when the source stops, its lock expires instead
+	s := &Source{Queue: "orders", MaxDeliveryCount: 5, Retry: source.Retry{Attempts: 1}, EventSource: "/servicebus"}
+	r := &fakeReceiver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.handle(ctx, r, sourcetest.NewSink(), &azservicebus.ReceivedMessage{MessageID: "42", Body: []byte("text"), DeliveryCount: 1})
+	if settled := r.settled(); len(settled) != 0 {
+		t.Errorf("got settlements %v", settled)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	s := &Source{Queue: "orders", MaxDeliveryCount: 5, BatchSize: 10, Retry: source.Retry{Attempts: 3, Backoff: 30 * time.Millisecond}, EventSource: "/servicebus"}
+	lockedUntil := time.Now().Add(40 * time.Millisecond)
+	r := &fakeLockRenewingReceiver{fakeReceiver: fakeReceiver{batches: [][]*azservicebus.ReceivedMessage{
+		{
+			{MessageID: "1", Body: []byte("first"), DeliveryCount: 1, LockedUntil: &lockedUntil},
+			{MessageID: "2", Body: []byte("second"), DeliveryCount: 1},
+		},
+		{
+			{MessageID: "3", Body: []byte("third"), DeliveryCount: 1},
+		},
+	}}}
+	sink := sourcetest.NewSink()
+	// the first message is delivered after its lock would have expired
+	sink.Fail(2)
+
+	// receiving from a session stops once it is idle
+	if err := s.receive(context.Background(), r, sink, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if settled := r.settled(); !reflect.DeepEqual(settled, []string{"complete 1", "complete 2", "complete 3"}) {
+		t.Errorf("got settlements %v", settled)
+	}
+	for _, want := range []string{"first", "second", "third"} {
+		if e := sink.Next(t); string(e.Data) != want {
+			t.Errorf("got event %+v, want %s", e, want)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.renewals == 0 {
+		t.Errorf("the lock of the first message was not renewed")
+	}
+}
@@ -0,0 +1,176 @@
+// Package sns subscribes HTTPS endpoints to Amazon SNS topics, and verifies the
+// messages SNS delivers to them.
+// See https://docs.aws.amazon.com/sns/latest/dg/sns-http-https-endpoint-as-subscriber.html
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PendingConfirmation is the subscription ARN of subscriptions not confirmed yet
+const PendingConfirmation = "PendingConfirmation"
+
+// Error is an error answered by the SNS API
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Error>Code"`
+	Message    string `xml:"Error>Message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("SNS answered %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsNotFound returns true when err is a NotFound error answered by the SNS API
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && (apiErr.Code == "NotFound" || apiErr.StatusCode == http.StatusNotFound)
+}
+
+// Subscription is the subscription of an endpoint to a topic
+type Subscription struct {
+	SubscriptionArn string
+	TopicArn        string
+	Protocol        string
+	Endpoint        string
+}
+
+// Client calls the SNS API of a region
+type Client struct {
+	endpoint string
+	region   string
+	creds    Credentials
+
+	client *http.Client
+}
+
+// NewClient returns a client for the SNS API of region. endpoint overrides the
+// regional endpoint, for SNS emulators.
+func NewClient(region, endpoint string, creds Credentials) *Client {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sns.%s.amazonaws.com", region)
+	}
+
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		region:   region,
+		creds:    creds,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ParseTopicARN returns the region of a topic, arn:aws:sns:<region>:<account>:<name>
+func ParseTopicARN(arn string) (string, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" {
+		return "", fmt.Errorf("invalid topic ARN %q", arn)
+	}
+
+	return parts[3], nil
+}
+
+// Subscribe subscribes an HTTPS endpoint to a topic, and returns the ARN of the subscription.
+// SNS then sends a SubscriptionConfirmation message to the endpoint.
+func (c *Client) Subscribe(ctx context.Context, topicARN, endpoint string) (string, error) {
+	protocol := "https"
+	if strings.HasPrefix(endpoint, "http://") {
+		protocol = "http"
+	}
+
+	var resp struct {
+		SubscriptionArn string `xml:"SubscribeResult>SubscriptionArn"`
+	}
+	err := c.do(ctx, url.Values{
+		"Action":                {"Subscribe"},
+		"TopicArn":              {topicARN},
+		"Protocol":              {protocol},
+		"Endpoint":              {endpoint},
+		"ReturnSubscriptionArn": {"true"},
+	}, &resp)
+
+	return resp.SubscriptionArn, err
+}
+
+// Unsubscribe deletes a subscription
+func (c *Client) Unsubscribe(ctx context.Context, subscriptionARN string) error {
+	return c.do(ctx, url.Values{
+		"Action":          {"Unsubscribe"},
+		"SubscriptionArn": {subscriptionARN},
+	}, nil)
+}
+
+// FindSubscription returns the subscription of endpoint to a topic, nil when there is none
+func (c *Client) FindSubscription(ctx context.Context, topicARN, endpoint string) (*Subscription, error) {
+	nextToken := ""
+	for {
+		params := url.Values{
+			"Action":   {"ListSubscriptionsByTopic"},
+			"TopicArn": {topicARN},
+		}
+		if nextToken != "" {
+			params.Set("NextToken", nextToken)
+		}
+
+		var resp struct {
+			Subscriptions []Subscription `xml:"ListSubscriptionsByTopicResult>Subscriptions>member"`
+			NextToken     string         `xml:"ListSubscriptionsByTopicResult>NextToken"`
+		}
+		if err := c.do(ctx, params, &resp); err != nil {
+			return nil, err
+		}
+
+		for i := range resp.Subscriptions {
+			if resp.Subscriptions[i].Endpoint == endpoint {
+				return &resp.Subscriptions[i], nil
+			}
+		}
+
+		if resp.NextToken == "" {
+			return nil, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
+
+// do calls an action of the query API, decoding the XML response into out
+func (c *Client) do(ctx context.Context, params url.Values, out interface{}) error {
+	params.Set("Version", "2010-03-31")
+	body := []byte(params.Encode())
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("cannot create request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	sign(req, body, c.creds, c.region, "sns", time.Now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot call %s: %v", params.Get("Action"), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(apiErr)
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("cannot decode %s response: %v", params.Get("Action"), err)
+	}
+
+	return nil
+}
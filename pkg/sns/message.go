@@ -0,0 +1,280 @@
+package sns
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// NotificationType is the type of the messages published to a topic
+	NotificationType = "Notification"
+	// SubscriptionConfirmationType is the type of the message asking to confirm a subscription
+	SubscriptionConfirmationType = "SubscriptionConfirmation"
+	// UnsubscribeConfirmationType is the type of the message sent when a subscription is deleted
+	UnsubscribeConfirmationType = "UnsubscribeConfirmation"
+)
+
+// certs caches the signing certificates by URL, across verifiers
+var certs sync.Map
+
+// amazonHost matches the hosts of the SNS regional endpoints, which serve the signing certificates
+var amazonHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// Message is a message delivered by SNS to an HTTP endpoint
+type Message struct {
+	Type              string                      `json:"Type"`
+	MessageID         string                      `json:"MessageId"`
+	Token             string                      `json:"Token,omitempty"`
+	TopicArn          string                      `json:"TopicArn"`
+	Subject           string                      `json:"Subject,omitempty"`
+	Message           string                      `json:"Message"`
+	Timestamp         time.Time                   `json:"Timestamp"`
+	SignatureVersion  string                      `json:"SignatureVersion"`
+	Signature         string                      `json:"Signature"`
+	SigningCertURL    string                      `json:"SigningCertURL"`
+	SubscribeURL      string                      `json:"SubscribeURL,omitempty"`
+	UnsubscribeURL    string                      `json:"UnsubscribeURL,omitempty"`
+	MessageAttributes map[string]MessageAttribute `json:"MessageAttributes,omitempty"`
+
+	// timestamp is the timestamp as sent, which is signed
+	timestamp string
+}
+
+// MessageAttribute is an attribute of a notification
+type MessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// UnmarshalJSON keeps the timestamp as sent, to verify the signature
+func (m *Message) UnmarshalJSON(b []byte) error {
+	type message Message
+	var raw struct {
+		message
+		Timestamp string `json:"Timestamp"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*m = Message(raw.message)
+	m.timestamp = raw.Timestamp
+	if raw.Timestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw.Timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid Timestamp: %v", err)
+		}
+		m.Timestamp = t
+	}
+
+	return nil
+}
+
+// Verifier checks messages come from SNS, for a single topic
+type Verifier struct {
+	topicARN string
+	// trusted returns true for the URLs certificates and subscription confirmations are fetched from
+	trusted func(u *url.URL) bool
+
+	client *http.Client
+}
+
+// NewVerifier returns a verifier for the messages of a topic. When endpoint overrides
+// the SNS endpoint, certificates and subscription confirmations are fetched from it.
+func NewVerifier(topicARN, endpoint string) (*Verifier, error) {
+	v := &Verifier{
+		topicARN: topicARN,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if endpoint == "" {
+		v.trusted = func(u *url.URL) bool {
+			return u.Scheme == "https" && amazonHost.MatchString(u.Hostname())
+		}
+		return v, nil
+	}
+
+	e, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNS endpoint %s: %v", endpoint, err)
+	}
+	v.trusted = func(u *url.URL) bool {
+		return u.Scheme == e.Scheme && u.Host == e.Host
+	}
+
+	return v, nil
+}
+
+// Authenticate decodes the message in body, and verifies it
+func (v *Verifier) Authenticate(r *http.Request, body []byte) error {
+	var m Message
+	if err := json.Unmarshal(body, &m); err != nil {
+		return fmt.Errorf("cannot decode message: %v", err)
+	}
+
+	return v.Verify(r.Context(), &m)
+}
+
+// Verify checks the message is for the topic, and its signature
+// See https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func (v *Verifier) Verify(ctx context.Context, m *Message) error {
+	if m.TopicArn != v.topicARN {
+		return fmt.Errorf("message for topic %s", m.TopicArn)
+	}
+
+	var hash crypto.Hash
+	switch m.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("unsupported signature version %q", m.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("cannot decode signature: %v", err)
+	}
+
+	cert, err := v.certificate(ctx, m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("the signing certificate does not hold an RSA key")
+	}
+
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(m.stringToSign()))
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(m.stringToSign()))
+		digest = sum[:]
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	return nil
+}
+
+// Confirm confirms a subscription, by visiting the SubscribeURL of its confirmation message
+func (v *Verifier) Confirm(ctx context.Context, m *Message) error {
+	u, err := url.Parse(m.SubscribeURL)
+	if err != nil || !v.trusted(u) {
+		return fmt.Errorf("untrusted SubscribeURL %s", m.SubscribeURL)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("cannot confirm subscription: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("cannot confirm subscription: SNS answered %s", resp.Status)
+	}
+
+	return nil
+}
+
+// certificate returns the signing certificate at certURL, which must be trusted
+func (v *Verifier) certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	u, err := url.Parse(certURL)
+	if err != nil || !v.trusted(u) || !strings.HasSuffix(u.Path, ".pem") {
+		return nil, fmt.Errorf("untrusted SigningCertURL %s", certURL)
+	}
+
+	if cert, ok := certs.Load(certURL); ok {
+		return cert.(*x509.Certificate), nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get signing certificate: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot get signing certificate: %s", resp.Status)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read signing certificate: %v", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM certificate at %s", certURL)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse signing certificate: %v", err)
+	}
+	certs.Store(certURL, cert)
+
+	return cert, nil
+}
+
+// stringToSign returns the string the message signature is computed over: name and
+// value lines of the signed fields, in alphabetical order
+func (m *Message) stringToSign() string {
+	var fields [][2]string
+	if m.Type == NotificationType {
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageID},
+			{"Subject", m.Subject},
+			{"Timestamp", m.timestamp},
+			{"TopicArn", m.TopicArn},
+			{"Type", m.Type},
+		}
+	} else {
+		fields = [][2]string{
+			{"Message", m.Message},
+			{"MessageId", m.MessageID},
+			{"SubscribeURL", m.SubscribeURL},
+			{"Timestamp", m.timestamp},
+			{"Token", m.Token},
+			{"TopicArn", m.TopicArn},
+			{"Type", m.Type},
+		}
+	}
+
+	var b strings.Builder
+	for _, f := range fields {
+		// the subject is only signed when the notification has one
+		if f[0] == "Subject" && f[1] == "" {
+			continue
+		}
+		b.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+
+	return b.String()
+}
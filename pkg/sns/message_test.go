@@ -0,0 +1,230 @@
+package sns
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testTopicARN = "arn:aws:sns:eu-west-1:123456789012:orders"
+
+// signer signs messages as SNS does, with a self-signed certificate served at certURL
+type signer struct {
+	key     *rsa.PrivateKey
+	certURL string
+	// endpoint is the SNS endpoint serving the certificate
+	endpoint string
+}
+
+func newSigner(t *testing.T) *signer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.eu-west-1.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(certPEM)
+	}))
+	t.Cleanup(srv.Close)
+
+	return &signer{key: key, certURL: srv.URL + "/SimpleNotificationService-test.pem", endpoint: srv.URL}
+}
+
+// sign returns the JSON of a signed message
+func (s *signer) sign(t *testing.T, m map[string]string) []byte {
+	var decoded Message
+	b, _ := json.Marshal(m)
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, digest := crypto.SHA1, sha1.Sum([]byte(decoded.stringToSign()))
+	sum := digest[:]
+	if m["SignatureVersion"] == "2" {
+		digest := sha256.Sum256([]byte(decoded.stringToSign()))
+		hash, sum = crypto.SHA256, digest[:]
+	}
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, hash, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m["Signature"] = base64.StdEncoding.EncodeToString(signature)
+	if _, ok := m["SigningCertURL"]; !ok {
+		m["SigningCertURL"] = s.certURL
+	}
+	b, _ = json.Marshal(m)
+
+	return b
+}
+
+// notification returns the fields of a notification of the test topic
+func notification(version string) map[string]string {
+	return map[string]string{
+		"Type":             NotificationType,
+		"MessageId":        "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		"TopicArn":         testTopicARN,
+		"Subject":          "order created",
+		"Message":          `{"id":1}`,
+		"Timestamp":        "2012-05-02T00:54:06.655Z",
+		"SignatureVersion": version,
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s := newSigner(t)
+	v, err := NewVerifier(testTopicARN, s.endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		body  func() []byte
+		valid bool
+	}{
+		{name: "signature version 1", body: func() []byte { return s.sign(t, notification("1")) }, valid: true},
+		{name: "signature version 2", body: func() []byte { return s.sign(t, notification("2")) }, valid: true},
+		{name: "without subject", body: func() []byte {
+			m := notification("2")
+			delete(m, "Subject")
+			return s.sign(t, m)
+		}, valid: true},
+		{name: "subscription confirmation", body: func() []byte {
+			return s.sign(t, map[string]string{
+				"Type":             SubscriptionConfirmationType,
+				"MessageId":        "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+				"Token":            "token",
+				"TopicArn":         testTopicARN,
+				"Message":          "You have chosen to subscribe to the topic",
+				"SubscribeURL":     s.endpoint + "/?Action=ConfirmSubscription",
+				"Timestamp":        "2012-04-26T20:45:04.751Z",
+				"SignatureVersion": "1",
+			})
+		}, valid: true},
+		{name: "other topic", body: func() []byte {
+			m := notification("1")
+			m["TopicArn"] = "arn:aws:sns:eu-west-1:123456789012:payments"
+			return s.sign(t, m)
+		}},
+		{name: "tampered message", body: func() []byte {
+			b := s.sign(t, notification("1"))
+			m := map[string]string{}
+			json.Unmarshal(b, &m)
+			m["Message"] = `{"id":2}`
+			b, _ = json.Marshal(m)
+			return b
+		}},
+		{name: "tampered timestamp", body: func() []byte {
+			b := s.sign(t, notification("2"))
+			m := map[string]string{}
+			json.Unmarshal(b, &m)
+			m["Timestamp"] = "2012-05-02T00:54:06.656Z"
+			b, _ = json.Marshal(m)
+			return b
+		}},
+		{name: "untrusted certificate", body: func() []byte {
+			m := notification("1")
+			m["SigningCertURL"] = "https://attacker.example.com/SimpleNotificationService-test.pem"
+			return s.sign(t, m)
+		}},
+		{name: "certificate not a pem", body: func() []byte {
+			m := notification("1")
+			m["SigningCertURL"] = s.endpoint + "/certificate"
+			return s.sign(t, m)
+		}},
+		{name: "unsupported signature version", body: func() []byte { return s.sign(t, notification("3")) }},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		err := v.Authenticate(r, tt.body())
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: verified", tt.name)
+		}
+	}
+}
+
+func TestVerifierTrust(t *testing.T) {
+	v, err := NewVerifier(testTopicARN, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Message{
+		TopicArn:         testTopicARN,
+		SignatureVersion: "1",
+		Signature:        base64.StdEncoding.EncodeToString([]byte("signature")),
+	}
+	for _, certURL := range []string{
+		"http://sns.eu-west-1.amazonaws.com/SimpleNotificationService-test.pem",
+		"https://sns.eu-west-1.amazonaws.com.attacker.example.com/SimpleNotificationService-test.pem",
+		"https://attacker.example.com/sns.eu-west-1.amazonaws.com.pem",
+	} {
+		m.SigningCertURL = certURL
+		if err := v.Verify(context.TODO(), m); err == nil || err.Error() != "untrusted SigningCertURL "+certURL {
+			t.Errorf("%s: got error %v", certURL, err)
+		}
+	}
+}
+
+func TestStringToSign(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "notification",
+			message: `{"Type":"Notification","MessageId":"id","TopicArn":"arn","Subject":"subject","Message":"message","Timestamp":"2012-05-02T00:54:06.655Z","UnsubscribeURL":"https://sns.eu-west-1.amazonaws.com/"}`,
+			want:    "Message\nmessage\nMessageId\nid\nSubject\nsubject\nTimestamp\n2012-05-02T00:54:06.655Z\nTopicArn\narn\nType\nNotification\n",
+		},
+		{
+			name:    "notification without subject",
+			message: `{"Type":"Notification","MessageId":"id","TopicArn":"arn","Message":"message","Timestamp":"2012-05-02T00:54:06.655Z"}`,
+			want:    "Message\nmessage\nMessageId\nid\nTimestamp\n2012-05-02T00:54:06.655Z\nTopicArn\narn\nType\nNotification\n",
+		},
+		{
+			name:    "subscription confirmation",
+			message: `{"Type":"SubscriptionConfirmation","MessageId":"id","Token":"token","TopicArn":"arn","Message":"message","SubscribeURL":"https://sns.eu-west-1.amazonaws.com/?Action=ConfirmSubscription","Timestamp":"2012-04-26T20:45:04.751Z"}`,
+			want:    "Message\nmessage\nMessageId\nid\nSubscribeURL\nhttps://sns.eu-west-1.amazonaws.com/?Action=ConfirmSubscription\nTimestamp\n2012-04-26T20:45:04.751Z\nToken\ntoken\nTopicArn\narn\nType\nSubscriptionConfirmation\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var m Message
+		if err := json.Unmarshal([]byte(tt.message), &m); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := m.stringToSign(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Elected runs a source in a single replica of the operator: the one holding a lease.
+// Replicas that do not hold it stand by, ready to take over.
+type Elected struct {
+	// Source is the source run by the replica holding the lease
+	Source Source
+	// Client is the client of the lease
+	Client kubernetes.Interface
+	// Namespace of the lease
+	Namespace string
+	// Name of the lease
+	Name string
+	// Identity identifies the replica of the operator in the lease
+	Identity string
+}
+
+// Run implements Source. It returns an error when the replica loses the lease, so that it is
+// restarted with a backoff and stands by again.
+func (e *Elected) Run(ctx context.Context, sink Sink) error {
+	electionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		stopped bool
+		running sync.WaitGroup
+		err     error
+	)
+	elector, electorErr := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: e.Namespace, Name: e.Name},
+			Client:     e.Client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: e.Identity},
+		},
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				mu.Lock()
+				if stopped {
+					mu.Unlock()
+					return
+				}
+				running.Add(1)
+				mu.Unlock()
+				defer running.Done()
+
+				err = e.Source.Run(ctx, sink)
+				// give the lease up when the source stops on its own
+				cancel()
+			},
+			OnStoppedLeading: func() {},
+		},
+	})
+	if electorErr != nil {
+		return fmt.Errorf("cannot elect a replica for the source: %v", electorErr)
+	}
+
+	// a replica standing by is ready to take over
+	sink.Ready()
+	elector.Run(electionCtx)
+
+	mu.Lock()
+	stopped = true
+	mu.Unlock()
+	running.Wait()
+
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+	return fmt.Errorf("lost lease %s/%s", e.Namespace, e.Name)
+}
@@ -0,0 +1,38 @@
+package source
+
+import (
+	"context"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+)
+
+// Retry is how many times, and how often, the delivery of an event is attempted
+type Retry struct {
+	// Attempts is the number of delivery attempts, at least one
+	Attempts int
+	// Backoff is the wait after the first failed attempt, doubled after every other one
+	Backoff time.Duration
+}
+
+// DefaultRetry attempts deliveries 5 times over about 15 seconds
+var DefaultRetry = Retry{Attempts: 5, Backoff: time.Second}
+
+// Deliver delivers an event to sink, retrying failed attempts. It returns the error of the last
+// attempt, or the error of ctx when it is done before the event could be delivered.
+func (r Retry) Deliver(ctx context.Context, sink Sink, e cloudevents.Event) error {
+	backoff := r.Backoff
+	for attempt := 1; ; attempt++ {
+		err := sink.Deliver(ctx, e)
+		if err == nil || attempt >= r.Attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,222 @@
+// Package source runs the eventproviders whose events are not pushed to the event gateway,
+// but produced or pulled by the operator itself, such as schedules or the consumers of brokers.
+package source
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+)
+
+const (
+	// minBackoff is how long a failed source waits before it is started again
+	minBackoff = time.Second
+	// maxBackoff caps the wait between restarts of a source failing over and over
+	maxBackoff = 5 * time.Minute
+	// stableAfter is how long a source must run to reset its backoff
+	stableAfter = time.Minute
+)
+
+// Source produces the events of an eventprovider
+type Source interface {
+	// Run produces events and hands them to sink until ctx is done. When Run returns
+	// an error, the source is started again after a backoff.
+	Run(ctx context.Context, sink Sink) error
+}
+
+// Sink is what sources hand their events to
+type Sink interface {
+	// Deliver dispatches an event to the sink of the eventprovider. Sources only
+	// acknowledge what they consumed once Deliver succeeded.
+	Deliver(ctx context.Context, e cloudevents.Event) error
+	// Ready reports the source is connected and producing events
+	Ready()
+}
+
+// Config is how the source of an eventprovider is run
+type Config struct {
+	// Namespace of the eventprovider
+	Namespace string
+	// Name of the eventprovider
+	Name string
+	// Sink is the URL events are dispatched to
+	Sink string
+	// Mode is the CloudEvents content mode events are dispatched in
+	Mode cloudevents.Mode
+	// Hash identifies the settings of the source, which is restarted when it changes
+	Hash string
+}
+
+// State is the state of a running source
+type State struct {
+	// Ready is true once the source reported it is producing events
+	Ready bool
+	// Err is why the source last stopped, until it is ready again
+	Err error
+}
+
+// Manager runs the sources of eventproviders
+type Manager struct {
+	dispatcher *gateway.Dispatcher
+	// OnChange is called when the state of a source changes
+	OnChange func(namespace, name string)
+
+	mu      sync.Mutex
+	runners map[string]*runner
+}
+
+// NewManager returns a new Manager dispatching events with dispatcher
+func NewManager(dispatcher *gateway.Dispatcher) *Manager {
+	return &Manager{
+		dispatcher: dispatcher,
+		runners:    map[string]*runner{},
+	}
+}
+
+// Run starts the source of an eventprovider. A running source is left alone, unless its
+// settings changed, in which case it is stopped and the new one started in its place.
+func (m *Manager) Run(config Config, source Source) {
+	key := config.Namespace + "/" + config.Name
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.runners[key]; ok {
+		if r.config.Hash == config.Hash {
+			r.setConfig(config)
+			return
+		}
+		r.stop()
+		glog.Infof("restarting source of eventprovider %s", key)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &runner{
+		manager: m,
+		config:  config,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	m.runners[key] = r
+	go r.run(ctx, source)
+}
+
+// Stop stops the source of an eventprovider, if it runs one
+func (m *Manager) Stop(namespace, name string) {
+	key := namespace + "/" + name
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.runners[key]; ok {
+		r.stop()
+		delete(m.runners, key)
+		glog.Infof("stopped source of eventprovider %s", key)
+	}
+}
+
+// State returns the state of the source of an eventprovider
+func (m *Manager) State(namespace, name string) State {
+	m.mu.Lock()
+	r, ok := m.runners[namespace+"/"+name]
+	m.mu.Unlock()
+
+	if !ok {
+		return State{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state
+}
+
+// runner runs one source, and restarts it when it fails
+type runner struct {
+	manager *Manager
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu     sync.Mutex
+	config Config
+	state  State
+}
+
+func (r *runner) run(ctx context.Context, source Source) {
+	defer close(r.done)
+
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := source.Run(ctx, r)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) > stableAfter {
+			backoff = minBackoff
+		}
+		glog.Errorf("source of eventprovider %s/%s stopped, restarting in %v: %v", r.config.Namespace, r.config.Name, backoff, err)
+		r.setState(State{Err: err})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (r *runner) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// Deliver implements Sink
+func (r *runner) Deliver(ctx context.Context, e cloudevents.Event) error {
+	r.mu.Lock()
+	config := r.config
+	r.mu.Unlock()
+
+	return r.manager.dispatcher.Dispatch(ctx, config.Sink, e, config.Mode)
+}
+
+// Ready implements Sink
+func (r *runner) Ready() {
+	r.setState(State{Ready: true})
+}
+
+func (r *runner) setConfig(config Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.config = config
+}
+
+// setState records the state of the source, and notifies the manager when it changed
+func (r *runner) setState(state State) {
+	r.mu.Lock()
+	changed := state.Ready != r.state.Ready || errorString(state.Err) != errorString(r.state.Err)
+	r.state = state
+	namespace, name := r.config.Namespace, r.config.Name
+	r.mu.Unlock()
+
+	if changed && r.manager.OnChange != nil {
+		r.manager.OnChange(namespace, name)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
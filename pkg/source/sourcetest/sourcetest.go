@@ -0,0 +1,132 @@
+// Package sourcetest provides a sink recording the events of sources, for the tests of sources.
+package sourcetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+// Timeout is how long the sink waits for sources
+const Timeout = 10 * time.Second
+
+// ErrUnavailable is the error of failed deliveries
+var ErrUnavailable = errors.New("sink unavailable")
+
+// Sink implements source.Sink, recording the events delivered to it
+type Sink struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+
+	events chan cloudevents.Event
+	ready  chan struct{}
+}
+
+// NewSink returns a sink accepting every event
+func NewSink() *Sink {
+	return &Sink{
+		events: make(chan cloudevents.Event, 100),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// Fail fails the next n deliveries
+func (s *Sink) Fail(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = n
+}
+
+// Attempts returns how many deliveries were attempted, failed or not
+func (s *Sink) Attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts
+}
+
+// Deliver implements source.Sink
+func (s *Sink) Deliver(ctx context.Context, e cloudevents.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.failures > 0 {
+		s.failures--
+		return ErrUnavailable
+	}
+	s.events <- e
+
+	return nil
+}
+
+// Ready implements source.Sink
+func (s *Sink) Ready() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Next returns the next delivered event, failing the test when none is delivered in time
+func (s *Sink) Next(t *testing.T) cloudevents.Event {
+	t.Helper()
+
+	select {
+	case e := <-s.events:
+		return e
+	case <-time.After(Timeout):
+		t.Fatalf("no event delivered after %s", Timeout)
+		return cloudevents.Event{}
+	}
+}
+
+// None fails the test when an event is delivered within d
+func (s *Sink) None(t *testing.T, d time.Duration) {
+	t.Helper()
+
+	select {
+	case e := <-s.events:
+		t.Errorf("got unexpected event %+v", e)
+	case <-time.After(d):
+	}
+}
+
+// Run runs src until the test ends, and waits for it to be ready. The returned channel
+// receives the error src returned.
+func (s *Sink) Run(t *testing.T, src source.Source) <-chan error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		done <- src.Run(ctx, s)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-stopped:
+		case <-time.After(Timeout):
+			t.Errorf("the source did not stop after %s", Timeout)
+		}
+	})
+
+	select {
+	case <-s.ready:
+	case err := <-done:
+		t.Fatalf("the source stopped before it was ready: %v", err)
+	case <-time.After(Timeout):
+		t.Fatalf("the source was not ready after %s", Timeout)
+	}
+
+	return done
+}
@@ -0,0 +1,268 @@
+// Package storagequeue implements the source of the storagequeue provider: it polls an Azure
+// Storage queue and emits its messages as CloudEvents, deleting them once they were delivered.
+package storagequeue
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-storage-queue-go/azqueue"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/eventgrid/receiver"
+	"github.com/radu-matei/events-operator/pkg/source"
+)
+
+const (
+	// MessageEventType is the CloudEvents type of queue messages that are not Event Grid events
+	MessageEventType = "dev.events-operator.storagequeue.message"
+
+	// maxBatchSize is the maximum number of messages a dequeue returns
+	maxBatchSize = 32
+)
+
+// Source polls a queue
+type Source struct {
+	// Endpoint is the URL of the queue service, such as https://<account>.queue.core.windows.net
+	// or http://127.0.0.1:10001/devstoreaccount1 for Azurite
+	Endpoint *url.URL
+	// Queue is the name of the queue
+	Queue string
+	// AccountName is the name of the storage account, authenticated with AccountKey
+	AccountName string
+	// AccountKey is the shared key of the storage account. When it is empty, requests are authorized with Token.
+	AccountKey string
+	// Token is the token of the Azure AD service principal authorized to process the messages of the queue
+	Token *adal.ServicePrincipalToken
+	// VisibilityTimeout is how long dequeued messages are invisible, before they are dequeued again
+	// unless they were deleted
+	VisibilityTimeout time.Duration
+	// PollInterval is how long the source waits before polling an empty queue again
+	PollInterval time.Duration
+	// BatchSize is the maximum number of messages dequeued at once
+	BatchSize int32
+	// MaxDequeueCount is how many times a message is dequeued before it is moved to PoisonQueue
+	MaxDequeueCount int64
+	// PoisonQueue is the queue poison messages are moved to
+	PoisonQueue string
+	// EventGrid decodes the messages as the base64 encoded Event Grid events of an Event Grid
+	// subscription delivering to the queue
+	EventGrid bool
+	// Retry is how the delivery of messages is retried, before they are left in the queue
+	Retry source.Retry
+	// EventSource is the CloudEvents source of events, the queue is appended to it as a fragment
+	EventSource string
+}
+
+// Run implements source.Source. Messages are deleted once they were delivered. Messages that could
+// not be delivered are dequeued again once their visibility timeout expires, and moved to the poison
+// queue once they were dequeued more than MaxDequeueCount times.
+func (s *Source) Run(ctx context.Context, sink source.Sink) error {
+	credential, err := s.credential(ctx)
+	if err != nil {
+		return err
+	}
+	service := azqueue.NewServiceURL(*s.Endpoint, azqueue.NewPipeline(credential, azqueue.PipelineOptions{}))
+	queue := service.NewQueueURL(s.Queue)
+	messages := queue.NewMessagesURL()
+
+	if _, err := queue.GetProperties(ctx); err != nil {
+		return fmt.Errorf("cannot get queue %s: %v", s.Queue, err)
+	}
+	sink.Ready()
+
+	batchSize := s.BatchSize
+	if batchSize <= 0 || batchSize > maxBatchSize {
+		batchSize = maxBatchSize
+	}
+	for {
+		dequeued, err := messages.Dequeue(ctx, batchSize, s.VisibilityTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("cannot dequeue messages of queue %s: %v", s.Queue, err)
+		}
+
+		for i := int32(0); i < dequeued.NumMessages(); i++ {
+			if err := s.handle(ctx, service, messages, sink, dequeued.Message(i)); err != nil {
+				return err
+			}
+		}
+
+		if dequeued.NumMessages() < batchSize {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(s.PollInterval):
+			}
+		}
+	}
+}
+
+// handle delivers a message and deletes it, or moves it to the poison queue once it was dequeued too many times
+func (s *Source) handle(ctx context.Context, service azqueue.ServiceURL, messages azqueue.MessagesURL, sink source.Sink, m *azqueue.DequeuedMessage) error {
+	if m.DequeueCount > s.MaxDequeueCount {
+		glog.Warningf("moving message %s of queue %s to poison queue %s after %d dequeues", m.ID, s.Queue, s.PoisonQueue, m.DequeueCount)
+		return s.poison(ctx, service, messages, m)
+	}
+
+	e, err := s.event(m)
+	if err != nil {
+		glog.Warningf("moving message %s of queue %s to poison queue %s: %v", m.ID, s.Queue, s.PoisonQueue, err)
+		return s.poison(ctx, service, messages, m)
+	}
+
+	message := messages.NewMessageIDURL(m.ID)
+	popReceipt, err := s.deliver(ctx, message, sink, m, e)
+	if err != nil {
+		if ctx.Err() == nil {
+			glog.Errorf("leaving message %s in queue %s (dequeue %d): %v", m.ID, s.Queue, m.DequeueCount, err)
+		}
+		return nil
+	}
+
+	if _, err := message.Delete(ctx, popReceipt); err != nil && ctx.Err() == nil {
+		// the visibility timeout expired, the message will be delivered again
+		glog.Errorf("cannot delete message %s of queue %s: %v", m.ID, s.Queue, err)
+	}
+
+	return nil
+}
+
+// deliver delivers the event of a message, renewing its visibility timeout while the delivery is retried,
+// and returns the pop receipt of the message, which changes with every renewal
+func (s *Source) deliver(ctx context.Context, message azqueue.MessageIDURL, sink source.Sink, m *azqueue.DequeuedMessage, e cloudevents.Event) (azqueue.PopReceipt, error) {
+	// the visibility timeout is set in seconds
+	if s.VisibilityTimeout < 2*time.Second {
+		return m.PopReceipt, s.Retry.Deliver(ctx, sink, e)
+	}
+
+	popReceipt := m.PopReceipt
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(s.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				updated, err := message.Update(ctx, popReceipt, s.VisibilityTimeout, m.Text)
+				if err != nil {
+					if ctx.Err() == nil {
+						glog.Warningf("cannot renew the visibility timeout of message %s of queue %s: %v", m.ID, s.Queue, err)
+					}
+					continue
+				}
+				popReceipt = updated.PopReceipt
+			}
+		}
+	}()
+
+	err := s.Retry.Deliver(ctx, sink, e)
+	close(done)
+	<-renewed
+
+	return popReceipt, err
+}
+
+// poison moves a message to the poison queue, created when it does not exist
+func (s *Source) poison(ctx context.Context, service azqueue.ServiceURL, messages azqueue.MessagesURL, m *azqueue.DequeuedMessage) error {
+	poison := service.NewQueueURL(s.PoisonQueue)
+	if _, err := poison.Create(ctx, azqueue.Metadata{}); err != nil {
+		if serr, ok := err.(azqueue.StorageError); !ok || serr.ServiceCode() != azqueue.ServiceCodeQueueAlreadyExists {
+			return fmt.Errorf("cannot create poison queue %s: %v", s.PoisonQueue, err)
+		}
+	}
+
+	// poison messages do not expire
+	if _, err := poison.NewMessagesURL().Enqueue(ctx, m.Text, 0, -time.Second); err != nil {
+		return fmt.Errorf("cannot move message %s to poison queue %s: %v", m.ID, s.PoisonQueue, err)
+	}
+	if _, err := messages.NewMessageIDURL(m.ID).Delete(ctx, m.PopReceipt); err != nil {
+		glog.Errorf("cannot delete poison message %s of queue %s: %v", m.ID, s.Queue, err)
+	}
+
+	return nil
+}
+
+// event returns the CloudEvent of a message. Event Grid events are converted the same way the
+// gateway converts the events it receives, and events in the CloudEvents schema are kept as is.
+// Other messages are emitted with their text as data.
+func (s *Source) event(m *azqueue.DequeuedMessage) (cloudevents.Event, error) {
+	if !s.EventGrid {
+		e := cloudevents.New(string(m.ID), s.EventSource+"#"+s.Queue, MessageEventType)
+		e.Time = m.InsertionTime
+		// message texts are UTF-8 strings
+		contentType := "text/plain; charset=utf-8"
+		if json.Valid([]byte(m.Text)) {
+			contentType = "application/json"
+		}
+		e.SetData(contentType, []byte(m.Text))
+		return e, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(m.Text)
+	if err != nil {
+		return cloudevents.Event{}, fmt.Errorf("cannot decode Event Grid message: %v", err)
+	}
+
+	var attributes struct {
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(b, &attributes); err != nil {
+		return cloudevents.Event{}, fmt.Errorf("cannot decode Event Grid event: %v", err)
+	}
+	if attributes.SpecVersion != "" {
+		var e cloudevents.Event
+		if err := json.Unmarshal(b, &e); err != nil {
+			return cloudevents.Event{}, fmt.Errorf("cannot decode CloudEvent: %v", err)
+		}
+		return e, nil
+	}
+
+	var e receiver.Event
+	if err := json.Unmarshal(b, &e); err != nil {
+		return cloudevents.Event{}, fmt.Errorf("cannot decode Event Grid event: %v", err)
+	}
+	return e.CloudEvent(), nil
+}
+
+// credential returns the credential requests to the queue are authorized with. Tokens are refreshed
+// until ctx is done.
+func (s *Source) credential(ctx context.Context) (azqueue.Credential, error) {
+	if s.AccountKey != "" {
+		credential, err := azqueue.NewSharedKeyCredential(s.AccountName, s.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage account key: %v", err)
+		}
+		return credential, nil
+	}
+
+	if err := s.Token.EnsureFreshWithContext(ctx); err != nil {
+		return nil, fmt.Errorf("cannot get Azure AD token: %v", err)
+	}
+	return azqueue.NewTokenCredential(s.Token.OAuthToken(), func(credential azqueue.TokenCredential) time.Duration {
+		if ctx.Err() != nil {
+			return 0
+		}
+		if err := s.Token.EnsureFreshWithContext(ctx); err != nil {
+			glog.Errorf("cannot refresh Azure AD token of queue %s: %v", s.Queue, err)
+			return time.Minute
+		}
+		credential.SetToken(s.Token.OAuthToken())
+		// EnsureFresh refreshes tokens expiring within 5 minutes
+		if next := time.Until(s.Token.Token().Expires()) - 5*time.Minute; next > time.Minute {
+			return next
+		}
+		return time.Minute
+	}), nil
+}
@@ -0,0 +1,326 @@
+package storagequeue
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-queue-go/azqueue"
+	"github.com/radu-matei/events-operator/pkg/source"
+	"github.com/radu-matei/events-operator/pkg/source/sourcetest"
+)
+
+const testAccount = "devstoreaccount1"
+
+// queueService is an in-memory Azure Storage queue service. It is stricter than Azure: a message
+// can only be deleted or updated with its last pop receipt while it is invisible.
+type queueService struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	queues  map[string][]*queueMessage
+	nextID  int
+	updates int
+	// deleted are the messages deleted from their queue
+	deleted []queueMessage
+}
+
+// queueMessage is a message stored in a queue
+type queueMessage struct {
+	id           string
+	text         string
+	inserted     time.Time
+	popReceipt   string
+	visibleAt    time.Time
+	dequeueCount int64
+}
+
+func newQueueService(t *testing.T, queues ...string) *queueService {
+	s := &queueService{queues: map[string][]*queueMessage{}}
+	for _, queue := range queues {
+		s.queues[queue] = nil
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *queueService) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// /<account>/<queue>[/messages[/<id>]]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"+testAccount+"/"), "/")
+	queue := parts[0]
+	messages, exists := s.queues[queue]
+	query := r.URL.Query()
+	now := time.Now()
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.queues[queue] = nil
+		w.WriteHeader(http.StatusCreated)
+	case !exists:
+		storageError(w, http.StatusNotFound, "QueueNotFound")
+	case len(parts) == 1 && r.Method == http.MethodGet && query.Get("comp") == "metadata":
+		w.Header().Set("x-ms-approximate-messages-count", strconv.Itoa(len(messages)))
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		n, _ := strconv.Atoi(query.Get("numofmessages"))
+		visibilityTimeout, _ := strconv.Atoi(query.Get("visibilitytimeout"))
+		var list struct {
+			XMLName xml.Name                      `xml:"QueueMessagesList"`
+			Items   []azqueue.DequeuedMessageItem `xml:"QueueMessage"`
+		}
+		for _, m := range messages {
+			if len(list.Items) == n || m.visibleAt.After(now) {
+				continue
+			}
+			m.popReceipt = s.newID("receipt")
+			m.visibleAt = now.Add(time.Duration(visibilityTimeout) * time.Second)
+			m.dequeueCount++
+			list.Items = append(list.Items, azqueue.DequeuedMessageItem{
+				MessageID:       m.id,
+				InsertionTime:   m.inserted,
+				ExpirationTime:  m.inserted.Add(7 * 24 * time.Hour),
+				PopReceipt:      m.popReceipt,
+				TimeNextVisible: m.visibleAt,
+				DequeueCount:    m.dequeueCount,
+				MessageText:     m.text,
+			})
+		}
+		xml.NewEncoder(w).Encode(list)
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var body struct {
+			MessageText string `xml:"MessageText"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&body); err != nil {
+			storageError(w, http.StatusBadRequest, "InvalidXmlDocument")
+			return
+		}
+		m := &queueMessage{id: s.newID("message"), text: body.MessageText, inserted: now}
+		s.queues[queue] = append(messages, m)
+		w.WriteHeader(http.StatusCreated)
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name                  `xml:"QueueMessagesList"`
+			Items   []azqueue.EnqueuedMessage `xml:"QueueMessage"`
+		}{Items: []azqueue.EnqueuedMessage{{MessageID: m.id, InsertionTime: now, ExpirationTime: now, PopReceipt: s.newID("receipt"), TimeNextVisible: now}}})
+	case len(parts) == 3:
+		i := -1
+		for j, m := range messages {
+			if m.id == parts[2] {
+				i = j
+			}
+		}
+		if i < 0 {
+			storageError(w, http.StatusNotFound, "MessageNotFound")
+			return
+		}
+		m := messages[i]
+		if m.popReceipt != query.Get("popreceipt") || !m.visibleAt.After(now) {
+			storageError(w, http.StatusBadRequest, "PopReceiptMismatch")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodDelete:
+			s.queues[queue] = append(messages[:i:i], messages[i+1:]...)
+			s.deleted = append(s.deleted, *m)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPut:
+			visibilityTimeout, _ := strconv.Atoi(query.Get("visibilitytimeout"))
+			m.popReceipt = s.newID("receipt")
+			m.visibleAt = now.Add(time.Duration(visibilityTimeout) * time.Second)
+			s.updates++
+			w.Header().Set("x-ms-popreceipt", m.popReceipt)
+			w.Header().Set("x-ms-time-next-visible", m.visibleAt.UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *queueService) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d", prefix, s.nextID)
+}
+
+// storageError answers an error of the storage service
+func storageError(w http.ResponseWriter, statusCode int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// enqueue adds a message to a queue
+func (s *queueService) enqueue(queue, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queues[queue] = append(s.queues[queue], &queueMessage{id: s.newID("message"), text: text, inserted: time.Now()})
+}
+
+// texts returns the texts of the messages of a queue
+func (s *queueService) texts(queue string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var texts []string
+	for _, m := range s.queues[queue] {
+		texts = append(texts, m.text)
+	}
+	return texts
+}
+
+// waitFor waits until cond is true, failing the test when it does not become true in time
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(sourcetest.Timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s after %s", what, sourcetest.Timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestSource(s *queueService) *Source {
+	endpoint, _ := url.Parse(s.URL + "/" + testAccount)
+	return &Source{
+		Endpoint:          endpoint,
+		Queue:             "orders",
+		AccountName:       testAccount,
+		AccountKey:        base64.StdEncoding.EncodeToString([]byte("key")),
+		VisibilityTimeout: 2 * time.Second,
+		PollInterval:      20 * time.Millisecond,
+		MaxDequeueCount:   5,
+		PoisonQueue:       "orders-poison",
+		Retry:             source.Retry{Attempts: 1},
+		EventSource:       "/storagequeue",
+	}
+}
+
+func TestRun(t *testing.T) {
+	s := newQueueService(t, "orders")
+	sink := sourcetest.NewSink()
+	sink.Run(t, newTestSource(s))
+
+	s.enqueue("orders", `{"id":1}`)
+	e := sink.Next(t)
+	if e.ID == "" || e.Source != "/storagequeue#orders" || e.Type != MessageEventType || e.Time.IsZero() {
+		t.Errorf("got event %+v", e)
+	}
+	if string(e.Data) != `{"id":1}` || e.DataContentType != "application/json" {
+		t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+	}
+
+	s.enqueue("orders", "shipped")
+	if e := sink.Next(t); string(e.Data) != "shipped" || e.DataContentType != "text/plain; charset=utf-8" {
+		t.Errorf("got data %s of type %s", e.Data, e.DataContentType)
+	}
+
+	// delivered messages are deleted
+	waitFor(t, "delivered messages not deleted", func() bool { return len(s.texts("orders")) == 0 })
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	s := newQueueService(t, "orders")
+	src := newTestSource(s)
+	// the delivery is retried for longer than the visibility timeout
+	src.Retry = source.Retry{Attempts: 4, Backoff: 500 * time.Millisecond}
+	sink := sourcetest.NewSink()
+	sink.Run(t, src)
+
+	sink.Fail(3)
+	s.enqueue("orders", "slow")
+	if e := sink.Next(t); string(e.Data) != "slow" {
+		t.Errorf("got event %+v", e)
+	}
+	waitFor(t, "the message was not deleted", func() bool { return len(s.texts("orders")) == 0 })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.updates == 0 {
+		t.Errorf("the visibility timeout was not renewed")
+	}
+	if len(s.deleted) != 1 || s.deleted[0].dequeueCount != 1 {
+		t.Errorf("the message was dequeued again while it was delivered: %+v", s.deleted)
+	}
+}
+
+func TestPoison(t *testing.T) {
+	s := newQueueService(t, "orders")
+	src := newTestSource(s)
+	// messages are not renewed, and dequeued again right after their delivery failed
+	src.VisibilityTimeout = time.Second
+	src.MaxDequeueCount = 2
+	sink := sourcetest.NewSink()
+	sink.Run(t, src)
+
+	// messages that cannot be delivered are left in the queue, until they were dequeued too many times
+	sink.Fail(2)
+	s.enqueue("orders", "undeliverable")
+	waitFor(t, "the message was not moved to the poison queue", func() bool { return len(s.texts("orders-poison")) == 1 })
+	if texts := s.texts("orders-poison"); texts[0] != "undeliverable" || sink.Attempts() != 2 {
+		t.Errorf("got poison messages %v after %d attempts", texts, sink.Attempts())
+	}
+	waitFor(t, "the poison message was not deleted", func() bool { return len(s.texts("orders")) == 0 })
+
+	// messages that are not Event Grid events are poison right away
+	src.EventGrid = true
+	sink = sourcetest.NewSink()
+	sink.Run(t, src)
+	s.enqueue("orders", "not an event")
+	waitFor(t, "the message was not moved to the poison queue", func() bool { return len(s.texts("orders-poison")) == 2 })
+	if sink.Attempts() != 0 {
+		t.Errorf("got %d attempts", sink.Attempts())
+	}
+}
+
+func TestEvent(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	src := &Source{Queue: "orders", EventGrid: true, EventSource: "/storagequeue"}
+
+	// Event Grid events are converted as the gateway converts them
+	e, err := src.event(&azqueue.DequeuedMessage{Text: encode(`{"id":"1","topic":"/subscriptions/s/resourceGroups/g/providers/Microsoft.Storage/storageAccounts/a","subject":"/blobServices/default/containers/c/blobs/b","eventType":"Microsoft.Storage.BlobCreated","eventTime":"2020-01-02T03:04:05Z","data":{"api":"PutBlob"},"dataVersion":"1"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "1" || e.Type != "Microsoft.Storage.BlobCreated" || e.Source != "/subscriptions/s/resourceGroups/g/providers/Microsoft.Storage/storageAccounts/a" || e.Subject != "/blobServices/default/containers/c/blobs/b" {
+		t.Errorf("got event %+v", e)
+	}
+	if string(e.Data) != `{"api":"PutBlob"}` || e.DataContentType != "application/json" || e.Extensions["dataversion"] != "1" {
+		t.Errorf("got data %s of type %s and extensions %v", e.Data, e.DataContentType, e.Extensions)
+	}
+
+	// events in the CloudEvents schema are kept as is
+	e, err = src.event(&azqueue.DequeuedMessage{Text: encode(`{"specversion":"1.0","id":"2","source":"/orders","type":"order.created","datacontenttype":"application/json","data":{"id":2}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "2" || e.Source != "/orders" || e.Type != "order.created" || string(e.Data) != `{"id":2}` {
+		t.Errorf("got event %+v", e)
+	}
+
+	for _, text := range []string{"not base64!", encode("not json")} {
+		if _, err := src.event(&azqueue.DequeuedMessage{Text: text}); err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+}
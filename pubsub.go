@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/pubsub"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// syncPubSub makes sure the push subscription of the eventprovider pushes the messages
+// of its topic to the event gateway, which verifies the OIDC token of every push
+func (c *Controller) syncPubSub(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.PubSub
+	if ep.Spec.Sink == nil {
+		return fmt.Errorf("the pubsub provider requires a sink")
+	}
+	if spec == nil {
+		return fmt.Errorf("the pubsub provider requires a pubsub spec")
+	}
+	if spec.Project == "" || spec.Topic == "" {
+		return fmt.Errorf("a Pub/Sub project and topic are required")
+	}
+
+	key, err := c.pubsubKey(ep)
+	if err != nil {
+		return err
+	}
+	client, err := pubsub.NewClient(spec.Endpoint, key)
+	if err != nil {
+		return err
+	}
+
+	// the subscription must be deleted with the eventprovider
+	if ep, err = c.ensureFinalizer(ep); err != nil {
+		return err
+	}
+
+	var pushConfig pubsub.PushConfig
+	var auth gateway.Authenticator = gateway.PathAuthenticator{}
+	if !spec.InsecureEmulator {
+		serviceAccount := spec.ServiceAccount
+		if serviceAccount == "" {
+			serviceAccount = key.ClientEmail
+		}
+		audience := pubsubAudience(ep)
+
+		pushConfig.OIDCToken = &pubsub.OIDCToken{ServiceAccountEmail: serviceAccount, Audience: audience}
+		auth = pubsub.NewPushVerifier(serviceAccount, audience)
+	}
+
+	ep, endpointURL, err := c.syncEndpoint(ep, gateway.Route{
+		Auth:   auth,
+		Source: fmt.Sprintf("//pubsub.googleapis.com/projects/%s/topics/%s", spec.Project, spec.Topic),
+	})
+	if err != nil || endpointURL == "" {
+		return err
+	}
+	pushConfig.PushEndpoint = endpointURL
+
+	ctx := context.TODO()
+	name := pubsubSubscriptionName(ep)
+	topic := fmt.Sprintf("projects/%s/topics/%s", spec.Project, spec.Topic)
+
+	current, err := client.GetSubscription(ctx, name)
+	switch {
+	case pubsub.IsNotFound(err):
+		_, err = client.CreateSubscription(ctx, pubsub.Subscription{
+			Name:       name,
+			Topic:      topic,
+			PushConfig: pushConfig,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create Pub/Sub subscription %s: %v", name, err)
+		}
+		glog.Infof("created Pub/Sub subscription %s for eventprovider %s/%s", name, ep.Namespace, ep.Name)
+
+	case err != nil:
+		return fmt.Errorf("cannot get Pub/Sub subscription %s: %v", name, err)
+
+	case current.Topic != topic:
+		return fmt.Errorf("Pub/Sub subscription %s is subscribed to %s, not %s", name, current.Topic, topic)
+
+	case !samePushConfig(current.PushConfig, pushConfig):
+		if err := client.ModifyPushConfig(ctx, name, pushConfig); err != nil {
+			return fmt.Errorf("cannot update Pub/Sub subscription %s: %v", name, err)
+		}
+		glog.Infof("updated Pub/Sub subscription %s for eventprovider %s/%s", name, ep.Namespace, ep.Name)
+	}
+
+	_, err = c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		status.HookID = name
+	})
+	if err != nil {
+		return fmt.Errorf("cannot update eventprovider status: %v", err)
+	}
+
+	return nil
+}
+
+// finalizePubSub deletes the subscription of a deleted eventprovider
+func (c *Controller) finalizePubSub(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.PubSub == nil || ep.Status.HookID == "" {
+		return nil
+	}
+
+	key, err := c.pubsubKey(ep)
+	if errors.IsNotFound(err) {
+		// without the key the subscription can never be deleted, do not hold the eventprovider forever
+		glog.Warningf("cannot delete Pub/Sub subscription %s of eventprovider %s/%s, its secret is gone", ep.Status.HookID, ep.Namespace, ep.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	client, err := pubsub.NewClient(ep.Spec.PubSub.Endpoint, key)
+	if err != nil {
+		return err
+	}
+
+	if err := client.DeleteSubscription(context.TODO(), ep.Status.HookID); err != nil && !pubsub.IsNotFound(err) {
+		return fmt.Errorf("cannot delete Pub/Sub subscription %s: %v", ep.Status.HookID, err)
+	}
+	glog.Infof("deleted Pub/Sub subscription %s of eventprovider %s/%s", ep.Status.HookID, ep.Namespace, ep.Name)
+
+	return nil
+}
+
+// pubsubKey returns the service account key of the eventprovider, which is optional with the emulator
+func (c *Controller) pubsubKey(ep *v1alpha1.EventProvider) (*pubsub.ServiceAccountKey, error) {
+	spec := ep.Spec.PubSub
+	if spec.InsecureEmulator && spec.Endpoint == "" {
+		return nil, fmt.Errorf("the endpoint of the emulator is required with insecureEmulator")
+	}
+	if spec.SecretName == "" {
+		if !spec.InsecureEmulator {
+			return nil, fmt.Errorf("a service account key secret is required, unless insecureEmulator is set")
+		}
+		return nil, nil
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+	if err != nil {
+		return nil, err
+	}
+	b, err := secretKey(secret, "key.json")
+	if err != nil {
+		return nil, err
+	}
+
+	return pubsub.ParseServiceAccountKey(b)
+}
+
+// pubsubSubscriptionName returns the full name of the subscription of the eventprovider
+func pubsubSubscriptionName(ep *v1alpha1.EventProvider) string {
+	name := ep.Spec.PubSub.Subscription
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", ep.Namespace, ep.Name)
+	}
+
+	return fmt.Sprintf("projects/%s/subscriptions/%s", ep.Spec.PubSub.Project, name)
+}
+
+// pubsubAudience returns the audience of the OIDC token of pushes
+func pubsubAudience(ep *v1alpha1.EventProvider) string {
+	if ep.Spec.PubSub.Audience != "" {
+		return ep.Spec.PubSub.Audience
+	}
+
+	return eventProviderURI(ep)
+}
+
+// samePushConfig compares the push configuration of a subscription with the desired one
+func samePushConfig(current, desired pubsub.PushConfig) bool {
+	if current.PushEndpoint != desired.PushEndpoint {
+		return false
+	}
+	if current.OIDCToken == nil || desired.OIDCToken == nil {
+		return current.OIDCToken == nil && desired.OIDCToken == nil
+	}
+
+	return *current.OIDCToken == *desired.OIDCToken
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+)
+
+func TestPubSubKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  v1alpha1.PubSubSpec
+		valid bool
+	}{
+		{name: "no secret", spec: v1alpha1.PubSubSpec{}},
+		{name: "emulator endpoint without opt-in", spec: v1alpha1.PubSubSpec{Endpoint: "http://emulator:8085"}},
+		{name: "opt-in without endpoint", spec: v1alpha1.PubSubSpec{InsecureEmulator: true}},
+		{name: "emulator", spec: v1alpha1.PubSubSpec{Endpoint: "http://emulator:8085", InsecureEmulator: true}, valid: true},
+		{name: "missing secret", spec: v1alpha1.PubSubSpec{SecretName: "missing"}},
+	}
+
+	c, _ := newTestController(t)
+	for _, tt := range tests {
+		ep := newTestEventProvider("orders")
+		ep.Spec.PubSub = &tt.spec
+
+		key, err := c.pubsubKey(ep)
+		if tt.valid && (err != nil || key != nil) {
+			t.Errorf("%s: got key %v and error %v", tt.name, key, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// receiverPort is the port the receiver sidecar listens on
+	receiverPort = 9180
+	// receiverPortName is the name of the receiver container port, targeted by the handler service
+	receiverPortName = "receiver"
+)
+
+// newReceiverContainer creates the receiver sidecar, which answers the Event Grid
+// handshakes on behalf of the handler and forwards it the actual events
+func newReceiverContainer(ep *v1alpha1.EventProvider, config ControllerConfig) corev1.Container {
+	return corev1.Container{
+		Name:    receiverPortName,
+		Image:   config.ReceiverImage,
+		Command: []string{"./receiver"},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "RECEIVER_ADDR",
+				Value: fmt.Sprintf(":%d", receiverPort),
+			},
+			{
+				Name:  "HANDLER_URL",
+				Value: fmt.Sprintf("http://localhost:%d", handlerPort(ep)),
+			},
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          receiverPortName,
+				Protocol:      corev1.ProtocolTCP,
+				ContainerPort: receiverPort,
+			},
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/redisstreams"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// providerRedisStreams is the provider name of eventproviders reading Redis streams
+	providerRedisStreams = "redis-streams"
+
+	// defaultClaimMinIdle is how long stream entries stay pending before they are reclaimed, by default
+	defaultClaimMinIdle = time.Minute
+	// defaultStreamCount is how many stream entries are read at once, by default
+	defaultStreamCount = 100
+	// defaultStreamMaxDeliveryCount is how many times a stream entry is delivered before it is given up on, by default
+	defaultStreamMaxDeliveryCount = 5
+)
+
+// syncRedisStreams runs the consumer of the eventprovider. Every replica of the operator is
+// a consumer of the group, named after its hostname, so entries are shared between them.
+func (c *Controller) syncRedisStreams(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.RedisStreams
+	if spec == nil {
+		return fmt.Errorf("the redis-streams provider requires a redisStreams spec")
+	}
+	if spec.Address == "" || spec.Stream == "" {
+		return fmt.Errorf("a Redis address and stream are required")
+	}
+	if spec.DeadLetterStream == spec.Stream {
+		return fmt.Errorf("the dead-letter stream must not be the stream %s", spec.Stream)
+	}
+
+	var earliest bool
+	switch spec.InitialPosition {
+	case "", "Latest":
+	case "Earliest":
+		earliest = true
+	default:
+		return fmt.Errorf("unknown initial position %q, expected Latest or Earliest", spec.InitialPosition)
+	}
+
+	consumer, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("cannot get the identity of the operator replica: %v", err)
+	}
+
+	src := &redisstreams.Source{
+		Options:          &redis.Options{Addr: spec.Address, DB: int(spec.DB)},
+		Stream:           spec.Stream,
+		Group:            spec.ConsumerGroup,
+		Consumer:         consumer,
+		Earliest:         earliest,
+		ClaimMinIdle:     defaultClaimMinIdle,
+		Count:            defaultStreamCount,
+		Retry:            retryPolicy(spec.Retry),
+		MaxDeliveryCount: defaultStreamMaxDeliveryCount,
+		DeadLetterStream: spec.DeadLetterStream,
+		EventSource:      eventProviderURI(ep),
+	}
+	if src.Group == "" {
+		src.Group = fmt.Sprintf("%s-%s", ep.Namespace, ep.Name)
+	}
+	if spec.ClaimMinIdle != nil && spec.ClaimMinIdle.Duration > 0 {
+		src.ClaimMinIdle = spec.ClaimMinIdle.Duration
+	}
+	if spec.Count > 0 {
+		src.Count = int64(spec.Count)
+	}
+	if spec.MaxDeliveryCount > 0 {
+		src.MaxDeliveryCount = int64(spec.MaxDeliveryCount)
+	}
+
+	var credentialsVersion string
+	if spec.SecretName != "" {
+		secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+		if err != nil {
+			return fmt.Errorf("cannot get credentials secret %s: %v", spec.SecretName, err)
+		}
+		password, err := secretKey(secret, "password")
+		if err != nil {
+			return err
+		}
+		src.Options.Username = string(secret.Data["username"])
+		src.Options.Password = string(password)
+		credentialsVersion = secret.ResourceVersion
+	}
+
+	tlsConfig, tlsVersion, err := c.clientTLS(ep, spec.TLS)
+	if err != nil {
+		return err
+	}
+	src.Options.TLSConfig = tlsConfig
+
+	return c.syncSource(ep, src, spec, credentialsVersion, tlsVersion)
+}
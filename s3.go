@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/s3"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// syncS3 exposes the endpoint of the eventprovider, and makes sure a webhook target of its MinIO
+// deployment delivers to it, and its bucket notifies the target. The event gateway verifies the
+// token of the target, and dispatches every event record of a notification as a CloudEvent.
+func (c *Controller) syncS3(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.S3
+	if ep.Spec.Sink == nil {
+		return fmt.Errorf("the s3 provider requires a sink")
+	}
+	if spec == nil {
+		return fmt.Errorf("the s3 provider requires an s3 spec")
+	}
+	if spec.Endpoint == "" || spec.Bucket == "" {
+		return fmt.Errorf("an S3 endpoint and bucket are required")
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+	if err != nil {
+		return fmt.Errorf("cannot get S3 secret %s/%s: %v", ep.Namespace, spec.SecretName, err)
+	}
+	client, err := s3Client(spec, secret)
+	if err != nil {
+		return err
+	}
+	token, err := secretKey(secret, "webhookToken")
+	if err != nil {
+		return err
+	}
+
+	// the notifications and the webhook target must be deleted with the eventprovider
+	if ep, err = c.ensureFinalizer(ep); err != nil {
+		return err
+	}
+
+	ep, endpointURL, err := c.syncEndpoint(ep, gateway.Route{
+		Auth:   gateway.BearerAuthenticator{Token: string(token)},
+		Source: fmt.Sprintf("arn:aws:s3:::%s", spec.Bucket),
+	})
+	if err != nil || endpointURL == "" {
+		return err
+	}
+
+	events := spec.Events
+	if len(events) == 0 {
+		events = []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"}
+	}
+
+	return c.syncHook(ep, &s3Hooks{
+		client:     client,
+		target:     s3TargetID(ep),
+		endpoint:   endpointURL,
+		token:      string(token),
+		bucket:     spec.Bucket,
+		hookBucket: s3HookBucket(ep),
+		events:     events,
+		prefix:     spec.Prefix,
+		suffix:     spec.Suffix,
+	}, secret.ResourceVersion)
+}
+
+// finalizeS3 removes the notifications of the bucket and the webhook target of a deleted eventprovider
+func (c *Controller) finalizeS3(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.S3
+	if spec == nil || ep.Status.HookID == "" {
+		return nil
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+	if errors.IsNotFound(err) {
+		// without credentials the notifications can never be removed, do not hold the eventprovider forever
+		glog.Warningf("cannot remove S3 webhook target %s of eventprovider %s/%s, its secret is gone", ep.Status.HookID, ep.Namespace, ep.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot get S3 secret %s/%s: %v", ep.Namespace, spec.SecretName, err)
+	}
+	client, err := s3Client(spec, secret)
+	if err != nil {
+		return err
+	}
+
+	hooks := &s3Hooks{client: client, hookBucket: s3HookBucket(ep)}
+	if err := hooks.delete(context.TODO(), ep.Status.HookID); err != nil {
+		return fmt.Errorf("cannot remove S3 webhook target %s: %v", ep.Status.HookID, err)
+	}
+	glog.Infof("removed S3 webhook target %s of eventprovider %s/%s", ep.Status.HookID, ep.Namespace, ep.Name)
+
+	return nil
+}
+
+// s3Client returns the client of the MinIO deployment of an eventprovider
+func s3Client(spec *v1alpha1.S3Spec, secret *corev1.Secret) (*s3.Client, error) {
+	accessKeyID, err := secretKey(secret, "accessKeyID")
+	if err != nil {
+		return nil, err
+	}
+	secretAccessKey, err := secretKey(secret, "secretAccessKey")
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewClient(spec.Endpoint, spec.Region, string(accessKeyID), string(secretAccessKey))
+}
+
+// s3HookBucket returns the bucket notifying the webhook target of the eventprovider, the one
+// recorded in its status, or the bucket of its spec for targets recorded before their bucket was
+func s3HookBucket(ep *v1alpha1.EventProvider) string {
+	if ep.Status.HookBucket != "" {
+		return ep.Status.HookBucket
+	}
+
+	return ep.Spec.S3.Bucket
+}
+
+// s3TargetID returns the ID of the webhook target of the eventprovider. Names cannot contain
+// underscores, so IDs of different eventproviders cannot collide.
+func s3TargetID(ep *v1alpha1.EventProvider) string {
+	return fmt.Sprintf("%s_%s", ep.Namespace, ep.Name)
+}
+
+// s3Hooks implements hookAPI for S3: hooks are webhook targets, identified by their ARN,
+// that the bucket notifies
+type s3Hooks struct {
+	client   *s3.Client
+	target   string
+	endpoint string
+	token    string
+	bucket   string
+	// hookBucket is the bucket notifying the existing target, which differs from bucket
+	// when the bucket of the eventprovider changed
+	hookBucket string
+	events     []string
+	prefix     string
+	suffix     string
+}
+
+func (h *s3Hooks) get(ctx context.Context, id string) (bool, bool, error) {
+	target, err := s3.ParseWebhookARN(id)
+	if err != nil {
+		return false, false, err
+	}
+
+	endpoint, found, err := h.client.WebhookTarget(ctx, target)
+	if err != nil || !found {
+		return false, false, err
+	}
+	notifies, err := h.client.BucketNotifies(ctx, h.bucket, id, h.events, h.prefix, h.suffix)
+	if err != nil {
+		return false, false, err
+	}
+
+	// the admin API never returns the token
+	upToDate := id == h.client.WebhookARN(h.target) && endpoint == h.endpoint && h.hookBucket == h.bucket && notifies
+
+	return true, upToDate, nil
+}
+
+func (h *s3Hooks) find(ctx context.Context) (string, error) {
+	_, found, err := h.client.WebhookTarget(ctx, h.target)
+	if err != nil || !found {
+		return "", err
+	}
+
+	return h.client.WebhookARN(h.target), nil
+}
+
+func (h *s3Hooks) create(ctx context.Context) (string, error) {
+	arn := h.client.WebhookARN(h.target)
+	// the bucket of the eventprovider changed, the previous one must not notify the target anymore
+	if h.hookBucket != "" && h.hookBucket != h.bucket {
+		if err := h.removeBucketQueue(ctx, arn); err != nil {
+			return "", err
+		}
+	}
+
+	restart, err := h.client.SetWebhookTarget(ctx, h.target, h.endpoint, h.token)
+	if err != nil {
+		return "", err
+	}
+	if restart {
+		// the bucket cannot notify the target until then
+		glog.Warningf("MinIO must be restarted to apply webhook target %s", h.target)
+	}
+
+	if err := h.client.SetBucketQueue(ctx, h.bucket, arn, h.events, h.prefix, h.suffix); err != nil {
+		return "", fmt.Errorf("cannot configure notifications of bucket %s: %v", h.bucket, err)
+	}
+
+	return arn, nil
+}
+
+func (h *s3Hooks) update(ctx context.Context, id string) error {
+	// the target of the eventprovider changed, as its region did
+	if id != h.client.WebhookARN(h.target) {
+		if err := h.delete(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	_, err := h.create(ctx)
+	return err
+}
+
+func (h *s3Hooks) delete(ctx context.Context, id string) error {
+	target, err := s3.ParseWebhookARN(id)
+	if err != nil {
+		return err
+	}
+
+	if err := h.removeBucketQueue(ctx, id); err != nil {
+		return err
+	}
+	if _, err := h.client.DeleteWebhookTarget(ctx, target); err != nil {
+		return fmt.Errorf("cannot delete webhook target %s: %v", target, err)
+	}
+
+	return nil
+}
+
+// removeBucketQueue removes the notifications of the target with the given ID from the bucket
+// notifying it, a missing bucket notifies nothing
+func (h *s3Hooks) removeBucketQueue(ctx context.Context, id string) error {
+	if err := h.client.RemoveBucketQueue(ctx, h.hookBucket, id); err != nil && !s3.IsNoSuchBucket(err) {
+		return fmt.Errorf("cannot remove notifications of bucket %s: %v", h.hookBucket, err)
+	}
+
+	return nil
+}
+
+func (h *s3Hooks) updateStatus(status *v1alpha1.EventProviderStatus) {
+	status.HookBucket = h.bucket
+}
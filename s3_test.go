@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testS3AccessKeyID     = "minio"
+	testS3SecretAccessKey = "minio-secret"
+	testS3OtherARN        = "arn:minio:sqs:us-east-1:other:webhook"
+	// webhookTestSubsystem is the MinIO configuration subsystem of webhook targets
+	webhookTestSubsystem = "notify_webhook"
+)
+
+// fakeMinIO is an in-memory MinIO deployment, holding the endpoints of its webhook targets by ID,
+// and the notifications of its buckets
+type fakeMinIO struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	targets map[string]string
+	buckets map[string]*notification.Configuration
+}
+
+func newFakeMinIO(t *testing.T, buckets ...string) *fakeMinIO {
+	f := &fakeMinIO{targets: map[string]string{}, buckets: map[string]*notification.Configuration{}}
+	for _, bucket := range buckets {
+		f.buckets[bucket] = &notification.Configuration{}
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeMinIO) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/minio/admin/") {
+		f.serveAdmin(w, r)
+		return
+	}
+
+	config, ok := f.buckets[strings.Trim(r.URL.Path, "/")]
+	if !ok || !r.URL.Query().Has("notification") {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "<Error><Code>NoSuchBucket</Code></Error>")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		xml.NewEncoder(w).Encode(config)
+	case http.MethodPut:
+		config.QueueConfigs = nil
+		if err := xml.NewDecoder(r.Body).Decode(config); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeMinIO) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(madmin.ConfigAppliedHeader, madmin.ConfigAppliedTrue)
+
+	if strings.HasSuffix(r.URL.Path, "/get-config-kv") {
+		var config strings.Builder
+		for id, endpoint := range f.targets {
+			fmt.Fprintf(&config, "%s:%s enable=on endpoint=%s\n", webhookTestSubsystem, id, endpoint)
+		}
+		data, err := madmin.EncryptData(testS3SecretAccessKey, []byte(config.String()))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+		return
+	}
+
+	data, err := madmin.DecryptData(testS3SecretAccessKey, r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fields := strings.Fields(string(data))
+	id := strings.TrimPrefix(fields[0], webhookTestSubsystem+":")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/set-config-kv"):
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "endpoint=") {
+				f.targets[id] = strings.Trim(strings.TrimPrefix(field, "endpoint="), `"`)
+			}
+		}
+	case strings.HasSuffix(r.URL.Path, "/del-config-kv"):
+		delete(f.targets, id)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// queues returns the ARNs the bucket notifies
+func (f *fakeMinIO) queues(bucket string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var arns []string
+	for _, queue := range f.buckets[bucket].QueueConfigs {
+		arns = append(arns, queue.Queue)
+	}
+
+	return arns
+}
+
+// deleteTarget deletes a webhook target, as an administrator of the deployment would
+func (f *fakeMinIO) deleteTarget(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.targets, id)
+}
+
+func TestSyncS3Hook(t *testing.T) {
+	f := newFakeMinIO(t, "uploads", "archive")
+	// a notification of the bucket the operator does not own
+	other, err := notification.NewArnFromString(testS3OtherARN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.buckets["uploads"].AddQueue(notification.NewConfig(other))
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "minio"},
+		Data: map[string][]byte{
+			"accessKeyID":     []byte(testS3AccessKeyID),
+			"secretAccessKey": []byte(testS3SecretAccessKey),
+		},
+	}
+	ep := newTestEventProvider("uploads")
+	ep.Spec.S3 = &v1alpha1.S3Spec{Endpoint: f.URL, Region: "us-east-1", Bucket: "uploads", SecretName: secret.Name}
+	c, _ := newTestController(t, ep, secret)
+
+	client, err := s3Client(ep.Spec.S3, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arn := client.WebhookARN(s3TargetID(ep))
+	sync := func(bucket string) {
+		t.Helper()
+
+		ep.Spec.S3.Bucket = bucket
+		ep = syncTestHook(t, c, ep, &s3Hooks{
+			client:     client,
+			target:     s3TargetID(ep),
+			endpoint:   "https://events.example.com/uploads",
+			token:      "token",
+			bucket:     bucket,
+			hookBucket: s3HookBucket(ep),
+			events:     []string{"s3:ObjectCreated:*"},
+		}, "1")
+		if ep.Status.HookID != arn || ep.Status.HookBucket != bucket {
+			t.Fatalf("got status %+v", ep.Status)
+		}
+	}
+	// notifies checks the ARNs each bucket notifies
+	notifies := func(want map[string][]string) {
+		t.Helper()
+
+		for bucket, arns := range want {
+			if got := f.queues(bucket); strings.Join(got, ",") != strings.Join(arns, ",") {
+				t.Errorf("bucket %s notifies %v, want %v", bucket, got, arns)
+			}
+		}
+	}
+
+	sync("uploads")
+	notifies(map[string][]string{"uploads": {testS3OtherARN, arn}, "archive": nil})
+
+	// the bucket changed, the previous one stops notifying the target
+	sync("archive")
+	notifies(map[string][]string{"uploads": {testS3OtherARN}, "archive": {arn}})
+
+	// the target was deleted while the bucket changed, it is created again
+	f.deleteTarget(s3TargetID(ep))
+	sync("uploads")
+	notifies(map[string][]string{"uploads": {testS3OtherARN, arn}, "archive": nil})
+
+	// the recorded bucket is cleaned up, even though the spec changed
+	ep.Spec.S3.Bucket = "archive"
+	if err := c.finalizeS3(ep); err != nil {
+		t.Fatalf("cannot finalize: %v", err)
+	}
+	notifies(map[string][]string{"uploads": {testS3OtherARN}, "archive": nil})
+	if _, ok := f.targets[s3TargetID(ep)]; ok {
+		t.Errorf("the webhook target was not deleted")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/schedule"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// providerSchedule is the provider name of eventproviders emitting ticks on a schedule
+const providerSchedule = "schedule"
+
+// syncSchedule runs the schedule of the eventprovider. Ticks are claimed on a lease
+// named after the eventprovider, so that every replica of the operator runs the
+// schedule but only one emits each tick.
+func (c *Controller) syncSchedule(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.Schedule
+	if spec == nil {
+		return fmt.Errorf("the schedule provider requires a schedule spec")
+	}
+
+	s, err := schedule.Parse(spec.Crontab, spec.TimeZone)
+	if err != nil {
+		return err
+	}
+	policy, err := schedule.ParseMissedTickPolicy(spec.MissedTicks)
+	if err != nil {
+		return err
+	}
+
+	var jitter time.Duration
+	if spec.Jitter != nil {
+		jitter = spec.Jitter.Duration
+	}
+
+	var data []byte
+	contentType := spec.ContentType
+	if spec.Data != "" {
+		data = []byte(spec.Data)
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("cannot get the identity of the operator replica: %v", err)
+	}
+
+	owner := *metav1.NewControllerRef(ep, v1alpha1.SchemeGroupVersion.WithKind("EventProvider"))
+	return c.syncSource(ep, &schedule.Source{
+		Schedule:    s,
+		MissedTicks: policy,
+		Jitter:      jitter,
+		EventSource: eventProviderURI(ep),
+		Data:        data,
+		ContentType: contentType,
+		Claimer:     schedule.NewLeaseClaimer(c.kubeclientset, ep.Namespace, fmt.Sprintf("%s-schedule", ep.Name), owner, identity),
+	}, spec)
+}
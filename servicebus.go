@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/servicebus"
+)
+
+const (
+	// providerServiceBus is the provider name of eventproviders receiving Azure Service Bus messages
+	providerServiceBus = "servicebus"
+
+	// defaultMaxDeliveryCount is how many times Service Bus messages are delivered before they are dead-lettered, by default
+	defaultMaxDeliveryCount = 5
+	// defaultServiceBusBatchSize is how many Service Bus messages are received at once, by default
+	defaultServiceBusBatchSize = 10
+)
+
+// syncServiceBus runs the receiver of the queue or topic subscription of the eventprovider.
+// Every replica of the operator receives messages, or sessions, so they are shared between them.
+func (c *Controller) syncServiceBus(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.ServiceBus
+	if spec == nil {
+		return fmt.Errorf("the servicebus provider requires a serviceBus spec")
+	}
+	if (spec.Queue == "") == (spec.Topic == "") {
+		return fmt.Errorf("either a Service Bus queue or a topic is required")
+	}
+	if spec.Topic != "" && spec.Subscription == "" {
+		return fmt.Errorf("a subscription of topic %s is required", spec.Topic)
+	}
+
+	src := &servicebus.Source{
+		Namespace:        spec.Namespace,
+		Queue:            spec.Queue,
+		Topic:            spec.Topic,
+		Subscription:     spec.Subscription,
+		Sessions:         spec.Sessions,
+		MaxDeliveryCount: defaultMaxDeliveryCount,
+		BatchSize:        defaultServiceBusBatchSize,
+		Retry:            retryPolicy(spec.Retry),
+		EventSource:      eventProviderURI(ep),
+	}
+	if spec.MaxDeliveryCount > 0 {
+		src.MaxDeliveryCount = uint32(spec.MaxDeliveryCount)
+	}
+	if spec.BatchSize > 0 {
+		src.BatchSize = int(spec.BatchSize)
+	}
+
+	// the namespace is authorized with a connection string, or with the
+	// service principal of the eventprovider
+	secret, err := c.azureSecret(ep)
+	if err != nil {
+		return err
+	}
+	if secret != nil && len(secret.Data["connectionString"]) > 0 {
+		src.ConnectionString = string(secret.Data["connectionString"])
+	} else {
+		if spec.Namespace == "" {
+			return fmt.Errorf("a Service Bus namespace is required")
+		}
+		credentials, err := azureCredentials(secret)
+		if err != nil {
+			return err
+		}
+		if src.Credential, err = credentials.TokenCredential(); err != nil {
+			return err
+		}
+	}
+
+	return c.syncSource(ep, src, spec, resourceVersion(secret))
+}
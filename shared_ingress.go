@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// sharedIngressName is the name of the Ingress, and of its certificate secret,
+	// serving every eventprovider exposed on the shared host
+	sharedIngressName = "events-operator-shared"
+
+	// sharedBackendLabel marks the ExternalName services the shared Ingress routes to
+	sharedBackendLabel = "eventprovider.k8s.io/shared-backend"
+)
+
+// syncSharedExposure routes the path of the eventprovider through the shared Ingress,
+// and returns the public URL of the handler
+func (c *Controller) syncSharedExposure(ep *v1alpha1.EventProvider) (string, error) {
+	if c.config.SharedHost == "" {
+		return "", fmt.Errorf("no shared host configured for the operator")
+	}
+	if c.ingressAPI != ingressAPINetworkingV1 {
+		return "", fmt.Errorf("the shared ingress requires the %s Ingress API", ingressAPINetworkingV1)
+	}
+
+	if err := c.syncSharedIngress(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://%s%s", c.config.SharedHost, ep.Status.Path), nil
+}
+
+// syncSharedIngress rebuilds the shared Ingress from all the eventproviders exposed on the shared host.
+// Ingress backends must live in the namespace of the Ingress, so every eventprovider gets an
+// ExternalName service in the operator namespace pointing to its handler service.
+func (c *Controller) syncSharedIngress() error {
+	if c.config.SharedHost == "" || c.ingressAPI != ingressAPINetworkingV1 {
+		return nil
+	}
+
+	// the eventprovider that was just synced may not be in the cache yet, which is
+	// fine - the status update requeues it and the next sync picks it up
+	eps, err := c.epLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("cannot list eventproviders: %v", err)
+	}
+
+	// sort the eventproviders so that the Ingress rules are stable across syncs
+	sort.Slice(eps, func(i, j int) bool {
+		return eps[i].Status.Path < eps[j].Status.Path
+	})
+
+	backends := map[string]bool{}
+	var paths []networkingv1.HTTPIngressPath
+	for _, ep := range eps {
+		if exposureMode(ep, c.config) != v1alpha1.ExposureModeSharedIngress || ep.Status.Path == "" || ep.DeletionTimestamp != nil {
+			continue
+		}
+
+		backendName := sharedBackendName(ep)
+		if err := c.syncSharedBackend(ep, backendName); err != nil {
+			return err
+		}
+		backends[backendName] = true
+
+		pathType := networkingv1.PathTypePrefix
+		paths = append(paths, networkingv1.HTTPIngressPath{
+			Path:     ep.Status.Path,
+			PathType: &pathType,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: backendName,
+					Port: networkingv1.ServiceBackendPort{
+						Number: backendPort(ep, c.config),
+					},
+				},
+			},
+		})
+	}
+
+	desired := newSharedIngress(c.config, paths)
+	ingress, err := c.ingressLister.Ingresses(c.config.OperatorNamespace).Get(sharedIngressName)
+	switch {
+	case errors.IsNotFound(err):
+		if len(paths) > 0 {
+			_, err = c.kubeclientset.NetworkingV1().Ingresses(c.config.OperatorNamespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+		} else {
+			err = nil
+		}
+	case err != nil:
+	case len(paths) == 0:
+		// an Ingress rule cannot be empty, so the shared Ingress goes away with the last path
+		err = c.kubeclientset.NetworkingV1().Ingresses(c.config.OperatorNamespace).Delete(context.TODO(), sharedIngressName, metav1.DeleteOptions{})
+	case !equality.Semantic.DeepEqual(ingress.Spec, desired.Spec):
+		// NEVER modify objects from the store. It's a read-only, local cache.
+		ingressCopy := ingress.DeepCopy()
+		ingressCopy.Spec = desired.Spec
+		_, err = c.kubeclientset.NetworkingV1().Ingresses(c.config.OperatorNamespace).Update(context.TODO(), ingressCopy, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("cannot sync shared ingress: %v", err)
+	}
+
+	return c.deleteStaleSharedBackends(backends)
+}
+
+// syncSharedBackend makes sure the ExternalName service pointing to the eventprovider handler exists,
+// and points to its current handler service and port
+func (c *Controller) syncSharedBackend(ep *v1alpha1.EventProvider, backendName string) error {
+	desired := newSharedBackend(ep, backendName, c.config)
+	service, err := c.servicesLister.Services(c.config.OperatorNamespace).Get(backendName)
+	switch {
+	case errors.IsNotFound(err):
+		_, err = c.kubeclientset.CoreV1().Services(c.config.OperatorNamespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+	case err != nil:
+	case service.Spec.ExternalName != desired.Spec.ExternalName || !equality.Semantic.DeepEqual(service.Spec.Ports, desired.Spec.Ports):
+		serviceCopy := service.DeepCopy()
+		serviceCopy.Spec.ExternalName = desired.Spec.ExternalName
+		serviceCopy.Spec.Ports = desired.Spec.Ports
+		_, err = c.kubeclientset.CoreV1().Services(c.config.OperatorNamespace).Update(context.TODO(), serviceCopy, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("cannot sync shared backend %s: %v", backendName, err)
+	}
+
+	return nil
+}
+
+// deleteStaleSharedBackends removes the ExternalName services of eventproviders
+// that were deleted or are no longer exposed on the shared host
+func (c *Controller) deleteStaleSharedBackends(backends map[string]bool) error {
+	services, err := c.servicesLister.Services(c.config.OperatorNamespace).List(labels.SelectorFromSet(labels.Set{sharedBackendLabel: "true"}))
+	if err != nil {
+		return fmt.Errorf("cannot list shared backends: %v", err)
+	}
+
+	for _, service := range services {
+		if backends[service.Name] {
+			continue
+		}
+
+		glog.Infof("deleting stale shared backend %s", service.Name)
+		err = c.kubeclientset.CoreV1().Services(c.config.OperatorNamespace).Delete(context.TODO(), service.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete shared backend %s: %v", service.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// newSharedIngress creates the Ingress serving all the eventprovider paths on the shared host
+func newSharedIngress(config ControllerConfig, paths []networkingv1.HTTPIngressPath) *networkingv1.Ingress {
+	annotations := map[string]string{}
+	for k, v := range config.IngressAnnotations {
+		annotations[k] = v
+	}
+	if config.CertificateIssuer.Name != "" {
+		delete(annotations, tlsAcmeAnnotation)
+	}
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        sharedIngressName,
+			Annotations: annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			TLS: []networkingv1.IngressTLS{
+				{
+					Hosts:      []string{config.SharedHost},
+					SecretName: sharedIngressName,
+				},
+			},
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: config.SharedHost,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: paths,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if config.IngressClass != "" {
+		className := config.IngressClass
+		ingress.Spec.IngressClassName = &className
+	}
+
+	return ingress
+}
+
+// newSharedBackend creates an ExternalName service in the operator namespace
+// resolving to the handler service of the eventprovider
+func newSharedBackend(ep *v1alpha1.EventProvider, backendName string, config ControllerConfig) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: backendName,
+			Labels: map[string]string{
+				sharedBackendLabel: "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: fmt.Sprintf("%s.%s.svc.%s", backendServiceName(ep, config), ep.Namespace, config.ClusterDomain),
+			Ports: []corev1.ServicePort{
+				{
+					Name:     "http",
+					Protocol: corev1.ProtocolTCP,
+					Port:     backendPort(ep, config),
+				},
+			},
+		},
+	}
+}
+
+// sharedBackendName returns the name of the ExternalName service of an eventprovider. It is derived
+// from a hash of namespace/name, as joining them with a dash can collide, or start with a digit,
+// which service names must not.
+func sharedBackendName(ep *v1alpha1.EventProvider) string {
+	hash := sha1.Sum([]byte(ep.Namespace + "/" + ep.Name))
+	return fmt.Sprintf("eventprovider-%s", hex.EncodeToString(hash[:])[:20])
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestSharedBackendName(t *testing.T) {
+	names := map[string]bool{}
+	for _, meta := range []metav1.ObjectMeta{
+		{Namespace: "a-b", Name: "c"},
+		{Namespace: "a", Name: "b-c"},
+		{Namespace: "1default", Name: "events"},
+		{Namespace: "a-very-long-namespace-name-that-goes-on-and-on", Name: "a-very-long-eventprovider-name-that-goes-on-and-on"},
+	} {
+		name := sharedBackendName(&v1alpha1.EventProvider{ObjectMeta: meta})
+		if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+			t.Errorf("%s/%s: invalid service name %s: %v", meta.Namespace, meta.Name, name, errs)
+		}
+		if names[name] {
+			t.Errorf("%s/%s: service name %s collides", meta.Namespace, meta.Name, name)
+		}
+		names[name] = true
+	}
+}
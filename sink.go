@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// resolveSink returns the URL the event gateway dispatches the events of an eventprovider to
+func (c *Controller) resolveSink(ep *v1alpha1.EventProvider) (string, error) {
+	sink := ep.Spec.Sink
+	if sink.Ref == nil {
+		u, err := url.Parse(sink.URI)
+		if err != nil {
+			return "", fmt.Errorf("invalid sink URI %s: %v", sink.URI, err)
+		}
+		if !u.IsAbs() {
+			return "", fmt.Errorf("the sink URI %s must be absolute when no sink ref is set", sink.URI)
+		}
+
+		return u.String(), nil
+	}
+
+	ref := *sink.Ref
+	if ref.APIVersion == "" {
+		ref.APIVersion = "v1"
+	}
+	if ref.Kind == "" {
+		ref.Kind = "Service"
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = ep.Namespace
+	}
+
+	var base *url.URL
+	var err error
+	if ref.APIVersion == "v1" && ref.Kind == "Service" {
+		base, err = c.resolveServiceSink(ref)
+	} else {
+		base, err = c.resolveAddressableSink(ref)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if sink.URI != "" {
+		rel, err := url.Parse(sink.URI)
+		if err != nil {
+			return "", fmt.Errorf("invalid sink URI %s: %v", sink.URI, err)
+		}
+		base = base.ResolveReference(rel)
+	}
+
+	return base.String(), nil
+}
+
+// resolveServiceSink returns the in-cluster URL of a Service
+func (c *Controller) resolveServiceSink(ref v1alpha1.SinkReference) (*url.URL, error) {
+	if _, err := c.servicesLister.Services(ref.Namespace).Get(ref.Name); err != nil {
+		return nil, fmt.Errorf("cannot get sink service %s/%s: %v", ref.Namespace, ref.Name, err)
+	}
+
+	port := ref.Port
+	if port == 0 {
+		port = 80
+	}
+
+	return &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s.%s.svc.%s:%d", ref.Name, ref.Namespace, c.config.ClusterDomain, port),
+		Path:   "/",
+	}, nil
+}
+
+// resolveAddressableSink returns the address of any resource exposing it in status.address.url
+func (c *Controller) resolveAddressableSink(ref v1alpha1.SinkReference) (*url.URL, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink apiVersion %s: %v", ref.APIVersion, err)
+	}
+
+	mapping, err := c.restMapper.RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the resource of sink kind %s: %v", ref.Kind, err)
+	}
+
+	obj, err := c.dynamicclientset.Resource(mapping.Resource).Namespace(ref.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get sink %s %s/%s: %v", ref.Kind, ref.Namespace, ref.Name, err)
+	}
+
+	address, _, _ := unstructured.NestedString(obj.Object, "status", "address", "url")
+	if address == "" {
+		return nil, fmt.Errorf("sink %s %s/%s has no address yet", ref.Kind, ref.Namespace, ref.Name)
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s of sink %s %s/%s: %v", address, ref.Kind, ref.Namespace, ref.Name, err)
+	}
+
+	return u, nil
+}
+
+// syncGatewayBackend makes sure the namespace of the eventprovider has an ExternalName
+// service resolving to the event gateway. Ingresses and HTTPRoutes can only route to
+// services in their own namespace, so this service is what they route to. The service is
+// owned by every eventprovider routing to it, and a service of the same name that is not
+// owned by an eventprovider is never adopted.
+func (c *Controller) syncGatewayBackend(ep *v1alpha1.EventProvider) error {
+	if ep.Namespace == c.config.OperatorNamespace {
+		return nil
+	}
+
+	owner := metav1.OwnerReference{
+		APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		Kind:       "EventProvider",
+		Name:       ep.Name,
+		UID:        ep.UID,
+	}
+	desired := newGatewayBackend(c.config)
+	service, err := c.servicesLister.Services(ep.Namespace).Get(c.config.GatewayService)
+	switch {
+	case errors.IsNotFound(err):
+		desired.OwnerReferences = []metav1.OwnerReference{owner}
+		_, err = c.kubeclientset.CoreV1().Services(ep.Namespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+	case err != nil:
+	case !ownedByEventProvider(service):
+		return fmt.Errorf("service %s/%s exists and is not managed by the operator", ep.Namespace, c.config.GatewayService)
+	case !hasOwner(service, ep.UID) || service.Spec.ExternalName != desired.Spec.ExternalName || !equality.Semantic.DeepEqual(service.Spec.Ports, desired.Spec.Ports):
+		// NEVER modify objects from the store. It's a read-only, local cache.
+		serviceCopy := service.DeepCopy()
+		if !hasOwner(service, ep.UID) {
+			serviceCopy.OwnerReferences = append(serviceCopy.OwnerReferences, owner)
+		}
+		serviceCopy.Spec.ExternalName = desired.Spec.ExternalName
+		serviceCopy.Spec.Ports = desired.Spec.Ports
+		_, err = c.kubeclientset.CoreV1().Services(ep.Namespace).Update(context.TODO(), serviceCopy, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("cannot sync gateway backend in namespace %s: %v", ep.Namespace, err)
+	}
+
+	return nil
+}
+
+// ownedByEventProvider returns true when an eventprovider is an owner of obj
+func ownedByEventProvider(obj metav1.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == "EventProvider" && ref.APIVersion == v1alpha1.SchemeGroupVersion.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasOwner returns true when the object with the given UID is an owner of obj
+func hasOwner(obj metav1.Object, uid types.UID) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == uid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newGatewayBackend creates an ExternalName service resolving to the event gateway
+func newGatewayBackend(config ControllerConfig) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: config.GatewayService,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: fmt.Sprintf("%s.%s.svc.%s", config.GatewayService, config.OperatorNamespace, config.ClusterDomain),
+			Ports: []corev1.ServicePort{
+				{
+					Name:     "http",
+					Protocol: corev1.ProtocolTCP,
+					Port:     config.GatewayPort,
+				},
+			},
+		},
+	}
+}
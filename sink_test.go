@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSyncGatewayBackend(t *testing.T) {
+	first, second := newTestEventProvider("first"), newTestEventProvider("second")
+	c, client := newTestController(t)
+
+	if err := c.syncGatewayBackend(first); err != nil {
+		t.Fatalf("cannot create gateway backend: %v", err)
+	}
+	service := getService(t, client, "default", c.config.GatewayService)
+	if service.Spec.ExternalName != "events-operator-gateway.events-operator.svc.cluster.local" {
+		t.Errorf("got external name %s", service.Spec.ExternalName)
+	}
+	if len(service.OwnerReferences) != 1 || service.OwnerReferences[0].UID != first.UID {
+		t.Errorf("got owners %+v", service.OwnerReferences)
+	}
+
+	// every eventprovider routing to the gateway owns the backend, and stale specs are updated
+	service.Spec.ExternalName = "elsewhere"
+	c, client = newTestController(t, service)
+	if err := c.syncGatewayBackend(second); err != nil {
+		t.Fatalf("cannot update gateway backend: %v", err)
+	}
+	service = getService(t, client, "default", c.config.GatewayService)
+	if service.Spec.ExternalName != "events-operator-gateway.events-operator.svc.cluster.local" {
+		t.Errorf("got external name %s", service.Spec.ExternalName)
+	}
+	if len(service.OwnerReferences) != 2 || service.OwnerReferences[1].UID != second.UID {
+		t.Errorf("got owners %+v", service.OwnerReferences)
+	}
+}
+
+func TestSyncGatewayBackendNotOwned(t *testing.T) {
+	foreign := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "events-operator-gateway"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP, Ports: []corev1.ServicePort{{Port: 8080}}},
+	}
+	c, client := newTestController(t, foreign)
+
+	err := c.syncGatewayBackend(newTestEventProvider("first"))
+	if err == nil || !strings.Contains(err.Error(), "not managed by the operator") {
+		t.Fatalf("got error %v for a service the operator does not own", err)
+	}
+	if service := getService(t, client, "default", "events-operator-gateway"); service.Spec.Type != corev1.ServiceTypeClusterIP || len(service.OwnerReferences) != 0 {
+		t.Errorf("a service the operator does not own was modified: %+v", service)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+	"github.com/radu-matei/events-operator/pkg/sns"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// snsResubscribeBackoff is how long an endpoint pending confirmation waits before it is subscribed
+	// again, doubled with every subscription up to snsMaxResubscribeBackoff
+	snsResubscribeBackoff    = time.Minute
+	snsMaxResubscribeBackoff = time.Hour
+)
+
+// syncSNS subscribes the endpoint of the eventprovider to its SNS topic. The event gateway
+// confirms the subscription, and verifies the signature of every message.
+func (c *Controller) syncSNS(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.SNS
+	if ep.Spec.Sink == nil {
+		return fmt.Errorf("the sns provider requires a sink")
+	}
+	if spec == nil {
+		return fmt.Errorf("the sns provider requires an sns spec")
+	}
+
+	region, err := sns.ParseTopicARN(spec.TopicARN)
+	if err != nil {
+		return err
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+	if err != nil {
+		return fmt.Errorf("cannot get SNS secret %s/%s: %v", ep.Namespace, spec.SecretName, err)
+	}
+	creds, err := awsCredentials(secret)
+	if err != nil {
+		return err
+	}
+
+	// the subscription must be deleted with the eventprovider
+	if ep, err = c.ensureFinalizer(ep); err != nil {
+		return err
+	}
+
+	verifier, err := sns.NewVerifier(spec.TopicARN, spec.Endpoint)
+	if err != nil {
+		return err
+	}
+	ep, endpointURL, err := c.syncEndpoint(ep, gateway.Route{
+		Auth:   verifier,
+		Source: spec.TopicARN,
+	})
+	if err != nil || endpointURL == "" {
+		return err
+	}
+
+	ctx := context.TODO()
+	client := sns.NewClient(region, spec.Endpoint, creds)
+	arn, err := c.subscribeSNS(ctx, client, ep, endpointURL)
+	if err != nil {
+		return err
+	}
+
+	// the endpoint changed, drop the subscription of the previous one
+	if ep.Status.HookID != "" && ep.Status.HookID != arn && ep.Status.HookID != sns.PendingConfirmation {
+		if err := client.Unsubscribe(ctx, ep.Status.HookID); err != nil && !sns.IsNotFound(err) {
+			return fmt.Errorf("cannot delete subscription %s: %v", ep.Status.HookID, err)
+		}
+	}
+
+	_, err = c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		status.HookID = arn
+	})
+	if err != nil {
+		return fmt.Errorf("cannot update eventprovider status: %v", err)
+	}
+
+	return nil
+}
+
+// subscribeSNS subscribes endpointURL to the topic of the eventprovider, unless it already is,
+// and returns the ARN of the subscription. While the endpoint did not confirm its subscription,
+// it is subscribed again with a backoff, as every subscription sends a confirmation message.
+func (c *Controller) subscribeSNS(ctx context.Context, client *sns.Client, ep *v1alpha1.EventProvider, endpointURL string) (string, error) {
+	topicARN := ep.Spec.SNS.TopicARN
+	key := ep.Namespace + "/" + ep.Name
+
+	subscription, err := client.FindSubscription(ctx, topicARN, endpointURL)
+	if err != nil {
+		return "", fmt.Errorf("cannot list subscriptions of %s: %v", topicARN, err)
+	}
+	if subscription != nil && subscription.SubscriptionArn != sns.PendingConfirmation {
+		c.snsSubscriptions.forget(key)
+		return subscription.SubscriptionArn, nil
+	}
+	if subscription != nil && !c.snsSubscriptions.due(key, endpointURL, time.Now()) {
+		return subscription.SubscriptionArn, nil
+	}
+
+	// subscribing again sends a new confirmation message, in case the previous one was missed
+	arn, err := client.Subscribe(ctx, topicARN, endpointURL)
+	if err != nil {
+		return "", fmt.Errorf("cannot subscribe to %s: %v", topicARN, err)
+	}
+	c.snsSubscriptions.subscribed(key, endpointURL, time.Now())
+	glog.Infof("subscribed eventprovider %s/%s to %s", ep.Namespace, ep.Name, topicARN)
+
+	return arn, nil
+}
+
+// finalizeSNS deletes the subscription of a deleted eventprovider
+func (c *Controller) finalizeSNS(ep *v1alpha1.EventProvider) error {
+	c.snsSubscriptions.forget(ep.Namespace + "/" + ep.Name)
+
+	spec := ep.Spec.SNS
+	if spec == nil || ep.Status.HookID == "" || ep.Status.HookID == sns.PendingConfirmation {
+		return nil
+	}
+
+	region, err := sns.ParseTopicARN(spec.TopicARN)
+	if err != nil {
+		return nil
+	}
+
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(spec.SecretName)
+	if errors.IsNotFound(err) {
+		// without credentials the subscription can never be deleted, do not hold the eventprovider forever
+		glog.Warningf("cannot delete SNS subscription %s of eventprovider %s/%s, its secret is gone", ep.Status.HookID, ep.Namespace, ep.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot get SNS secret %s/%s: %v", ep.Namespace, spec.SecretName, err)
+	}
+	creds, err := awsCredentials(secret)
+	if err != nil {
+		return err
+	}
+
+	client := sns.NewClient(region, spec.Endpoint, creds)
+	if err := client.Unsubscribe(context.TODO(), ep.Status.HookID); err != nil && !sns.IsNotFound(err) {
+		return fmt.Errorf("cannot delete SNS subscription %s: %v", ep.Status.HookID, err)
+	}
+	glog.Infof("deleted SNS subscription %s of eventprovider %s/%s", ep.Status.HookID, ep.Namespace, ep.Name)
+
+	return nil
+}
+
+// snsSubscriptions remembers when the endpoints pending confirmation were last subscribed
+type snsSubscriptions struct {
+	mu      sync.Mutex
+	pending map[string]snsPending
+}
+
+// snsPending is the subscription of an endpoint pending confirmation
+type snsPending struct {
+	endpointURL  string
+	subscribedAt time.Time
+	attempts     uint
+}
+
+// due returns true when the endpoint of an eventprovider should be subscribed again: it was not
+// subscribed yet, or the backoff since its last subscription elapsed
+func (s *snsSubscriptions) due(key, endpointURL string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[key]
+	if !ok || p.endpointURL != endpointURL {
+		return true
+	}
+
+	backoff := snsResubscribeBackoff
+	for i := uint(1); i < p.attempts && backoff < snsMaxResubscribeBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > snsMaxResubscribeBackoff {
+		backoff = snsMaxResubscribeBackoff
+	}
+
+	return now.Sub(p.subscribedAt) >= backoff
+}
+
+// subscribed records the subscription of the endpoint of an eventprovider
+func (s *snsSubscriptions) subscribed(key, endpointURL string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == nil {
+		s.pending = map[string]snsPending{}
+	}
+	p := s.pending[key]
+	if p.endpointURL != endpointURL {
+		p = snsPending{endpointURL: endpointURL}
+	}
+	p.subscribedAt = now
+	p.attempts++
+	s.pending[key] = p
+}
+
+// forget forgets the subscription of an eventprovider, once confirmed or deleted
+func (s *snsSubscriptions) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pending, key)
+}
+
+// awsCredentials returns the AWS credentials of a secret
+func awsCredentials(secret *corev1.Secret) (sns.Credentials, error) {
+	accessKeyID, err := secretKey(secret, "accessKeyID")
+	if err != nil {
+		return sns.Credentials{}, err
+	}
+	secretAccessKey, err := secretKey(secret, "secretAccessKey")
+	if err != nil {
+		return sns.Credentials{}, err
+	}
+
+	return sns.Credentials{
+		AccessKeyID:     string(accessKeyID),
+		SecretAccessKey: string(secretAccessKey),
+		SessionToken:    string(secret.Data["sessionToken"]),
+	}, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/sns"
+)
+
+// fakeSNS is an in-memory SNS query API, holding the subscriptions of a single topic by endpoint
+type fakeSNS struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	subscriptions map[string]string
+	subscribes    int
+}
+
+func newFakeSNS(t *testing.T) *fakeSNS {
+	f := &fakeSNS{subscriptions: map[string]string{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeSNS) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch r.PostForm.Get("Action") {
+	case "ListSubscriptionsByTopic":
+		type member struct {
+			SubscriptionArn string
+			TopicArn        string
+			Endpoint        string
+		}
+		var resp struct {
+			XMLName xml.Name `xml:"ListSubscriptionsByTopicResponse"`
+			Members []member `xml:"ListSubscriptionsByTopicResult>Subscriptions>member"`
+		}
+		for endpoint, arn := range f.subscriptions {
+			resp.Members = append(resp.Members, member{SubscriptionArn: arn, TopicArn: r.PostForm.Get("TopicArn"), Endpoint: endpoint})
+		}
+		xml.NewEncoder(w).Encode(resp)
+	case "Subscribe":
+		f.subscribes++
+		endpoint := r.PostForm.Get("Endpoint")
+		if _, ok := f.subscriptions[endpoint]; !ok {
+			f.subscriptions[endpoint] = sns.PendingConfirmation
+		}
+		fmt.Fprintf(w, "<SubscribeResponse><SubscribeResult><SubscriptionArn>%s:%d</SubscriptionArn></SubscribeResult></SubscribeResponse>", r.PostForm.Get("TopicArn"), f.subscribes)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// confirm confirms the subscription of an endpoint
+func (f *fakeSNS) confirm(endpoint, arn string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.subscriptions[endpoint] = arn
+}
+
+// subscribed returns how many times endpoints were subscribed, and forgets it
+func (f *fakeSNS) subscribed() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := f.subscribes
+	f.subscribes = 0
+
+	return n
+}
+
+func TestSubscribeSNS(t *testing.T) {
+	const topicARN = "arn:aws:sns:eu-west-1:123456789012:orders"
+	f := newFakeSNS(t)
+	client := sns.NewClient("eu-west-1", f.URL, sns.Credentials{AccessKeyID: "id", SecretAccessKey: "secret"})
+
+	c, _ := newTestController(t)
+	ep := newTestEventProvider("orders")
+	ep.Spec.SNS = &v1alpha1.SNSSpec{TopicARN: topicARN}
+	key := ep.Namespace + "/" + ep.Name
+
+	subscribe := func(endpointURL string) string {
+		t.Helper()
+
+		arn, err := c.subscribeSNS(context.TODO(), client, ep, endpointURL)
+		if err != nil {
+			t.Fatalf("cannot subscribe: %v", err)
+		}
+		return arn
+	}
+	// ago moves the last subscription of the eventprovider back by d
+	ago := func(d time.Duration) {
+		c.snsSubscriptions.mu.Lock()
+		defer c.snsSubscriptions.mu.Unlock()
+
+		p := c.snsSubscriptions.pending[key]
+		p.subscribedAt = p.subscribedAt.Add(-d)
+		c.snsSubscriptions.pending[key] = p
+	}
+
+	if arn := subscribe("https://events.example.com/a"); arn == "" || f.subscribed() != 1 {
+		t.Fatalf("got subscription %s", arn)
+	}
+
+	// pending confirmation, the endpoint is not subscribed again before the backoff elapsed
+	if arn := subscribe("https://events.example.com/a"); arn != sns.PendingConfirmation || f.subscribed() != 0 {
+		t.Errorf("got subscription %s, subscribed again within the backoff", arn)
+	}
+	ago(snsResubscribeBackoff)
+	if subscribe("https://events.example.com/a"); f.subscribed() != 1 {
+		t.Errorf("not subscribed again after the backoff")
+	}
+
+	// the backoff doubles
+	ago(snsResubscribeBackoff)
+	if subscribe("https://events.example.com/a"); f.subscribed() != 0 {
+		t.Errorf("subscribed again before the backoff doubled")
+	}
+	ago(snsResubscribeBackoff)
+	if subscribe("https://events.example.com/a"); f.subscribed() != 1 {
+		t.Errorf("not subscribed again after the doubled backoff")
+	}
+
+	// the endpoint changed, it is subscribed right away
+	if subscribe("https://events.example.com/b"); f.subscribed() != 1 {
+		t.Errorf("the new endpoint was not subscribed")
+	}
+
+	// confirmed, the subscription is used and the backoff forgotten
+	f.confirm("https://events.example.com/b", topicARN+":confirmed")
+	if arn := subscribe("https://events.example.com/b"); arn != topicARN+":confirmed" || f.subscribed() != 0 {
+		t.Errorf("got subscription %s", arn)
+	}
+	if _, ok := c.snsSubscriptions.pending[key]; ok {
+		t.Errorf("the backoff of a confirmed subscription was not forgotten")
+	}
+}
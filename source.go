@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/cloudevents"
+	"github.com/radu-matei/events-operator/pkg/source"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// syncSource runs the source producing the events of an eventprovider in the operator, and
+// reports its state in the SourceReady condition. settings are everything the source is built
+// from, such as the provider spec and the versions of its secrets: the source is restarted when
+// they change.
+func (c *Controller) syncSource(ep *v1alpha1.EventProvider, src source.Source, settings ...interface{}) error {
+	if ep.Spec.Sink == nil {
+		return fmt.Errorf("the %s provider requires a sink", ep.Spec.ProviderName)
+	}
+
+	// events of sources are not received by the gateway
+	c.gateway.DeleteRoute(ep.Namespace, ep.Name)
+
+	sinkURL, err := c.resolveSink(ep)
+	if err != nil {
+		return err
+	}
+	mode, err := c.sinkMode(ep)
+	if err != nil {
+		return err
+	}
+	hash, err := sourceHash(settings...)
+	if err != nil {
+		return err
+	}
+
+	c.sources.Run(source.Config{
+		Namespace: ep.Namespace,
+		Name:      ep.Name,
+		Sink:      sinkURL,
+		Mode:      mode,
+		Hash:      hash,
+	}, src)
+
+	condition := sourceCondition(c.sources.State(ep.Namespace, ep.Name))
+	_, err = c.updateStatus(ep, func(status *v1alpha1.EventProviderStatus) {
+		status.SinkURL = sinkURL
+		meta.SetStatusCondition(&status.Conditions, condition)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot update eventprovider status: %v", err)
+	}
+
+	return nil
+}
+
+// sinkMode returns the CloudEvents content mode events are dispatched to the sink of the eventprovider in
+func (c *Controller) sinkMode(ep *v1alpha1.EventProvider) (cloudevents.Mode, error) {
+	if ep.Spec.Sink.ContentMode == "" {
+		return c.config.ContentMode, nil
+	}
+
+	mode, err := cloudevents.ParseMode(ep.Spec.Sink.ContentMode)
+	if err != nil {
+		return "", fmt.Errorf("invalid sink content mode: %v", err)
+	}
+
+	return mode, nil
+}
+
+// retryPolicy returns the delivery retry policy of a source, the default one when spec is nil
+func retryPolicy(spec *v1alpha1.RetrySpec) source.Retry {
+	retry := source.DefaultRetry
+	if spec == nil {
+		return retry
+	}
+
+	if spec.Attempts > 0 {
+		retry.Attempts = int(spec.Attempts)
+	}
+	if spec.Backoff != nil {
+		retry.Backoff = spec.Backoff.Duration
+	}
+
+	return retry
+}
+
+// sourceCondition translates the state of a source into the SourceReady condition
+func sourceCondition(state source.State) metav1.Condition {
+	switch {
+	case state.Ready:
+		return metav1.Condition{
+			Type:    v1alpha1.ConditionSourceReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Running",
+			Message: "the source is producing events",
+		}
+	case state.Err != nil:
+		return metav1.Condition{
+			Type:    v1alpha1.ConditionSourceReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Failed",
+			Message: state.Err.Error(),
+		}
+	default:
+		return metav1.Condition{
+			Type:    v1alpha1.ConditionSourceReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Starting",
+			Message: "the source is starting",
+		}
+	}
+}
+
+// sourceHash returns a hash of the settings of a source
+func sourceHash(settings ...interface{}) (string, error) {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash source settings: %v", err)
+	}
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// eventProviderURI returns the path of the eventprovider in the API, the CloudEvents
+// source of events produced by the operator itself
+func eventProviderURI(ep *v1alpha1.EventProvider) string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/eventproviders/%s", v1alpha1.SchemeGroupVersion, ep.Namespace, ep.Name)
+}
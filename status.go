@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// updateStatus applies update to a copy of the eventprovider status, and persists it
+// if anything changed. It returns the updated eventprovider, so that further status
+// updates in the same sync do not conflict.
+func (c *Controller) updateStatus(ep *v1alpha1.EventProvider, update func(status *v1alpha1.EventProviderStatus)) (*v1alpha1.EventProvider, error) {
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	epCopy := ep.DeepCopy()
+	update(&epCopy.Status)
+	if equality.Semantic.DeepEqual(ep.Status, epCopy.Status) {
+		return ep, nil
+	}
+
+	updated, err := c.epclientset.EventproviderV1alpha1().EventProviders(ep.Namespace).UpdateStatus(context.TODO(), epCopy, metav1.UpdateOptions{})
+	if err != nil {
+		return ep, err
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/storagequeue"
+)
+
+const (
+	// providerStorageQueue is the provider name of eventproviders polling Azure Storage queues
+	providerStorageQueue = "storagequeue"
+
+	// storageResource is the Azure AD resource of Azure Storage
+	storageResource = "https://storage.azure.com/"
+)
+
+// syncStorageQueue runs the poller of the queue of the eventprovider. Every replica of the
+// operator polls the queue, messages being invisible to the others once dequeued.
+func (c *Controller) syncStorageQueue(ep *v1alpha1.EventProvider) error {
+	spec := ep.Spec.StorageQueue
+	if spec == nil {
+		return fmt.Errorf("the storagequeue provider requires a storageQueue spec")
+	}
+	if ep.Spec.StorageAccount == "" || spec.Queue == "" {
+		return fmt.Errorf("a storage account and queue are required")
+	}
+
+	endpoint := spec.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.queue.core.windows.net", ep.Spec.StorageAccount)
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid queue endpoint %q: %v", endpoint, err)
+	}
+
+	src := &storagequeue.Source{
+		Endpoint:          endpointURL,
+		Queue:             spec.Queue,
+		AccountName:       ep.Spec.StorageAccount,
+		VisibilityTimeout: time.Minute,
+		PollInterval:      10 * time.Second,
+		BatchSize:         spec.BatchSize,
+		MaxDequeueCount:   5,
+		PoisonQueue:       spec.PoisonQueue,
+		EventGrid:         spec.EventGrid,
+		Retry:             retryPolicy(spec.Retry),
+		EventSource:       eventProviderURI(ep),
+	}
+	if spec.VisibilityTimeout != nil && spec.VisibilityTimeout.Duration > 0 {
+		src.VisibilityTimeout = spec.VisibilityTimeout.Duration
+	}
+	if spec.PollInterval != nil && spec.PollInterval.Duration > 0 {
+		src.PollInterval = spec.PollInterval.Duration
+	}
+	if spec.MaxDequeueCount > 0 {
+		src.MaxDequeueCount = int64(spec.MaxDequeueCount)
+	}
+	if src.PoisonQueue == "" {
+		src.PoisonQueue = spec.Queue + "-poison"
+	}
+
+	// the storage account is authorized with its shared key, as with Azurite, or
+	// with the service principal of the eventprovider
+	secret, err := c.azureSecret(ep)
+	if err != nil {
+		return err
+	}
+	if secret != nil && len(secret.Data["accountKey"]) > 0 {
+		src.AccountKey = string(secret.Data["accountKey"])
+	} else {
+		credentials, err := azureCredentials(secret)
+		if err != nil {
+			return err
+		}
+		if src.Token, err = credentials.Token(storageResource); err != nil {
+			return err
+		}
+	}
+
+	return c.syncSource(ep, src, spec, ep.Spec.StorageAccount, resourceVersion(secret))
+}
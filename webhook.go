@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/radu-matei/events-operator/pkg/apis/eventprovider/v1alpha1"
+	"github.com/radu-matei/events-operator/pkg/gateway"
+)
+
+// syncWebhook exposes the endpoint of a generic webhook, and routes the payloads
+// of authenticated callers to the sink of the eventprovider
+func (c *Controller) syncWebhook(ep *v1alpha1.EventProvider) error {
+	if ep.Spec.Sink == nil {
+		return fmt.Errorf("the webhook provider requires a sink")
+	}
+	if ep.Spec.Webhook == nil {
+		return fmt.Errorf("the webhook provider requires a webhook spec")
+	}
+
+	auth, err := c.webhookAuthenticator(ep)
+	if err != nil {
+		return err
+	}
+
+	eventType := ep.Spec.EventType
+	if eventType == "" {
+		eventType = gateway.DefaultWebhookEventType
+	}
+
+	source := ep.Spec.Webhook.Source
+	if source == "" {
+		source = fmt.Sprintf("/apis/%s/namespaces/%s/eventproviders/%s", v1alpha1.SchemeGroupVersion, ep.Namespace, ep.Name)
+	}
+
+	// the endpoint is ready once the route is set, there is nothing to subscribe to
+	_, _, err = c.syncEndpoint(ep, gateway.Route{
+		Auth:      auth,
+		Source:    source,
+		EventType: eventType,
+	})
+
+	return err
+}
+
+// webhookAuthenticator builds the authenticator of a webhook from the keys of its secret
+func (c *Controller) webhookAuthenticator(ep *v1alpha1.EventProvider) (gateway.Authenticator, error) {
+	auth := ep.Spec.Webhook.Auth
+	if auth.SecretName == "" {
+		return nil, fmt.Errorf("no secret configured for the webhook authentication")
+	}
+
+	// eventproviders are synced again every 30s, so rotated secrets are picked up from the cache
+	secret, err := c.secretsLister.Secrets(ep.Namespace).Get(auth.SecretName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get webhook secret %s/%s: %v", ep.Namespace, auth.SecretName, err)
+	}
+
+	key := func(name string) ([]byte, error) {
+		value, ok := secret.Data[name]
+		if !ok || len(value) == 0 {
+			return nil, fmt.Errorf("webhook secret %s/%s has no %s key", ep.Namespace, auth.SecretName, name)
+		}
+		return value, nil
+	}
+
+	switch auth.Type {
+	case v1alpha1.WebhookAuthBearer:
+		token, err := key("token")
+		if err != nil {
+			return nil, err
+		}
+		return gateway.BearerAuthenticator{Token: string(token)}, nil
+
+	case v1alpha1.WebhookAuthBasic:
+		username, err := key("username")
+		if err != nil {
+			return nil, err
+		}
+		password, err := key("password")
+		if err != nil {
+			return nil, err
+		}
+		return gateway.BasicAuthenticator{Username: string(username), Password: string(password)}, nil
+
+	case v1alpha1.WebhookAuthHMAC:
+		hmacSecret, err := key("secret")
+		if err != nil {
+			return nil, err
+		}
+		header := auth.HMAC.Header
+		if header == "" {
+			header = "X-Signature"
+		}
+		authenticator, err := gateway.NewHMACAuthenticator(header, auth.HMAC.Prefix, auth.HMAC.Algorithm, auth.HMAC.Encoding, hmacSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook HMAC configuration: %v", err)
+		}
+		return authenticator, nil
+
+	default:
+		return nil, fmt.Errorf("unknown webhook authentication type %q", auth.Type)
+	}
+}